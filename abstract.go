@@ -471,6 +471,82 @@ func Max[T Number](xs ...T) T {
 	return max
 }
 
+// Sum returns the sum of the provided values.
+// If no values are provided, returns the zero value for the type.
+//
+// Parameters:
+//   - xs: Variable number of values to add up.
+//
+// Returns:
+//   - The sum of the provided values.
+//
+// Example usage:
+//
+//	sum := Sum(1, 2, 3)           // 6
+//	sum := Sum(values...)         // sum of a slice
+//	sum := Sum[int]()             // 0 (zero value)
+func Sum[T Number](xs ...T) T {
+	var sum T
+	for _, x := range xs {
+		sum += x
+	}
+	return sum
+}
+
+// Avg returns the arithmetic mean of the provided values as float64.
+// If no values are provided, returns 0.
+//
+// Parameters:
+//   - xs: Variable number of values to average.
+//
+// Returns:
+//   - The arithmetic mean of the provided values.
+//
+// Example usage:
+//
+//	avg := Avg(1, 2, 3, 4)        // 2.5
+//	avg := Avg(values...)         // mean of a slice
+//	avg := Avg[int]()             // 0
+func Avg[T Number](xs ...T) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, x := range xs {
+		sum += float64(x)
+	}
+	return sum / float64(len(xs))
+}
+
+// Clamp returns v limited to the inclusive range [lo, hi].
+// If lo is greater than hi, the bounds are swapped.
+//
+// Parameters:
+//   - v: The value to clamp.
+//   - lo: The lower bound.
+//   - hi: The upper bound.
+//
+// Returns:
+//   - lo if v is less than lo, hi if v is greater than hi, v otherwise.
+//
+// Example usage:
+//
+//	c := Clamp(15, 0, 10)         // 10
+//	c := Clamp(-1.5, 0.0, 1.0)    // 0.0
+//	c := Clamp("m", "a", "k")     // "k"
+func Clamp[T Ordered](v, lo, hi T) T {
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
 // Abs returns the absolute value of the provided numeric value.
 // This function works with any numeric type and returns the same type.
 //
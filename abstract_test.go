@@ -503,6 +503,108 @@ func TestMax(t *testing.T) {
 	})
 }
 
+func TestSum(t *testing.T) {
+	t.Run("Integer values", func(t *testing.T) {
+		result := abstract.Sum(5, 3, 8, 1, 9)
+		if result != 26 {
+			t.Errorf("Expected %v, got %v", 26, result)
+		}
+	})
+
+	t.Run("Float values", func(t *testing.T) {
+		result := abstract.Sum(1.5, 2.5, -1.0)
+		if result != 3.0 {
+			t.Errorf("Expected %v, got %v", 3.0, result)
+		}
+	})
+
+	t.Run("Slice values", func(t *testing.T) {
+		values := []int64{10, 20, 30}
+		result := abstract.Sum(values...)
+		if result != 60 {
+			t.Errorf("Expected %v, got %v", 60, result)
+		}
+	})
+
+	t.Run("No values", func(t *testing.T) {
+		result := abstract.Sum[int]()
+		if result != 0 {
+			t.Errorf("Expected %v, got %v", 0, result)
+		}
+	})
+}
+
+func TestAvg(t *testing.T) {
+	t.Run("Integer values", func(t *testing.T) {
+		result := abstract.Avg(1, 2, 3, 4)
+		if result != 2.5 {
+			t.Errorf("Expected %v, got %v", 2.5, result)
+		}
+	})
+
+	t.Run("Float values", func(t *testing.T) {
+		result := abstract.Avg(1.0, -1.0, 3.0)
+		if result != 1.0 {
+			t.Errorf("Expected %v, got %v", 1.0, result)
+		}
+	})
+
+	t.Run("Small unsigned values", func(t *testing.T) {
+		// Must not overflow uint8 while summing
+		result := abstract.Avg[uint8](200, 200, 200)
+		if result != 200 {
+			t.Errorf("Expected %v, got %v", 200, result)
+		}
+	})
+
+	t.Run("No values", func(t *testing.T) {
+		result := abstract.Avg[int]()
+		if result != 0 {
+			t.Errorf("Expected %v, got %v", 0, result)
+		}
+	})
+}
+
+func TestClamp(t *testing.T) {
+	tests := []struct {
+		name     string
+		v        int
+		lo       int
+		hi       int
+		expected int
+	}{
+		{"Inside range", 5, 0, 10, 5},
+		{"Below range", -5, 0, 10, 0},
+		{"Above range", 15, 0, 10, 10},
+		{"On lower bound", 0, 0, 10, 0},
+		{"On upper bound", 10, 0, 10, 10},
+		{"Swapped bounds", 15, 10, 0, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := abstract.Clamp(tt.v, tt.lo, tt.hi)
+			if result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+
+	t.Run("Float values", func(t *testing.T) {
+		result := abstract.Clamp(-1.5, 0.0, 1.0)
+		if result != 0.0 {
+			t.Errorf("Expected %v, got %v", 0.0, result)
+		}
+	})
+
+	t.Run("String values", func(t *testing.T) {
+		result := abstract.Clamp("m", "a", "k")
+		if result != "k" {
+			t.Errorf("Expected %v, got %v", "k", result)
+		}
+	})
+}
+
 func TestAbs(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -2,7 +2,9 @@ package abstract
 
 import (
 	"context"
+	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/maxbolgarin/lang"
@@ -454,3 +456,205 @@ func (p *RateProcessor) worker(ctx context.Context) {
 		}
 	}
 }
+
+// Lazy returns a function that calls init on the first call and returns the cached value
+// on every subsequent call. It is safe for concurrent use: init is executed exactly once
+// and concurrent callers wait for it to complete.
+//
+// If init panics, every call of the returned function panics with the same value.
+//
+// Parameters:
+//   - init: Function that computes the value
+//
+// Returns:
+//   - A memoized function returning the computed value
+//
+// Example usage:
+//
+//	getConfig := Lazy(func() *Config {
+//		return loadConfig("config.yaml")
+//	})
+//
+//	cfg := getConfig() // loads config
+//	cfg = getConfig()  // returns cached config
+func Lazy[T any](init func() T) func() T {
+	return sync.OnceValue(init)
+}
+
+// LazyErr returns a function that calls init on the first call and returns the cached value
+// and error on every subsequent call. It is safe for concurrent use: init is executed exactly once
+// and concurrent callers wait for it to complete.
+//
+// The error is cached as well, so init is not retried after a failure.
+// If init panics, every call of the returned function panics with the same value.
+//
+// Parameters:
+//   - init: Function that computes the value or returns an error
+//
+// Returns:
+//   - A memoized function returning the computed value and error
+//
+// Example usage:
+//
+//	getDB := LazyErr(func() (*sql.DB, error) {
+//		return sql.Open("postgres", dsn)
+//	})
+//
+//	db, err := getDB()
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+func LazyErr[T any](init func() (T, error)) func() (T, error) {
+	return sync.OnceValues(init)
+}
+
+// Retry calls f until it succeeds or the number of attempts is exhausted and returns
+// the result of the last call. Between attempts it sleeps for the duration returned by backoff,
+// which receives the number of the failed attempt starting from 1. A nil backoff means no delay.
+// If attempts is not positive, f is called once.
+//
+// Parameters:
+//   - attempts: Maximum number of calls of f
+//   - backoff: Function returning the delay after the failed attempt
+//   - f: Function to call
+//
+// Returns:
+//   - The value returned by the first successful call
+//   - The error of the last call if all attempts failed
+//
+// Example usage:
+//
+//	// Exponential backoff: 100ms, 200ms, 400ms...
+//	resp, err := Retry(5, func(attempt int) time.Duration {
+//		return 100 * time.Millisecond << (attempt - 1)
+//	}, func() (*http.Response, error) {
+//		return http.Get("https://example.com")
+//	})
+func Retry[T any](attempts int, backoff func(attempt int) time.Duration, f func() (T, error)) (T, error) {
+	return RetryCtx(context.Background(), attempts, backoff, func(context.Context) (T, error) {
+		return f()
+	})
+}
+
+// RetryCtx calls f until it succeeds, the number of attempts is exhausted or the context is canceled.
+// It works like [Retry], but passes the context to f and stops waiting for the next attempt
+// when the context is canceled. In that case the returned error contains both the context error
+// and the error of the last call.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeout control
+//   - attempts: Maximum number of calls of f
+//   - backoff: Function returning the delay after the failed attempt
+//   - f: Function to call
+//
+// Returns:
+//   - The value returned by the first successful call
+//   - The error of the last call if all attempts failed or the context error if it was canceled
+//
+// Example usage:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//
+//	user, err := RetryCtx(ctx, 3, func(int) time.Duration { return time.Second }, func(ctx context.Context) (*User, error) {
+//		return client.GetUser(ctx, id)
+//	})
+func RetryCtx[T any](ctx context.Context, attempts int, backoff func(attempt int) time.Duration, f func(ctx context.Context) (T, error)) (T, error) {
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	var (
+		value T
+		err   error
+	)
+	for attempt := 1; ; attempt++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return value, errors.Join(ctxErr, err)
+		}
+
+		value, err = f(ctx)
+		if err == nil || attempt >= attempts {
+			return value, err
+		}
+
+		var delay time.Duration
+		if backoff != nil {
+			delay = backoff(attempt)
+		}
+		if delay <= 0 {
+			continue
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return value, errors.Join(ctx.Err(), err)
+		}
+	}
+}
+
+// Pool is a typed wrapper of [sync.Pool] that reuses objects to reduce allocations and counts
+// how many objects were reused (hits) and how many were created (misses).
+// It MUST be initialized with NewPool. It is safe for concurrent use.
+//
+// Example usage:
+//
+//	buffers := NewPool(func() *bytes.Buffer {
+//		return new(bytes.Buffer)
+//	}, func(b *bytes.Buffer) *bytes.Buffer {
+//		b.Reset()
+//		return b
+//	})
+//
+//	buf := buffers.Get()
+//	defer buffers.Put(buf)
+type Pool[T any] struct {
+	pool   sync.Pool
+	newFn  func() T
+	reset  func(T) T
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// NewPool creates a new Pool that creates objects with newFn when there is no object to reuse.
+// The optional reset is called on every reused object returned by Get, e.g. to clear a buffer.
+func NewPool[T any](newFn func() T, reset ...func(T) T) *Pool[T] {
+	p := &Pool[T]{newFn: newFn}
+	if len(reset) > 0 {
+		p.reset = reset[0]
+	}
+	return p
+}
+
+// Get returns an object from the pool or a new one created with the constructor
+// if there is no object to reuse.
+func (p *Pool[T]) Get() T {
+	if v, ok := p.pool.Get().(T); ok {
+		p.hits.Add(1)
+		if p.reset != nil {
+			v = p.reset(v)
+		}
+		return v
+	}
+
+	p.misses.Add(1)
+	if p.newFn == nil {
+		var zero T
+		return zero
+	}
+	return p.newFn()
+}
+
+// Put returns an object to the pool to be reused by Get.
+// The object must not be used after it is returned to the pool.
+func (p *Pool[T]) Put(v T) {
+	p.pool.Put(v)
+}
+
+// Stats returns the number of objects reused by Get (hits) and created by the constructor (misses).
+func (p *Pool[T]) Stats() (hits, misses uint64) {
+	return p.hits.Load(), p.misses.Load()
+}
@@ -369,3 +369,168 @@ func TestRateProcessorCancellation(t *testing.T) {
 		t.Errorf("Expected no errors due to immediate cancellation, got %v", len(errors))
 	}
 }
+
+func TestLazy(t *testing.T) {
+	var calls atomic.Int64
+	get := abstract.Lazy(func() int {
+		calls.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return 42
+	})
+
+	if calls.Load() != 0 {
+		t.Fatal("Expected init not to be called before first use")
+	}
+
+	done := make(chan int, 10)
+	for range 10 {
+		go func() { done <- get() }()
+	}
+	for range 10 {
+		if v := <-done; v != 42 {
+			t.Errorf("Expected 42, got %d", v)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected init to be called once, got %d", calls.Load())
+	}
+}
+
+func TestLazyErr(t *testing.T) {
+	var calls atomic.Int64
+	testErr := errors.New("init failed")
+	get := abstract.LazyErr(func() (string, error) {
+		calls.Add(1)
+		return "", testErr
+	})
+
+	for range 3 {
+		if _, err := get(); !errors.Is(err, testErr) {
+			t.Errorf("Expected init error, got %v", err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected init to be called once, got %d", calls.Load())
+	}
+
+	getOK := abstract.LazyErr(func() (string, error) { return "ok", nil })
+	if v, err := getOK(); err != nil || v != "ok" {
+		t.Errorf("Expected ok, got %q %v", v, err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	testErr := errors.New("temporary")
+
+	var calls int
+	var delays []int
+	v, err := abstract.Retry(5, func(attempt int) time.Duration {
+		delays = append(delays, attempt)
+		return time.Millisecond
+	}, func() (int, error) {
+		calls++
+		if calls < 3 {
+			return 0, testErr
+		}
+		return 42, nil
+	})
+	if err != nil || v != 42 {
+		t.Errorf("Expected 42, got %d %v", v, err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+	if len(delays) != 2 || delays[0] != 1 || delays[1] != 2 {
+		t.Errorf("Expected backoff for attempts 1 and 2, got %v", delays)
+	}
+
+	calls = 0
+	_, err = abstract.Retry(3, nil, func() (string, error) {
+		calls++
+		return "", testErr
+	})
+	if !errors.Is(err, testErr) || calls != 3 {
+		t.Errorf("Expected last error after 3 calls, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	_, _ = abstract.Retry(0, nil, func() (string, error) {
+		calls++
+		return "", testErr
+	})
+	if calls != 1 {
+		t.Errorf("Expected single call for non-positive attempts, got %d", calls)
+	}
+}
+
+func TestRetryCtx(t *testing.T) {
+	testErr := errors.New("temporary")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var calls atomic.Int64
+	start := time.Now()
+	_, err := abstract.RetryCtx(ctx, 100, func(int) time.Duration { return 20 * time.Millisecond }, func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		return 0, testErr
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, testErr) {
+		t.Errorf("Expected context and last errors, got %v", err)
+	}
+	if time.Since(start) > time.Second || calls.Load() >= 100 {
+		t.Errorf("Expected retry to stop on context cancellation, got %d calls", calls.Load())
+	}
+
+	canceled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	calls.Store(0)
+	_, err = abstract.RetryCtx(canceled, 3, nil, func(context.Context) (int, error) {
+		calls.Add(1)
+		return 1, nil
+	})
+	if !errors.Is(err, context.Canceled) || calls.Load() != 0 {
+		t.Errorf("Expected no calls with canceled context, got %d calls and %v", calls.Load(), err)
+	}
+}
+
+func TestPool(t *testing.T) {
+	var resets int
+	pool := abstract.NewPool(func() *[]byte {
+		b := make([]byte, 0, 64)
+		return &b
+	}, func(b *[]byte) *[]byte {
+		resets++
+		*b = (*b)[:0]
+		return b
+	})
+
+	first := pool.Get()
+	if first == nil || cap(*first) != 64 {
+		t.Fatal("Expected a new object from the constructor")
+	}
+	*first = append(*first, "data"...)
+	pool.Put(first)
+
+	const gets = 10
+	for range gets {
+		b := pool.Get()
+		if len(*b) != 0 {
+			t.Errorf("Expected reused object to be reset, got %q", *b)
+		}
+		pool.Put(b)
+	}
+
+	hits, misses := pool.Stats()
+	if hits+misses != gets+1 {
+		t.Errorf("Expected %d gets in stats, got %d hits and %d misses", gets+1, hits, misses)
+	}
+	if misses < 1 || uint64(resets) != hits {
+		t.Errorf("Expected reset on every hit, got %d resets, %d hits, %d misses", resets, hits, misses)
+	}
+
+	empty := abstract.NewPool[*int](nil)
+	if v := empty.Get(); v != nil {
+		t.Errorf("Expected zero value without constructor, got %v", v)
+	}
+}
@@ -4,6 +4,7 @@ package abstract
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/ecdh"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/hmac"
@@ -13,11 +14,14 @@ import (
 	"crypto/subtle"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
 	"math/big"
+	"os"
+	"sync"
 )
 
 // NewEncryptionKey generates a cryptographically secure random 256-bit key
@@ -160,6 +164,409 @@ func DecryptAES(ciphertext []byte, key *[32]byte) (plaintext []byte, err error)
 	)
 }
 
+// EnvelopeEncrypt encrypts data using envelope encryption: a random 256-bit data key is generated,
+// the plaintext is encrypted with the data key and the data key is encrypted (wrapped) with the master key.
+// Both encryptions use AES-256-GCM (see EncryptAES).
+//
+// Envelope encryption allows rotating the master key by re-wrapping only the small data key
+// instead of re-encrypting the bulk data: decrypt the wrapped key with the old master key using DecryptAES
+// and encrypt it with the new master key using EncryptAES.
+//
+// Security considerations:
+//   - A new data key is generated for every call, so the master key encrypts only 32-byte data keys
+//   - The data key is zeroed out after use
+//   - The wrapped key and the ciphertext are both authenticated
+//
+// Parameters:
+//   - plaintext: The data to encrypt (can be any length)
+//   - masterKey: A 32-byte key used to wrap the data key (use NewEncryptionKey() to generate)
+//
+// Returns:
+//   - wrappedKey: The data key encrypted with the master key
+//   - ciphertext: The data encrypted with the data key
+//   - err: Any error that occurred during encryption
+//
+// Example usage:
+//
+//	masterKey := NewEncryptionKey()
+//	wrappedKey, ciphertext, err := EnvelopeEncrypt([]byte("large document"), masterKey)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	// Store wrappedKey alongside ciphertext
+func EnvelopeEncrypt(plaintext []byte, masterKey *[32]byte) (wrappedKey, ciphertext []byte, err error) {
+	if masterKey == nil {
+		return nil, nil, errors.New("master key is nil")
+	}
+
+	dataKey := [32]byte{}
+	if _, err := io.ReadFull(rand.Reader, dataKey[:]); err != nil {
+		return nil, nil, fmt.Errorf("generate data key: %w", err)
+	}
+	defer clear(dataKey[:])
+
+	ciphertext, err = EncryptAES(plaintext, &dataKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encrypt data: %w", err)
+	}
+
+	wrappedKey, err = EncryptAES(dataKey[:], masterKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("wrap data key: %w", err)
+	}
+
+	return wrappedKey, ciphertext, nil
+}
+
+// EnvelopeDecrypt decrypts data that was encrypted with EnvelopeEncrypt.
+// It unwraps the data key with the master key and decrypts the ciphertext with the data key.
+//
+// Security considerations:
+//   - Returns an error if the wrapped key or the ciphertext has been tampered with
+//   - The data key is zeroed out after use
+//
+// Parameters:
+//   - wrappedKey: The wrapped data key (as returned by EnvelopeEncrypt)
+//   - ciphertext: The encrypted data (as returned by EnvelopeEncrypt)
+//   - masterKey: The same 32-byte master key used for encryption
+//
+// Returns:
+//   - plaintext: The decrypted data
+//   - err: Any error that occurred during decryption or authentication
+//
+// Example usage:
+//
+//	plaintext, err := EnvelopeDecrypt(wrappedKey, ciphertext, masterKey)
+//	if err != nil {
+//		log.Fatal("Decryption failed:", err)
+//	}
+func EnvelopeDecrypt(wrappedKey, ciphertext []byte, masterKey *[32]byte) (plaintext []byte, err error) {
+	if masterKey == nil {
+		return nil, errors.New("master key is nil")
+	}
+
+	rawKey, err := DecryptAES(wrappedKey, masterKey)
+	if err != nil {
+		return nil, fmt.Errorf("unwrap data key: %w", err)
+	}
+	defer clear(rawKey)
+
+	if len(rawKey) != 32 {
+		return nil, errors.New("malformed data key")
+	}
+	dataKey := [32]byte(rawKey)
+	defer clear(dataKey[:])
+
+	plaintext, err = DecryptAES(ciphertext, &dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt data: %w", err)
+	}
+
+	return plaintext, nil
+}
+
+// NonceCounter encrypts data with 256-bit AES-GCM like EncryptAES, but uses a 96-bit counter
+// instead of a random nonce, so a nonce is never reused by the same NonceCounter.
+// The output format is the same as of EncryptAES, so the data can be decrypted with DecryptAES.
+//
+// Security considerations:
+//   - Random nonces have a birthday-bound collision risk after about 2^32 messages, counters have not
+//   - The counter is kept in memory, so the key MUST NOT be used by another NonceCounter or EncryptAES
+//     unless the counter is persisted with Counter and restored when creating a new NonceCounter
+//   - Encrypt refuses to encrypt after all 2^96 nonces are used
+//
+// Example usage:
+//
+//	key := NewEncryptionKey()
+//	enc, err := NewNonceCounter(key)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	ciphertext, err := enc.Encrypt([]byte("message"))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	plaintext, err := DecryptAES(ciphertext, key)
+type NonceCounter struct {
+	gcm cipher.AEAD
+
+	mu        sync.Mutex
+	counter   [12]byte
+	exhausted bool
+}
+
+// NewNonceCounter creates a new NonceCounter with the provided key.
+// The optional start nonce is the first nonce to use, it is the value returned by Counter
+// of the previous NonceCounter with the same key. The counter starts from zero by default.
+func NewNonceCounter(key *[32]byte, startNonceRaw ...[12]byte) (*NonceCounter, error) {
+	if key == nil {
+		return nil, errors.New("key is nil")
+	}
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nc := &NonceCounter{gcm: gcm}
+	if len(startNonceRaw) > 0 {
+		nc.counter = startNonceRaw[0]
+	}
+	return nc, nil
+}
+
+// Encrypt encrypts and authenticates the plaintext using the next counter value as the nonce.
+// The output format is: nonce || ciphertext || tag.
+// Returns an error if the plaintext is nil or the counter is exhausted.
+// It is safe for concurrent/parallel use.
+func (nc *NonceCounter) Encrypt(plaintext []byte) ([]byte, error) {
+	if plaintext == nil {
+		return nil, errors.New("plaintext is nil")
+	}
+
+	nonce, err := nc.next()
+	if err != nil {
+		return nil, err
+	}
+
+	return nc.gcm.Seal(nonce[:], nonce[:], plaintext, nil), nil
+}
+
+// Counter returns the nonce that will be used by the next Encrypt call.
+// It should be persisted to continue encryption with the same key after restart.
+// It is safe for concurrent/parallel use.
+func (nc *NonceCounter) Counter() [12]byte {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	return nc.counter
+}
+
+// next returns the current counter value and increments the counter.
+func (nc *NonceCounter) next() ([12]byte, error) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	if nc.exhausted {
+		return [12]byte{}, errors.New("nonce counter is exhausted")
+	}
+
+	nonce := nc.counter
+	for i := len(nc.counter) - 1; i >= 0; i-- {
+		nc.counter[i]++
+		if nc.counter[i] != 0 {
+			return nonce, nil
+		}
+	}
+	// All bytes have overflowed, the counter has wrapped to zero
+	nc.exhausted = true
+
+	return nonce, nil
+}
+
+// NewSIVKey generates a cryptographically secure random 512-bit key
+// for use with EncryptSIV and DecryptSIV functions.
+// The first half of the key is used for authentication (S2V), the second half for encryption (CTR).
+// Panics if the system's secure random number generator fails.
+func NewSIVKey() *[64]byte {
+	key := [64]byte{}
+	_, err := io.ReadFull(rand.Reader, key[:])
+	if err != nil {
+		panic(err)
+	}
+	return &key
+}
+
+// EncryptSIV encrypts data using AES-256-SIV (RFC 5297), a deterministic authenticated encryption mode:
+// the same plaintext and associated data always produce the same ciphertext with the same key.
+//
+// The output format is: synthetic IV (16 bytes) || ciphertext
+// where || indicates concatenation.
+//
+// Security considerations:
+//   - Nonce-misuse resistant: no nonce is used, the IV is derived from the key, data and associated data
+//   - Equal plaintexts with equal associated data are detectable as equal ciphertexts,
+//     add a unique value to aad if that is not acceptable
+//   - The associated data is authenticated but not encrypted, it must be provided again to decrypt
+//   - The associated data is always passed to S2V as exactly one component, so nil aad is encoded
+//     as one empty component and not as no associated data. Implementations that treat these cases
+//     differently produce other ciphertexts for nil aad and do not interoperate in that case
+//
+// Parameters:
+//   - plaintext: The data to encrypt (can be any length)
+//   - aad: The associated data to authenticate (can be nil, that is the same as empty)
+//   - key: A 64-byte key (use NewSIVKey() to generate)
+//
+// Returns:
+//   - ciphertext: The synthetic IV followed by the encrypted data
+//   - error: Any error that occurred during encryption
+//
+// Example usage:
+//
+//	key := NewSIVKey()
+//	ciphertext, err := EncryptSIV([]byte("user@example.com"), []byte("email"), key)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+func EncryptSIV(plaintext, aad []byte, key *[64]byte) (ciphertext []byte, err error) {
+	if plaintext == nil {
+		return nil, errors.New("plaintext is nil")
+	}
+	if key == nil {
+		return nil, errors.New("key is nil")
+	}
+
+	macBlock, ctrBlock, err := sivCiphers(key)
+	if err != nil {
+		return nil, err
+	}
+
+	v := s2v(macBlock, aad, plaintext)
+	ciphertext = make([]byte, aes.BlockSize+len(plaintext))
+	copy(ciphertext, v[:])
+	sivCTR(ctrBlock, v, ciphertext[aes.BlockSize:], plaintext)
+
+	return ciphertext, nil
+}
+
+// DecryptSIV decrypts data that was encrypted with EncryptSIV using AES-256-SIV (RFC 5297)
+// and verifies its authenticity together with the associated data.
+//
+// The input must be in the format: synthetic IV (16 bytes) || ciphertext
+// (as produced by EncryptSIV).
+//
+// Returns an error if the data or the associated data has been tampered with.
+//
+// Example usage:
+//
+//	plaintext, err := DecryptSIV(ciphertext, []byte("email"), key)
+//	if err != nil {
+//		log.Fatal("Decryption failed:", err)
+//	}
+func DecryptSIV(ciphertext, aad []byte, key *[64]byte) (plaintext []byte, err error) {
+	if ciphertext == nil {
+		return nil, errors.New("ciphertext is nil")
+	}
+	if key == nil {
+		return nil, errors.New("key is nil")
+	}
+	if len(ciphertext) < aes.BlockSize {
+		return nil, errors.New("malformed ciphertext")
+	}
+
+	macBlock, ctrBlock, err := sivCiphers(key)
+	if err != nil {
+		return nil, err
+	}
+
+	var v [aes.BlockSize]byte
+	copy(v[:], ciphertext[:aes.BlockSize])
+	plaintext = make([]byte, len(ciphertext)-aes.BlockSize)
+	sivCTR(ctrBlock, v, plaintext, ciphertext[aes.BlockSize:])
+
+	expected := s2v(macBlock, aad, plaintext)
+	if subtle.ConstantTimeCompare(v[:], expected[:]) != 1 {
+		clear(plaintext)
+		return nil, errors.New("message authentication failed")
+	}
+	return plaintext, nil
+}
+
+// sivCiphers creates the AES ciphers for S2V and CTR from the halves of the SIV key.
+func sivCiphers(key *[64]byte) (macBlock, ctrBlock cipher.Block, err error) {
+	macBlock, err = aes.NewCipher(key[:32])
+	if err != nil {
+		return nil, nil, err
+	}
+	ctrBlock, err = aes.NewCipher(key[32:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return macBlock, ctrBlock, nil
+}
+
+// sivCTR encrypts or decrypts src into dst using AES-CTR with the counter derived from the synthetic IV.
+func sivCTR(block cipher.Block, v [aes.BlockSize]byte, dst, src []byte) {
+	// Bits 31 and 63 are cleared to allow 64-bit counter implementations (RFC 5297, section 2.6)
+	v[8] &= 0x7f
+	v[12] &= 0x7f
+	cipher.NewCTR(block, v[:]).XORKeyStream(dst, src)
+}
+
+// s2v is the S2V pseudo-random function of RFC 5297 for the associated data and the plaintext.
+func s2v(block cipher.Block, aad, plaintext []byte) [aes.BlockSize]byte {
+	var zero [aes.BlockSize]byte
+	d := cmac(block, zero[:])
+	d = sivDouble(d)
+	ad := cmac(block, aad)
+	subtle.XORBytes(d[:], d[:], ad[:])
+
+	var t []byte
+	if len(plaintext) >= aes.BlockSize {
+		t = append([]byte{}, plaintext...)
+		tail := t[len(t)-aes.BlockSize:]
+		subtle.XORBytes(tail, tail, d[:])
+	} else {
+		d = sivDouble(d)
+		padded := make([]byte, aes.BlockSize)
+		copy(padded, plaintext)
+		padded[len(plaintext)] = 0x80
+		subtle.XORBytes(padded, padded, d[:])
+		t = padded
+	}
+	return cmac(block, t)
+}
+
+// cmac computes AES-CMAC (RFC 4493) of the message.
+func cmac(block cipher.Block, msg []byte) [aes.BlockSize]byte {
+	var l [aes.BlockSize]byte
+	block.Encrypt(l[:], l[:])
+	k1 := sivDouble(l)
+	k2 := sivDouble(k1)
+
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	complete := n > 0 && len(msg)%aes.BlockSize == 0
+	if n == 0 {
+		n = 1
+	}
+
+	var last [aes.BlockSize]byte
+	tail := msg[(n-1)*aes.BlockSize:]
+	if complete {
+		subtle.XORBytes(last[:], tail, k1[:])
+	} else {
+		copy(last[:], tail)
+		last[len(tail)] = 0x80
+		subtle.XORBytes(last[:], last[:], k2[:])
+	}
+
+	var x [aes.BlockSize]byte
+	for i := 0; i < n-1; i++ {
+		subtle.XORBytes(x[:], x[:], msg[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		block.Encrypt(x[:], x[:])
+	}
+	subtle.XORBytes(x[:], x[:], last[:])
+	block.Encrypt(x[:], x[:])
+	return x
+}
+
+// sivDouble multiplies the block by x in GF(2^128) as defined in RFC 5297.
+func sivDouble(b [aes.BlockSize]byte) [aes.BlockSize]byte {
+	var out [aes.BlockSize]byte
+	carry := b[0] >> 7
+	for i := 0; i < aes.BlockSize-1; i++ {
+		out[i] = b[i]<<1 | b[i+1]>>7
+	}
+	out[aes.BlockSize-1] = b[aes.BlockSize-1] << 1
+	out[aes.BlockSize-1] ^= 0x87 * carry
+	return out
+}
+
 // HashHMAC generates a keyed hash of data using HMAC-SHA-512/256.
 // This is suitable for data integrity verification and key derivation,
 // but NOT for password hashing (use bcrypt, scrypt, or Argon2 for passwords).
@@ -372,6 +779,223 @@ func EncodePrivateKey(key *ecdsa.PrivateKey) ([]byte, error) {
 	return pem.EncodeToMemory(keyBlock), nil
 }
 
+// EncodeEncryptionKey encodes a 256-bit key from NewEncryptionKey to a base64 string.
+// The output is suitable for storage in config files or environment variables.
+//
+// Security considerations:
+//   - The encoded key is NOT protected, it should be stored as securely as the key itself
+//
+// Parameters:
+//   - key: The 256-bit encryption key to encode
+//
+// Returns:
+//   - Base64-encoded key string, or empty string if key is nil
+//
+// Example usage:
+//
+//	key := NewEncryptionKey()
+//	encoded := EncodeEncryptionKey(key)
+//	// Store encoded securely
+func EncodeEncryptionKey(key *[32]byte) string {
+	if key == nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(key[:])
+}
+
+// DecodeEncryptionKey decodes a base64-encoded 256-bit key.
+// This is the reverse operation of EncodeEncryptionKey.
+//
+// Parameters:
+//   - encodedKey: Base64-encoded key string
+//
+// Returns:
+//   - A pointer to a 32-byte array containing the encryption key
+//   - An error if the key cannot be decoded or has invalid length
+//
+// Example usage:
+//
+//	key, err := DecodeEncryptionKey(os.Getenv("ENCRYPTION_KEY"))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	plaintext, err := DecryptAES(ciphertext, key)
+func DecodeEncryptionKey(encodedKey string) (*[32]byte, error) {
+	if encodedKey == "" {
+		return nil, errors.New("encoded key is empty")
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return encryptionKeyFromBytes(raw)
+}
+
+// EncodeEncryptionKeyPEM encodes a 256-bit key from NewEncryptionKey to PEM format
+// with type "AES KEY".
+//
+// Security considerations:
+//   - The encoded key is NOT protected, it should be stored as securely as the key itself
+//
+// Parameters:
+//   - key: The 256-bit encryption key to encode
+//
+// Returns:
+//   - PEM-encoded key bytes
+//   - An error if the key is nil
+//
+// Example usage:
+//
+//	key := NewEncryptionKey()
+//	pemData, err := EncodeEncryptionKeyPEM(key)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	// Store pemData securely
+func EncodeEncryptionKeyPEM(key *[32]byte) ([]byte, error) {
+	if key == nil {
+		return nil, errors.New("key is nil")
+	}
+
+	block := &pem.Block{
+		Type:  "AES KEY",
+		Bytes: key[:],
+	}
+
+	return pem.EncodeToMemory(block), nil
+}
+
+// DecodeEncryptionKeyPEM decodes a PEM-encoded 256-bit key.
+// The input should be a PEM block with type "AES KEY".
+//
+// Parameters:
+//   - encodedKey: PEM-encoded key bytes
+//
+// Returns:
+//   - A pointer to a 32-byte array containing the encryption key
+//   - An error if the key cannot be decoded or has invalid length
+//
+// Example usage:
+//
+//	key, err := DecodeEncryptionKeyPEM(pemData)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+func DecodeEncryptionKeyPEM(encodedKey []byte) (*[32]byte, error) {
+	if len(encodedKey) == 0 {
+		return nil, errors.New("encoded key is empty")
+	}
+
+	block, _ := pem.Decode(encodedKey)
+	if block == nil || block.Type != "AES KEY" {
+		return nil, errors.New("marshal: could not decode PEM block or not an AES KEY")
+	}
+
+	return encryptionKeyFromBytes(block.Bytes)
+}
+
+func encryptionKeyFromBytes(raw []byte) (*[32]byte, error) {
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("invalid key length: expected 32 bytes, got %d", len(raw))
+	}
+
+	key := [32]byte{}
+	copy(key[:], raw)
+	return &key, nil
+}
+
+// PublicKeysEqual reports whether two ECDSA public keys are identical,
+// comparing their curves and points. The coordinates are compared in constant time.
+//
+// Parameters:
+//   - a, b: The ECDSA public keys to compare
+//
+// Returns:
+//   - true if both keys are non-nil and identical, false otherwise
+//
+// Example usage:
+//
+//	if !PublicKeysEqual(&privKey.PublicKey, trustedKey) {
+//		return errors.New("untrusted key")
+//	}
+func PublicKeysEqual(a, b *ecdsa.PublicKey) bool {
+	if a == nil || b == nil || a.Curve == nil || b.Curve == nil || a.X == nil || b.X == nil || a.Y == nil || b.Y == nil {
+		return false
+	}
+	if a.Curve.Params().Name != b.Curve.Params().Name || a.Curve.Params().BitSize != b.Curve.Params().BitSize {
+		return false
+	}
+
+	size := (a.Curve.Params().BitSize + 7) / 8
+	return constantTimeIntEqual(a.X, b.X, size)&constantTimeIntEqual(a.Y, b.Y, size) == 1
+}
+
+// PrivateKeysEqual reports whether two ECDSA private keys are identical,
+// comparing their public parts and private scalars. The values are compared in constant time.
+//
+// Parameters:
+//   - a, b: The ECDSA private keys to compare
+//
+// Returns:
+//   - true if both keys are non-nil and identical, false otherwise
+//
+// Example usage:
+//
+//	if PrivateKeysEqual(loadedKey, expectedKey) {
+//		fmt.Println("Key was restored correctly")
+//	}
+func PrivateKeysEqual(a, b *ecdsa.PrivateKey) bool {
+	if a == nil || b == nil || a.D == nil || b.D == nil {
+		return false
+	}
+	if !PublicKeysEqual(&a.PublicKey, &b.PublicKey) {
+		return false
+	}
+
+	size := (a.Curve.Params().BitSize + 7) / 8
+	return constantTimeIntEqual(a.D, b.D, size) == 1
+}
+
+// PEMKeysEqual decodes two PEM-encoded ECDSA keys and reports whether they are identical.
+// Both inputs must be either public keys ("PUBLIC KEY") or private keys ("EC PRIVATE KEY"),
+// so the result does not depend on the formatting of the PEM data, e.g. headers or line breaks.
+//
+// Parameters:
+//   - a, b: PEM-encoded keys to compare
+//
+// Returns:
+//   - true if both keys can be decoded and are identical, false otherwise
+//
+// Example usage:
+//
+//	stored, _ := os.ReadFile("key.pem")
+//	if !PEMKeysEqual(stored, received) {
+//		log.Println("Key has changed")
+//	}
+func PEMKeysEqual(a, b []byte) bool {
+	if pubA, err := DecodePublicKey(a); err == nil {
+		pubB, err := DecodePublicKey(b)
+		return err == nil && PublicKeysEqual(pubA, pubB)
+	}
+
+	privA, err := DecodePrivateKey(a)
+	if err != nil {
+		return false
+	}
+	privB, err := DecodePrivateKey(b)
+	return err == nil && PrivateKeysEqual(privA, privB)
+}
+
+// constantTimeIntEqual returns 1 if the non-negative integers are equal when encoded to size bytes and 0 otherwise.
+func constantTimeIntEqual(a, b *big.Int, size int) int {
+	if a.Sign() < 0 || b.Sign() < 0 || a.BitLen() > size*8 || b.BitLen() > size*8 {
+		return 0
+	}
+	return subtle.ConstantTimeCompare(a.FillBytes(make([]byte, size)), b.FillBytes(make([]byte, size)))
+}
+
 // EncodeSignatureJWT encodes an ECDSA signature for use in JWT tokens.
 // This follows the JWT specification (RFC 7515, Appendix A.3.1) for
 // ECDSA signature encoding.
@@ -552,6 +1176,103 @@ func NewSigningKey() (*ecdsa.PrivateKey, error) {
 	return key, err
 }
 
+// signingKeySeedMinSize is the minimal size of the seed for NewSigningKeyFromSeed.
+const signingKeySeedMinSize = 16
+
+// signingKeySeedInfo is the HKDF context string used to derive signing keys from seeds.
+var signingKeySeedInfo = []byte("abstract: ECDSA P-256 signing key from seed")
+
+// NewSigningKeyFromSeed deterministically derives a P-256 ECDSA private key from the seed.
+// The same seed always yields the same key, so it is useful for reproducible test fixtures
+// and hierarchical key derivation. It is NOT a replacement for NewSigningKey:
+// the key is only as secret as the seed it was derived from.
+//
+// Security considerations:
+//   - The private scalar is derived using HKDF-SHA256 over the seed and reduced into [1, N-1]
+//   - The seed must be at least 16 bytes long and should have enough entropy
+//   - Anyone who knows the seed can recreate the private key
+//
+// Parameters:
+//   - seed: The secret seed to derive the key from (at least 16 bytes)
+//
+// Returns:
+//   - A deterministic ECDSA private key
+//   - An error if the seed is too short or key derivation fails
+//
+// Example usage:
+//
+//	seed := []byte("0123456789abcdef-test-fixture")
+//	privKey, err := NewSigningKeyFromSeed(seed)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//
+//	// Always the same key for the same seed
+//	signature, _ := SignData([]byte("document"), privKey)
+func NewSigningKeyFromSeed(seed []byte) (*ecdsa.PrivateKey, error) {
+	if len(seed) < signingKeySeedMinSize {
+		return nil, fmt.Errorf("seed is too short: need at least %d bytes", signingKeySeedMinSize)
+	}
+
+	curve := elliptic.P256()
+	params := curve.Params()
+
+	// Take 64 extra bits to make the modular bias negligible
+	okm := hkdfSHA256(seed, nil, signingKeySeedInfo, params.N.BitLen()/8+8)
+
+	// d = okm mod (N-1) + 1, so that 1 <= d <= N-1
+	nMinusOne := new(big.Int).Sub(params.N, big.NewInt(1))
+	d := new(big.Int).SetBytes(okm)
+	d.Mod(d, nMinusOne)
+	d.Add(d, big.NewInt(1))
+
+	scalar := make([]byte, params.N.BitLen()/8)
+	d.FillBytes(scalar)
+
+	ecdhKey, err := ecdh.P256().NewPrivateKey(scalar)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %w", err)
+	}
+
+	// Uncompressed point encoding: 0x04 || X || Y
+	point := ecdhKey.PublicKey().Bytes()
+	coordSize := (len(point) - 1) / 2
+
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(point[1 : 1+coordSize]),
+			Y:     new(big.Int).SetBytes(point[1+coordSize:]),
+		},
+		D: d,
+	}, nil
+}
+
+// hkdfSHA256 implements HKDF (RFC 5869) with SHA-256 and returns length bytes of output keying material.
+func hkdfSHA256(secret, salt, info []byte, length int) []byte {
+	if len(salt) == 0 {
+		salt = make([]byte, sha256.Size)
+	}
+	extractor := hmac.New(sha256.New, salt)
+	extractor.Write(secret)
+	prk := extractor.Sum(nil)
+
+	var (
+		out      = make([]byte, 0, length+sha256.Size)
+		prev     []byte
+		expander = hmac.New(sha256.New, prk)
+	)
+	for counter := byte(1); len(out) < length; counter++ {
+		expander.Reset()
+		expander.Write(prev)
+		expander.Write(info)
+		expander.Write([]byte{counter})
+		prev = expander.Sum(nil)
+		out = append(out, prev...)
+	}
+	return out[:length]
+}
+
 // SignData creates a digital signature for arbitrary data using ECDSA.
 // The signature can be verified using VerifySign with the corresponding public key.
 //
@@ -595,8 +1316,12 @@ func SignData(data []byte, privkey *ecdsa.PrivateKey) ([]byte, error) {
 	// hash message
 	digest := sha256.Sum256(data)
 
+	return signDigest(digest[:], privkey)
+}
+
+func signDigest(digest []byte, privkey *ecdsa.PrivateKey) ([]byte, error) {
 	// sign the hash
-	r, s, err := ecdsa.Sign(rand.Reader, privkey, digest[:])
+	r, s, err := ecdsa.Sign(rand.Reader, privkey, digest)
 	if err != nil {
 		return nil, err
 	}
@@ -658,6 +1383,10 @@ func VerifySign(data, signature []byte, pubkey *ecdsa.PublicKey) bool {
 	// hash message
 	digest := sha256.Sum256(data)
 
+	return verifyDigest(digest[:], signature, pubkey)
+}
+
+func verifyDigest(digest, signature []byte, pubkey *ecdsa.PublicKey) bool {
 	curveOrderByteSize := pubkey.Curve.Params().P.BitLen() / 8
 
 	if len(signature) < curveOrderByteSize*2 {
@@ -675,5 +1404,179 @@ func VerifySign(data, signature []byte, pubkey *ecdsa.PublicKey) bool {
 		return false
 	}
 
-	return ecdsa.Verify(pubkey, digest[:], r, s)
+	return ecdsa.Verify(pubkey, digest, r, s)
+}
+
+// signatureMagic is the prefix of the detached signature format.
+var signatureMagic = [4]byte{'A', 'S', 'I', 'G'}
+
+// SignatureAlgECDSASHA256 is the algorithm identifier of signatures created by [SignData] and [SignFile].
+const SignatureAlgECDSASHA256 byte = 1
+
+// maxSignatureLength limits the length of a signature read by [ReadSignature].
+const maxSignatureLength = 1024
+
+// WriteSignature writes a detached signature to the writer in a small self-describing format:
+// 4 bytes of magic ("ASIG"), 1 byte of algorithm, 2 bytes of big-endian length and the signature itself.
+//
+// Parameters:
+//   - w: The destination writer, e.g. a file with ".sig" extension
+//   - sig: The signature as returned by SignData or SignFile
+//
+// Returns:
+//   - An error if the signature is empty, too long or writing fails
+//
+// Example usage:
+//
+//	sig, _ := SignFile("release.tar.gz", privKey)
+//	f, _ := os.Create("release.tar.gz.sig")
+//	defer f.Close()
+//	if err := WriteSignature(f, sig); err != nil {
+//		log.Fatal(err)
+//	}
+func WriteSignature(w io.Writer, sig []byte) error {
+	if len(sig) == 0 {
+		return errors.New("signature is empty")
+	}
+	if len(sig) > maxSignatureLength {
+		return fmt.Errorf("signature is too long: %d bytes", len(sig))
+	}
+
+	buf := make([]byte, 0, len(signatureMagic)+3+len(sig))
+	buf = append(buf, signatureMagic[:]...)
+	buf = append(buf, SignatureAlgECDSASHA256)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(sig)))
+	buf = append(buf, sig...)
+
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadSignature reads a detached signature written by WriteSignature.
+//
+// Parameters:
+//   - r: The source reader
+//
+// Returns:
+//   - The raw signature bytes
+//   - An error if the data is not a valid signature or reading fails
+//
+// Example usage:
+//
+//	f, _ := os.Open("release.tar.gz.sig")
+//	defer f.Close()
+//	sig, err := ReadSignature(f)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+func ReadSignature(r io.Reader) ([]byte, error) {
+	var header [len(signatureMagic) + 3]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("read signature header: %w", err)
+	}
+	if [4]byte(header[:len(signatureMagic)]) != signatureMagic {
+		return nil, errors.New("invalid signature magic")
+	}
+	if alg := header[len(signatureMagic)]; alg != SignatureAlgECDSASHA256 {
+		return nil, fmt.Errorf("unsupported signature algorithm: %d", alg)
+	}
+
+	length := int(binary.BigEndian.Uint16(header[len(signatureMagic)+1:]))
+	if length == 0 || length > maxSignatureLength {
+		return nil, fmt.Errorf("invalid signature length: %d", length)
+	}
+
+	sig := make([]byte, length)
+	if _, err := io.ReadFull(r, sig); err != nil {
+		return nil, fmt.Errorf("read signature: %w", err)
+	}
+	return sig, nil
+}
+
+// SignFile creates a detached ECDSA signature of the file.
+// The file is streamed through SHA-256, so it is not loaded into memory.
+// The signature is compatible with VerifySign over the whole file content.
+//
+// Parameters:
+//   - path: The path to the file to sign
+//   - privkey: The ECDSA private key for signing
+//
+// Returns:
+//   - The raw signature bytes, use WriteSignature to store them
+//   - An error if the file cannot be read or signing fails
+//
+// Example usage:
+//
+//	sig, err := SignFile("release.tar.gz", privKey)
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+func SignFile(path string, privkey *ecdsa.PrivateKey) ([]byte, error) {
+	if privkey == nil {
+		return nil, errors.New("private key is nil")
+	}
+
+	digest, err := hashFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return signDigest(digest, privkey)
+}
+
+// VerifyFile verifies a detached signature of the file.
+// The signature is read from sigPath in the format of WriteSignature.
+//
+// Parameters:
+//   - path: The path to the signed file
+//   - sigPath: The path to the signature file
+//   - pubkey: The ECDSA public key corresponding to the signing key
+//
+// Returns:
+//   - nil if the signature is valid, an error otherwise
+//
+// Example usage:
+//
+//	if err := VerifyFile("release.tar.gz", "release.tar.gz.sig", pubKey); err != nil {
+//		log.Fatal(err)
+//	}
+func VerifyFile(path, sigPath string, pubkey *ecdsa.PublicKey) error {
+	if pubkey == nil {
+		return errors.New("public key is nil")
+	}
+
+	f, err := os.Open(sigPath)
+	if err != nil {
+		return fmt.Errorf("open signature: %w", err)
+	}
+	defer f.Close()
+
+	sig, err := ReadSignature(f)
+	if err != nil {
+		return err
+	}
+
+	digest, err := hashFile(path)
+	if err != nil {
+		return err
+	}
+
+	if !verifyDigest(digest, sig, pubkey) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func hashFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	return h.Sum(nil), nil
 }
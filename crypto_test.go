@@ -8,9 +8,12 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/pem"
 	"fmt"
 	"io"
 	"math/big"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -687,12 +690,6 @@ func TestHashCollisionResistance(t *testing.T) {
 	}
 }
 
-func compareECDSAPublicKeys(key1, key2 *ecdsa.PublicKey) bool {
-	return key1.Curve == key2.Curve &&
-		key1.X.Cmp(key2.X) == 0 &&
-		key1.Y.Cmp(key2.Y) == 0
-}
-
 func TestPrivateKeyToPubKey(t *testing.T) {
 	// Generate a signing key
 	privKey, _ := abstract.NewSigningKey()
@@ -701,7 +698,7 @@ func TestPrivateKeyToPubKey(t *testing.T) {
 	encodedPriv, _ := abstract.EncodePrivateKey(privKey)
 	decodedPriv, _ := abstract.DecodePrivateKey(encodedPriv)
 
-	if !compareECDSAPublicKeys(&privKey.PublicKey, &decodedPriv.PublicKey) {
+	if !abstract.PublicKeysEqual(&privKey.PublicKey, &decodedPriv.PublicKey) {
 		t.Error("Public key component lost during private key encoding/decoding")
 	}
 
@@ -1941,3 +1938,439 @@ func TestSignatureWithModifiedPublicKey(t *testing.T) {
 		t.Error("Signature verification should fail with modified public key")
 	}
 }
+
+func TestNewSigningKeyFromSeed(t *testing.T) {
+	seed := []byte("0123456789abcdef-test-fixture")
+
+	key1, err := abstract.NewSigningKeyFromSeed(seed)
+	if err != nil {
+		t.Fatalf("NewSigningKeyFromSeed failed: %v", err)
+	}
+	key2, err := abstract.NewSigningKeyFromSeed(seed)
+	if err != nil {
+		t.Fatalf("NewSigningKeyFromSeed failed: %v", err)
+	}
+
+	if key1.Curve != elliptic.P256() {
+		t.Error("Expected P-256 curve")
+	}
+	if !key1.Equal(key2) {
+		t.Error("Same seed should produce the same key")
+	}
+	if key1.D.Sign() <= 0 || key1.D.Cmp(elliptic.P256().Params().N) >= 0 {
+		t.Error("Private scalar is out of range")
+	}
+
+	// Public key must correspond to the private scalar
+	if _, err := key1.PublicKey.ECDH(); err != nil {
+		t.Errorf("Public key is not a valid point: %v", err)
+	}
+	ecdhKey, err := key1.ECDH()
+	if err != nil {
+		t.Fatalf("Failed to convert private key: %v", err)
+	}
+	pubECDH, _ := key1.PublicKey.ECDH()
+	if !ecdhKey.PublicKey().Equal(pubECDH) {
+		t.Error("Public key does not match private key")
+	}
+
+	data := []byte("document")
+	signature, err := abstract.SignData(data, key1)
+	if err != nil {
+		t.Fatalf("SignData failed: %v", err)
+	}
+	if !abstract.VerifySign(data, signature, &key2.PublicKey) {
+		t.Error("Signature made with derived key should be verified with the same derived key")
+	}
+
+	encoded, err := abstract.EncodePrivateKey(key1)
+	if err != nil {
+		t.Fatalf("EncodePrivateKey failed: %v", err)
+	}
+	decoded, err := abstract.DecodePrivateKey(encoded)
+	if err != nil {
+		t.Fatalf("DecodePrivateKey failed: %v", err)
+	}
+	if !decoded.Equal(key1) {
+		t.Error("Derived key should survive PEM round trip")
+	}
+
+	other, err := abstract.NewSigningKeyFromSeed([]byte("0123456789abcdef-test-fixturf"))
+	if err != nil {
+		t.Fatalf("NewSigningKeyFromSeed failed: %v", err)
+	}
+	if other.Equal(key1) {
+		t.Error("Different seeds should produce different keys")
+	}
+
+	if _, err := abstract.NewSigningKeyFromSeed([]byte("short")); err == nil {
+		t.Error("Expected error for short seed")
+	}
+	if _, err := abstract.NewSigningKeyFromSeed(nil); err == nil {
+		t.Error("Expected error for nil seed")
+	}
+}
+
+func TestEnvelopeEncryptDecrypt(t *testing.T) {
+	masterKey := abstract.NewEncryptionKey()
+	plaintext := []byte("large document that should be encrypted with a data key")
+
+	wrappedKey, ciphertext, err := abstract.EnvelopeEncrypt(plaintext, masterKey)
+	if err != nil {
+		t.Fatalf("EnvelopeEncrypt failed: %v", err)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Error("Ciphertext contains plaintext")
+	}
+
+	decrypted, err := abstract.EnvelopeDecrypt(wrappedKey, ciphertext, masterKey)
+	if err != nil {
+		t.Fatalf("EnvelopeDecrypt failed: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Expected %q, got %q", plaintext, decrypted)
+	}
+
+	// Every call must use a new data key
+	wrappedKey2, _, err := abstract.EnvelopeEncrypt(plaintext, masterKey)
+	if err != nil {
+		t.Fatalf("EnvelopeEncrypt failed: %v", err)
+	}
+	if bytes.Equal(wrappedKey, wrappedKey2) {
+		t.Error("Expected different wrapped keys for different calls")
+	}
+
+	// Master key rotation by re-wrapping the data key
+	newMasterKey := abstract.NewEncryptionKey()
+	rawDataKey, err := abstract.DecryptAES(wrappedKey, masterKey)
+	if err != nil {
+		t.Fatalf("DecryptAES failed: %v", err)
+	}
+	rewrapped, err := abstract.EncryptAES(rawDataKey, newMasterKey)
+	if err != nil {
+		t.Fatalf("EncryptAES failed: %v", err)
+	}
+	decrypted, err = abstract.EnvelopeDecrypt(rewrapped, ciphertext, newMasterKey)
+	if err != nil || !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Expected decryption with rotated master key, got %q (err %v)", decrypted, err)
+	}
+
+	// Failures
+	if _, err := abstract.EnvelopeDecrypt(wrappedKey, ciphertext, abstract.NewEncryptionKey()); err == nil {
+		t.Error("Expected error with wrong master key")
+	}
+	tampered := bytes.Clone(ciphertext)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := abstract.EnvelopeDecrypt(wrappedKey, tampered, masterKey); err == nil {
+		t.Error("Expected error with tampered ciphertext")
+	}
+	shortKey, _ := abstract.EncryptAES([]byte("short"), masterKey)
+	if _, err := abstract.EnvelopeDecrypt(shortKey, ciphertext, masterKey); err == nil {
+		t.Error("Expected error with malformed data key")
+	}
+	if _, _, err := abstract.EnvelopeEncrypt(plaintext, nil); err == nil {
+		t.Error("Expected error with nil master key")
+	}
+	if _, err := abstract.EnvelopeDecrypt(wrappedKey, ciphertext, nil); err == nil {
+		t.Error("Expected error with nil master key")
+	}
+	if _, _, err := abstract.EnvelopeEncrypt(nil, masterKey); err == nil {
+		t.Error("Expected error with nil plaintext")
+	}
+}
+
+func TestEncryptionKeyEncoding(t *testing.T) {
+	key := abstract.NewEncryptionKey()
+
+	encoded := abstract.EncodeEncryptionKey(key)
+	decoded, err := abstract.DecodeEncryptionKey(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *decoded != *key {
+		t.Error("decoded key does not match original")
+	}
+
+	pemData, err := abstract.EncodeEncryptionKeyPEM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(pemData, []byte("BEGIN AES KEY")) {
+		t.Errorf("unexpected PEM data: %s", pemData)
+	}
+	decoded, err = abstract.DecodeEncryptionKeyPEM(pemData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *decoded != *key {
+		t.Error("decoded PEM key does not match original")
+	}
+
+	ciphertext, err := abstract.EncryptAES([]byte("secret"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := abstract.DecryptAES(ciphertext, decoded)
+	if err != nil || string(plaintext) != "secret" {
+		t.Errorf("failed to decrypt with decoded key: %v", err)
+	}
+}
+
+func TestEncryptionKeyEncodingErrors(t *testing.T) {
+	if abstract.EncodeEncryptionKey(nil) != "" {
+		t.Error("Expected empty string for nil key")
+	}
+	if _, err := abstract.EncodeEncryptionKeyPEM(nil); err == nil {
+		t.Error("Expected error for nil key")
+	}
+
+	if _, err := abstract.DecodeEncryptionKey(""); err == nil {
+		t.Error("Expected error for empty input")
+	}
+	if _, err := abstract.DecodeEncryptionKey("not base64!"); err == nil {
+		t.Error("Expected error for invalid base64")
+	}
+	if _, err := abstract.DecodeEncryptionKey(base64.StdEncoding.EncodeToString(make([]byte, 16))); err == nil {
+		t.Error("Expected error for short key")
+	}
+
+	if _, err := abstract.DecodeEncryptionKeyPEM(nil); err == nil {
+		t.Error("Expected error for empty input")
+	}
+	if _, err := abstract.DecodeEncryptionKeyPEM([]byte("not a valid PEM")); err == nil {
+		t.Error("Expected error for invalid PEM data")
+	}
+	privKey, _ := abstract.NewSigningKey()
+	privPEM, _ := abstract.EncodePrivateKey(privKey)
+	if _, err := abstract.DecodeEncryptionKeyPEM(privPEM); err == nil {
+		t.Error("Expected error for wrong PEM type")
+	}
+	shortPEM := pem.EncodeToMemory(&pem.Block{Type: "AES KEY", Bytes: make([]byte, 31)})
+	if _, err := abstract.DecodeEncryptionKeyPEM(shortPEM); err == nil {
+		t.Error("Expected error for short key")
+	}
+}
+
+func TestKeysEqual(t *testing.T) {
+	key1, _ := abstract.NewSigningKey()
+	key2, _ := abstract.NewSigningKey()
+	key384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+
+	encoded, _ := abstract.EncodePrivateKey(key1)
+	decoded, _ := abstract.DecodePrivateKey(encoded)
+
+	if !abstract.PrivateKeysEqual(key1, decoded) || !abstract.PublicKeysEqual(&key1.PublicKey, &decoded.PublicKey) {
+		t.Error("Expected decoded key to be equal to original")
+	}
+	if abstract.PrivateKeysEqual(key1, key2) || abstract.PublicKeysEqual(&key1.PublicKey, &key2.PublicKey) {
+		t.Error("Expected different keys to be not equal")
+	}
+	if abstract.PrivateKeysEqual(key1, key384) || abstract.PublicKeysEqual(&key1.PublicKey, &key384.PublicKey) {
+		t.Error("Expected keys on different curves to be not equal")
+	}
+	if abstract.PrivateKeysEqual(nil, key1) || abstract.PublicKeysEqual(&key1.PublicKey, nil) {
+		t.Error("Expected nil keys to be not equal")
+	}
+
+	pub1, _ := abstract.EncodePublicKey(&key1.PublicKey)
+	pub2, _ := abstract.EncodePublicKey(&key2.PublicKey)
+	block, _ := pem.Decode(pub1)
+	block.Headers = map[string]string{"Comment": "same key"}
+	pub1Formatted := append([]byte("leading text\n"), pem.EncodeToMemory(block)...)
+
+	if !abstract.PEMKeysEqual(pub1, pub1Formatted) {
+		t.Error("Expected PEM public keys with different formatting to be equal")
+	}
+	if abstract.PEMKeysEqual(pub1, pub2) {
+		t.Error("Expected different PEM public keys to be not equal")
+	}
+
+	priv2, _ := abstract.EncodePrivateKey(key2)
+	if !abstract.PEMKeysEqual(encoded, encoded) || abstract.PEMKeysEqual(encoded, priv2) {
+		t.Error("Unexpected result for PEM private keys")
+	}
+	if abstract.PEMKeysEqual(pub1, encoded) || abstract.PEMKeysEqual(encoded, pub1) {
+		t.Error("Expected public and private PEM keys to be not equal")
+	}
+	if abstract.PEMKeysEqual(nil, nil) || abstract.PEMKeysEqual([]byte("garbage"), []byte("garbage")) {
+		t.Error("Expected invalid PEM data to be not equal")
+	}
+}
+
+func TestSignatureFile(t *testing.T) {
+	key, _ := abstract.NewSigningKey()
+	otherKey, _ := abstract.NewSigningKey()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "release.tar.gz")
+	sigPath := path + ".sig"
+	data := []byte("release artifact content")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := abstract.SignFile(path, key)
+	if err != nil {
+		t.Fatalf("SignFile failed: %v", err)
+	}
+	if !abstract.VerifySign(data, sig, &key.PublicKey) {
+		t.Error("Expected file signature to be valid for VerifySign")
+	}
+
+	var buf bytes.Buffer
+	if err := abstract.WriteSignature(&buf, sig); err != nil {
+		t.Fatalf("WriteSignature failed: %v", err)
+	}
+	if err := os.WriteFile(sigPath, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	read, err := abstract.ReadSignature(bytes.NewReader(buf.Bytes()))
+	if err != nil || !bytes.Equal(read, sig) {
+		t.Errorf("Expected read signature to match, err: %v", err)
+	}
+
+	if err := abstract.VerifyFile(path, sigPath, &key.PublicKey); err != nil {
+		t.Errorf("Expected valid file signature, got %v", err)
+	}
+	if err := abstract.VerifyFile(path, sigPath, &otherKey.PublicKey); err == nil {
+		t.Error("Expected error for wrong public key")
+	}
+	if err := os.WriteFile(path, []byte("tampered"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := abstract.VerifyFile(path, sigPath, &key.PublicKey); err == nil {
+		t.Error("Expected error for tampered file")
+	}
+	if _, err := abstract.SignFile(filepath.Join(dir, "missing"), key); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestReadSignatureErrors(t *testing.T) {
+	if err := abstract.WriteSignature(io.Discard, nil); err == nil {
+		t.Error("Expected error for empty signature")
+	}
+
+	cases := map[string][]byte{
+		"empty":     nil,
+		"bad magic": []byte("XSIG\x01\x00\x01a"),
+		"bad alg":   []byte("ASIG\x07\x00\x01a"),
+		"zero len":  []byte("ASIG\x01\x00\x00"),
+		"truncated": []byte("ASIG\x01\x00\x05ab"),
+	}
+	for name, data := range cases {
+		if _, err := abstract.ReadSignature(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestNonceCounter(t *testing.T) {
+	key := abstract.NewEncryptionKey()
+	enc, err := abstract.NewNonceCounter(key)
+	if err != nil {
+		t.Fatalf("NewNonceCounter failed: %v", err)
+	}
+
+	first, err := enc.Encrypt([]byte("message"))
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	second, _ := enc.Encrypt([]byte("message"))
+	if bytes.Equal(first[:12], second[:12]) {
+		t.Error("Expected different nonces")
+	}
+	if second[11] != 1 {
+		t.Errorf("Expected counter nonce 1, got %x", second[:12])
+	}
+	for _, ciphertext := range [][]byte{first, second} {
+		plaintext, err := abstract.DecryptAES(ciphertext, key)
+		if err != nil || string(plaintext) != "message" {
+			t.Errorf("Expected DecryptAES to decrypt, got %q, %v", plaintext, err)
+		}
+	}
+	if counter := enc.Counter(); counter[11] != 2 {
+		t.Errorf("Expected next counter 2, got %x", counter)
+	}
+	if _, err := enc.Encrypt(nil); err == nil {
+		t.Error("Expected error for nil plaintext")
+	}
+	if _, err := abstract.NewNonceCounter(nil); err == nil {
+		t.Error("Expected error for nil key")
+	}
+
+	var last [12]byte
+	for i := range last {
+		last[i] = 0xff
+	}
+	enc, _ = abstract.NewNonceCounter(key, last)
+	if _, err := enc.Encrypt([]byte("last")); err != nil {
+		t.Fatalf("Expected the last nonce to be used, got %v", err)
+	}
+	if _, err := enc.Encrypt([]byte("more")); err == nil {
+		t.Error("Expected error after counter exhaustion")
+	}
+}
+
+func TestSIV(t *testing.T) {
+	key := abstract.NewSIVKey()
+	aad := []byte("context")
+
+	for _, size := range []int{0, 1, 15, 16, 17, 100} {
+		plaintext := bytes.Repeat([]byte{'x'}, size)
+		ciphertext, err := abstract.EncryptSIV(plaintext, aad, key)
+		if err != nil {
+			t.Fatalf("EncryptSIV failed: %v", err)
+		}
+		if len(ciphertext) != 16+size {
+			t.Errorf("Expected ciphertext length %d, got %d", 16+size, len(ciphertext))
+		}
+
+		again, _ := abstract.EncryptSIV(plaintext, aad, key)
+		if !bytes.Equal(ciphertext, again) {
+			t.Error("Expected encryption to be deterministic")
+		}
+
+		decrypted, err := abstract.DecryptSIV(ciphertext, aad, key)
+		if err != nil {
+			t.Fatalf("DecryptSIV failed for size %d: %v", size, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("Expected %q, got %q", plaintext, decrypted)
+		}
+
+		if _, err := abstract.DecryptSIV(ciphertext, []byte("other"), key); err == nil {
+			t.Error("Expected error for wrong associated data")
+		}
+		for i := range ciphertext {
+			tampered := bytes.Clone(ciphertext)
+			tampered[i] ^= 0x01
+			if _, err := abstract.DecryptSIV(tampered, aad, key); err == nil {
+				t.Fatalf("Expected error for tampered byte %d", i)
+			}
+		}
+	}
+
+	a, _ := abstract.EncryptSIV([]byte("data"), nil, key)
+	b, _ := abstract.EncryptSIV([]byte("data"), []byte("aad"), key)
+	c, _ := abstract.EncryptSIV([]byte("data"), nil, abstract.NewSIVKey())
+	if bytes.Equal(a, b) || bytes.Equal(a, c) {
+		t.Error("Expected different ciphertexts for different associated data or keys")
+	}
+	if empty, _ := abstract.EncryptSIV([]byte("data"), []byte{}, key); !bytes.Equal(a, empty) {
+		t.Error("Expected nil and empty associated data to be encoded the same way")
+	}
+
+	if _, err := abstract.EncryptSIV(nil, nil, key); err == nil {
+		t.Error("Expected error for nil plaintext")
+	}
+	if _, err := abstract.EncryptSIV([]byte("x"), nil, nil); err == nil {
+		t.Error("Expected error for nil key")
+	}
+	if _, err := abstract.DecryptSIV([]byte("short"), nil, key); err == nil {
+		t.Error("Expected error for short ciphertext")
+	}
+	if _, err := abstract.DecryptSIV(nil, nil, key); err == nil {
+		t.Error("Expected error for nil ciphertext")
+	}
+}
@@ -1,15 +1,25 @@
 package abstract
 
 import (
+	"bytes"
+	"encoding"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"maps"
+	"math"
 	"os"
+	"reflect"
+	"regexp"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"unicode/utf8"
 )
 
 // CSVTable represents a table of data from a CSV file where the first column is used as the ID
@@ -25,6 +35,16 @@ type CSVTable struct {
 	headerIndex map[string]int
 	// Store rows data in a slice for each row, preserving order
 	rows [][]string
+	// Cells changed since tracking was enabled, nil if tracking is disabled
+	dirty map[CSVCellRef]struct{}
+	// Output formatters of columns used by BytesFormatted
+	formatters map[string]func(string) string
+}
+
+// CSVCellRef is a reference to a cell of a table by its row ID and column name.
+type CSVCellRef struct {
+	ID     string
+	Column string
 }
 
 // NewCSVTableFromFilePath creates a new CSVTable from a file at the given path.
@@ -49,12 +69,387 @@ func NewCSVTableFromReader(reader io.Reader) (*CSVTable, error) {
 	return NewCSVTable(records), nil
 }
 
+// CSVConflictPolicy defines how rows with the same ID from different sources are merged.
+type CSVConflictPolicy int
+
+const (
+	// CSVConflictKeepFirst keeps the row from the first source that contains the ID.
+	CSVConflictKeepFirst CSVConflictPolicy = iota
+	// CSVConflictKeepLast replaces the row with the one from the last source that contains the ID,
+	// the row keeps the position of its first occurrence.
+	CSVConflictKeepLast
+	// CSVConflictError returns an error if the same ID is found in more than one source.
+	CSVConflictError
+)
+
+// CSVReadersOptions configures [NewCSVTableFromReaders].
+type CSVReadersOptions struct {
+	// Conflict defines how rows with the same ID from different readers are merged.
+	Conflict CSVConflictPolicy
+	// IDPrefixes are prefixes added to the row IDs of the reader with the same index.
+	// It can be used to avoid ID conflicts between readers. Readers without a prefix keep their IDs.
+	IDPrefixes []string
+}
+
+// CSVReadersReport describes how sources contributed to the table created by [NewCSVTableFromReaders].
+type CSVReadersReport struct {
+	// Columns contains the column names of every reader by its index, excluding the ID column.
+	Columns [][]string
+	// Skipped contains the columns of every reader by its index that were not added to the table
+	// because they have the name of the ID column of the first reader.
+	Skipped [][]string
+	// Rows contains the number of rows read from every reader by its index.
+	Rows []int
+}
+
+// NewCSVTableFromReaders creates a new CSVTable from several readers that contain CSV data with possibly different columns.
+// Headers are united in order of their first appearance, missing cells are filled with empty strings.
+// The first column of every reader is used as the ID, the name of the ID column is taken from the first reader.
+// Rows are concatenated in reader order, conflicts of IDs are resolved according to opts.
+// A non-ID column with the name of the ID column is skipped and listed in [CSVReadersReport.Skipped].
+// Returns the table and the report of which readers contributed which columns,
+// or an error if any reader cannot be parsed or there is a conflict with [CSVConflictError] policy.
+func NewCSVTableFromReaders(readers []io.Reader, opts CSVReadersOptions) (*CSVTable, CSVReadersReport, error) {
+	report := CSVReadersReport{
+		Columns: make([][]string, len(readers)),
+		Skipped: make([][]string, len(readers)),
+		Rows:    make([]int, len(readers)),
+	}
+	table := NewCSVTable(nil)
+
+	for i, reader := range readers {
+		records, err := csv.NewReader(reader).ReadAll()
+		if err != nil {
+			return nil, report, fmt.Errorf("read reader %d: %w", i, err)
+		}
+		source := NewCSVTable(records)
+		if len(source.headers) == 0 {
+			continue
+		}
+
+		if len(table.headers) == 0 {
+			table.headers = []string{source.headers[0]}
+			table.headerIndex[source.headers[0]] = 0
+		}
+		report.Rows[i] = len(source.ids)
+
+		// Indexes of the source columns in the table, zero for the ID column and columns clashing with it
+		columns := make([]int, len(source.headers))
+		for k, header := range source.headers {
+			if k == 0 {
+				continue
+			}
+			if header == table.headers[0] {
+				report.Skipped[i] = append(report.Skipped[i], header)
+				continue
+			}
+			report.Columns[i] = append(report.Columns[i], header)
+			if index, ok := table.headerIndex[header]; ok {
+				columns[k] = index
+				continue
+			}
+			columns[k] = len(table.headers)
+			table.headerIndex[header] = len(table.headers)
+			table.headers = append(table.headers, header)
+			for j := range table.rows {
+				table.rows[j] = append(table.rows[j], "")
+			}
+		}
+
+		var prefix string
+		if i < len(opts.IDPrefixes) {
+			prefix = opts.IDPrefixes[i]
+		}
+
+		for j, sourceRow := range source.rows {
+			id := prefix + source.ids[j]
+			row := make([]string, len(table.headers))
+			row[0] = id
+			for k := 1; k < len(source.headers) && k < len(sourceRow); k++ {
+				if columns[k] > 0 {
+					row[columns[k]] = sourceRow[k]
+				}
+			}
+
+			index, exists := table.idIndex[id]
+			switch {
+			case !exists:
+				table.idIndex[id] = len(table.ids)
+				table.ids = append(table.ids, id)
+				table.rows = append(table.rows, row)
+			case opts.Conflict == CSVConflictKeepLast:
+				table.rows[index] = row
+			case opts.Conflict == CSVConflictError:
+				return nil, report, fmt.Errorf("reader %d: duplicate row id %q", i, id)
+			}
+		}
+	}
+
+	return table, report, nil
+}
+
+// AggregateStream folds the values of valueCol grouped by the values of groupCol while reading CSV data row by row,
+// so the whole table is never held in memory. The first row must be the header row.
+// For every row agg is called with the current accumulator of the row group (empty string for the first row of a group)
+// and the value of valueCol, the returned value becomes the new accumulator.
+// Returns a map of group values to their accumulators,
+// or an error if the data cannot be parsed or the columns are not found in the header.
+func AggregateStream(r io.Reader, groupCol, valueCol string, agg func(acc, v string) string) (map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+
+	headers, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	groupIndex := slices.Index(headers, groupCol)
+	if groupIndex < 0 {
+		return nil, fmt.Errorf("group column %q not found", groupCol)
+	}
+	valueIndex := slices.Index(headers, valueCol)
+	if valueIndex < 0 {
+		return nil, fmt.Errorf("value column %q not found", valueCol)
+	}
+
+	result := make(map[string]string)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read row: %w", err)
+		}
+
+		var group, value string
+		if groupIndex < len(record) {
+			group = record[groupIndex]
+		}
+		if valueIndex < len(record) {
+			value = record[valueIndex]
+		}
+		result[group] = agg(result[group], value)
+	}
+}
+
+// TransformStream reads CSV data from r row by row, passes every row to transform
+// and writes the returned rows to w, so the whole table is never held in memory.
+// The first row must be the header row, it is written to w as is and defines the output columns:
+// keys of the returned row that are not in the header are ignored, missing ones are written as empty strings.
+// A row is dropped if transform returns false. Returns an error if the data cannot be parsed or written.
+func TransformStream(r io.Reader, w io.Writer, transform func(row map[string]string) (map[string]string, bool)) error {
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	writer := csv.NewWriter(w)
+
+	record, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+	headers := slices.Clone(record)
+	if err := writer.Write(headers); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+
+	out := make([]string, len(headers))
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read row: %w", err)
+		}
+
+		row := make(map[string]string, len(headers))
+		for i, header := range headers {
+			if i < len(record) {
+				row[header] = record[i]
+			} else {
+				row[header] = ""
+			}
+		}
+
+		row, ok := transform(row)
+		if !ok {
+			continue
+		}
+		for i, header := range headers {
+			out[i] = row[header]
+		}
+		if err := writer.Write(out); err != nil {
+			return fmt.Errorf("write row: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("flush: %w", err)
+	}
+	return nil
+}
+
+// CSVMapOptions configures how [NewCSVTableFromMapWithOptions] builds a table.
+type CSVMapOptions struct {
+	// IDColumn is the name of the ID column, it is "id" if empty.
+	IDColumn string
+	// ColumnOrder is the order of columns after the ID column.
+	// The listed columns are placed first in the provided order, even if there are no values for them,
+	// and the remaining columns from the data follow in sorted order.
+	// The ID column and duplicates in the list are ignored.
+	ColumnOrder []string
+}
+
+// NewCSVTableFromNDJSON creates a new CSVTable from newline-delimited JSON, one object per row.
+// The value of idField is used as the row ID and becomes the first column, it is "id" if idField is empty.
+// Other columns are added in the order they first appear in the objects, missing fields are left empty.
+// String values are stored as is, null values as empty strings, and other values as compact JSON text.
+// Returns an error if the data cannot be parsed, an object has no ID field or empty ID, or an ID is repeated.
+func NewCSVTableFromNDJSON(r io.Reader, idField string) (*CSVTable, error) {
+	if idField == "" {
+		idField = "id"
+	}
+
+	table := &CSVTable{
+		ids:         make([]string, 0),
+		idIndex:     make(map[string]int),
+		headers:     []string{idField},
+		headerIndex: map[string]int{idField: 0},
+		rows:        make([][]string, 0),
+	}
+
+	dec := json.NewDecoder(r)
+	for n := 1; ; n++ {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decode object %d: %w", n, err)
+		}
+
+		fields, err := decodeNDJSONObject(raw)
+		if err != nil {
+			return nil, fmt.Errorf("decode object %d: %w", n, err)
+		}
+
+		row := make([]string, len(table.headers))
+		hasID := false
+		for _, field := range fields {
+			colIndex, ok := table.headerIndex[field.Key]
+			if !ok {
+				colIndex = len(table.headers)
+				table.headers = append(table.headers, field.Key)
+				table.headerIndex[field.Key] = colIndex
+			}
+			for len(row) <= colIndex {
+				row = append(row, "")
+			}
+			row[colIndex] = field.Value
+			hasID = hasID || colIndex == 0
+		}
+
+		id := row[0]
+		if !hasID || id == "" {
+			return nil, fmt.Errorf("object %d has no %q field", n, idField)
+		}
+		if _, ok := table.idIndex[id]; ok {
+			return nil, fmt.Errorf("object %d has duplicate id %q", n, id)
+		}
+		table.idIndex[id] = len(table.ids)
+		table.ids = append(table.ids, id)
+		table.rows = append(table.rows, row)
+	}
+
+	// Align all rows with the final set of columns
+	for i, row := range table.rows {
+		for len(row) < len(table.headers) {
+			row = append(row, "")
+		}
+		table.rows[i] = row
+	}
+
+	return table, nil
+}
+
+// decodeNDJSONObject returns fields of the JSON object as cell values preserving their order.
+func decodeNDJSONObject(raw json.RawMessage) ([]Entry[string, string], error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, errors.New("not a JSON object")
+	}
+
+	var fields []Entry[string, string]
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		cell, err := jsonValueToCell(value)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, Entry[string, string]{Key: key, Value: cell})
+	}
+	return fields, nil
+}
+
+// jsonValueToCell converts a JSON value to a cell value.
+func jsonValueToCell(value json.RawMessage) (string, error) {
+	switch {
+	case len(value) == 0 || string(value) == "null":
+		return "", nil
+	case value[0] == '"':
+		var s string
+		err := json.Unmarshal(value, &s)
+		return s, err
+	}
+
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, value); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 // NewCSVTableFromMap creates a new CSVTable from a map structure.
 // The outer map keys become row IDs, and the inner map keys become column headers.
 // An ID column is automatically added as the first column.
 // If idColumnName is provided, it will be used as the ID column name.
+// Columns are sorted by name, use [NewCSVTableFromMapWithOptions] with [CSVMapOptions.ColumnOrder] to set their order.
 func NewCSVTableFromMap(data map[string]map[string]string, idColumnName ...string) *CSVTable {
-	if len(data) == 0 {
+	idColumn := "id"
+	if len(idColumnName) > 0 {
+		idColumn = idColumnName[0]
+	}
+	return newCSVTableFromMap(data, idColumn, nil)
+}
+
+// NewCSVTableFromMapWithOptions creates a new CSVTable from a map structure like [NewCSVTableFromMap],
+// configured with the provided options. Rows are sorted by ID.
+// The column order is kept by [CSVTable.Bytes] and [CSVTable.WriteTo], so map -> CSV -> map
+// round-trips produce the same layout.
+func NewCSVTableFromMapWithOptions(data map[string]map[string]string, opts CSVMapOptions) *CSVTable {
+	if opts.IDColumn == "" {
+		opts.IDColumn = "id"
+	}
+	return newCSVTableFromMap(data, opts.IDColumn, opts.ColumnOrder)
+}
+
+// newCSVTableFromMap creates a new CSVTable from a map structure with the ID column and the order of other columns.
+func newCSVTableFromMap(data map[string]map[string]string, idColumn string, columnOrder []string) *CSVTable {
+	if len(data) == 0 && len(columnOrder) == 0 {
 		return &CSVTable{
 			ids:         make([]string, 0),
 			idIndex:     make(map[string]int),
@@ -71,17 +466,23 @@ func NewCSVTableFromMap(data map[string]map[string]string, idColumnName ...strin
 		}
 	}
 
-	// Create headers slice with ID as first column
-	headers := make([]string, 1, len(columnSet)+1)
-	if len(idColumnName) > 0 {
-		headers[0] = idColumnName[0]
-	} else {
-		headers[0] = "id"
+	// Create headers slice with ID as first column, then ordered columns
+	headers := make([]string, 1, len(columnSet)+len(columnOrder)+1)
+	headers[0] = idColumn
+	seen := map[string]bool{idColumn: true}
+	for _, col := range columnOrder {
+		if !seen[col] {
+			seen[col] = true
+			headers = append(headers, col)
+		}
 	}
+	ordered := len(headers)
 	for col := range columnSet {
-		headers = append(headers, col)
+		if !seen[col] {
+			headers = append(headers, col)
+		}
 	}
-	sort.Strings(headers[1:]) // Sort non-ID columns for consistency
+	sort.Strings(headers[ordered:]) // Sort the rest of columns for consistency
 
 	table := &CSVTable{
 		ids:         make([]string, 0, len(data)),
@@ -196,14 +597,23 @@ func (t *CSVTable) AddRow(id string, row map[string]string) {
 		}
 	}
 
-	// If this ID already exists, update the existing row
-	if index, exists := t.idIndex[id]; exists {
-		t.rows[index] = newRow
-	} else {
-		// Otherwise add as a new row
-		t.idIndex[id] = len(t.ids)
+	index, exists := t.idIndex[id]
+	if !exists {
+		// Add an empty row, so values are set in it as changes of cells
+		index = len(t.ids)
+		emptyRow := make([]string, len(t.headers))
+		emptyRow[0] = id
+		t.idIndex[id] = index
 		t.ids = append(t.ids, id)
-		t.rows = append(t.rows, newRow)
+		t.rows = append(t.rows, emptyRow)
+	}
+
+	// If this ID already exists, the existing row is replaced
+	if n := len(newRow) - len(t.rows[index]); n > 0 {
+		t.rows[index] = append(t.rows[index], make([]string, n)...)
+	}
+	for colIndex := 1; colIndex < len(newRow); colIndex++ {
+		t.setCell(index, colIndex, newRow[colIndex])
 	}
 }
 
@@ -219,13 +629,75 @@ func (t *CSVTable) UpdateRow(id string, row map[string]string) bool {
 	// Update only the provided columns
 	for colName, value := range row {
 		if colIndex, exists := t.headerIndex[colName]; exists && colIndex < len(t.rows[rowIndex]) {
-			t.rows[rowIndex][colIndex] = value
+			t.setCell(rowIndex, colIndex, value)
 		}
 	}
 
 	return true
 }
 
+// SetValue sets the value of the cell in the row with the given ID and the given column.
+// Returns true if the row and the column exist and the value was set, false otherwise.
+// The ID column cannot be changed, false is returned for it.
+func (t *CSVTable) SetValue(id, column, value string) bool {
+	rowIndex, ok := t.idIndex[id]
+	if !ok {
+		return false
+	}
+	colIndex, ok := t.headerIndex[column]
+	if !ok || colIndex == 0 || colIndex >= len(t.rows[rowIndex]) {
+		return false
+	}
+	t.setCell(rowIndex, colIndex, value)
+	return true
+}
+
+// setCell sets the value of the cell and marks it as dirty if tracking is enabled and the value has changed.
+func (t *CSVTable) setCell(rowIndex, colIndex int, value string) {
+	if t.dirty != nil && t.rows[rowIndex][colIndex] != value {
+		t.dirty[CSVCellRef{ID: t.ids[rowIndex], Column: t.headers[colIndex]}] = struct{}{}
+	}
+	t.rows[rowIndex][colIndex] = value
+}
+
+// TrackChanges enables or disables recording of cells changed by [CSVTable.SetValue], [CSVTable.AddRow],
+// [CSVTable.UpdateRow] and [CSVTable.UpdateColumn]. Only cells whose value has actually changed are recorded.
+// Cells of deleted rows and columns are forgotten. Disabling tracking discards the recorded cells.
+func (t *CSVTable) TrackChanges(enabled bool) {
+	switch {
+	case !enabled:
+		t.dirty = nil
+	case t.dirty == nil:
+		t.dirty = make(map[CSVCellRef]struct{})
+	}
+}
+
+// DirtyCells returns the cells changed since tracking was enabled or [CSVTable.ClearDirty] was called,
+// sorted by row ID and column name. Returns nil if tracking is disabled.
+func (t *CSVTable) DirtyCells() []CSVCellRef {
+	if t.dirty == nil {
+		return nil
+	}
+	cells := make([]CSVCellRef, 0, len(t.dirty))
+	for cell := range t.dirty {
+		cells = append(cells, cell)
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if cells[i].ID != cells[j].ID {
+			return cells[i].ID < cells[j].ID
+		}
+		return cells[i].Column < cells[j].Column
+	})
+	return cells
+}
+
+// ClearDirty forgets the recorded changed cells, tracking stays enabled.
+func (t *CSVTable) ClearDirty() {
+	if t.dirty != nil {
+		clear(t.dirty)
+	}
+}
+
 // AppendColumn adds a new column to the table with the given name and values.
 // Values are assigned to rows in order. If there are more rows than values,
 // the remaining rows will not have a value for this column.
@@ -258,9 +730,247 @@ func (t *CSVTable) UpdateColumn(column string, values []string) {
 	// Update values in the specified column
 	for i := 0; i < len(t.rows) && i < len(values); i++ {
 		if colIndex < len(t.rows[i]) {
-			t.rows[i][colIndex] = values[i]
+			t.setCell(i, colIndex, values[i])
+		}
+	}
+}
+
+// ReplaceRegex replaces matches of the pattern in every cell of the column with the replacement,
+// which can reference capture groups like $1 (see [regexp.Regexp.ReplaceAllString]).
+// Returns the number of changed cells or an error if the pattern is invalid, the column does not exist
+// or it is the ID column.
+func (t *CSVTable) ReplaceRegex(column, pattern, replacement string) (int, error) {
+	colIndex, ok := t.headerIndex[column]
+	switch {
+	case !ok:
+		return 0, fmt.Errorf("unknown column %q", column)
+	case colIndex == 0:
+		return 0, fmt.Errorf("ID column %q cannot be changed", column)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return 0, fmt.Errorf("compile pattern: %w", err)
+	}
+
+	return t.replaceRegex(re, replacement, colIndex), nil
+}
+
+// ReplaceRegexAll replaces matches of the pattern in every cell of all columns except the ID column.
+// Returns the number of changed cells or an error if the pattern is invalid.
+func (t *CSVTable) ReplaceRegexAll(pattern, replacement string) (int, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return 0, fmt.Errorf("compile pattern: %w", err)
+	}
+
+	var changed int
+	for colIndex := 1; colIndex < len(t.headers); colIndex++ {
+		changed += t.replaceRegex(re, replacement, colIndex)
+	}
+	return changed, nil
+}
+
+func (t *CSVTable) replaceRegex(re *regexp.Regexp, replacement string, colIndex int) int {
+	var changed int
+	for i, row := range t.rows {
+		if colIndex >= len(row) {
+			continue
+		}
+		if value := re.ReplaceAllString(row[colIndex], replacement); value != row[colIndex] {
+			t.setCell(i, colIndex, value)
+			changed++
+		}
+	}
+	return changed
+}
+
+// ColumnType is a type of values of a column used by [CSVTable.ConvertColumns].
+type ColumnType int
+
+const (
+	// ColumnTypeString accepts any value, surrounding whitespace is trimmed.
+	ColumnTypeString ColumnType = iota
+	// ColumnTypeInt accepts base 10 integers, they are formatted without leading zeros and plus sign.
+	ColumnTypeInt
+	// ColumnTypeFloat accepts floating-point numbers, they are formatted in the shortest decimal form without exponent.
+	ColumnTypeFloat
+	// ColumnTypeBool accepts values supported by [strconv.ParseBool], they are formatted as "true" or "false".
+	ColumnTypeBool
+)
+
+// ConvertColumns validates values of the columns according to their types and replaces them with canonical forms,
+// e.g. " 1.50 " in a [ColumnTypeFloat] column becomes "1.5". Empty cells stay empty in columns of every type.
+// If any cell cannot be parsed, it returns an error with the row ID and the column name of every such cell
+// and leaves the table unchanged. It also returns an error for unknown columns and the ID column.
+func (t *CSVTable) ConvertColumns(types map[string]ColumnType) error {
+	type cellUpdate struct {
+		rowIndex, colIndex int
+		value              string
+	}
+
+	var (
+		updates []cellUpdate
+		errs    []error
+	)
+	for _, column := range slices.Sorted(maps.Keys(types)) {
+		typ := types[column]
+		colIndex, ok := t.headerIndex[column]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("unknown column %q", column))
+			continue
+		case colIndex == 0:
+			errs = append(errs, fmt.Errorf("ID column %q cannot be changed", column))
+			continue
+		}
+
+		for i, row := range t.rows {
+			if colIndex >= len(row) {
+				continue
+			}
+			value, err := convertCell(row[colIndex], typ)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("row %q, column %q: %w", t.ids[i], column, err))
+				continue
+			}
+			if value != row[colIndex] {
+				updates = append(updates, cellUpdate{rowIndex: i, colIndex: colIndex, value: value})
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	for _, u := range updates {
+		t.setCell(u.rowIndex, u.colIndex, u.value)
+	}
+	return nil
+}
+
+// convertCell returns the canonical form of the value of the type.
+func convertCell(raw string, typ ColumnType) (string, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" {
+		return "", nil
+	}
+
+	switch typ {
+	case ColumnTypeString:
+		return value, nil
+	case ColumnTypeInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid integer %q", raw)
+		}
+		return strconv.FormatInt(n, 10), nil
+	case ColumnTypeFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid number %q", raw)
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	case ColumnTypeBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid boolean %q", raw)
+		}
+		return strconv.FormatBool(b), nil
+	}
+	return "", fmt.Errorf("unknown column type %d", typ)
+}
+
+// ColumnStats is a summary of values of a column returned by [CSVTable.Describe].
+type ColumnStats struct {
+	// Count is the number of non-empty values.
+	Count int
+	// Distinct is the number of distinct non-empty values.
+	Distinct int
+	// Empty is the number of empty values.
+	Empty int
+	// Numeric is true if the column has non-empty values and all of them are finite numbers.
+	Numeric bool
+	// Min, Max, Mean and StdDev describe values of a numeric column, StdDev is the sample standard deviation.
+	// They are zero for a non-numeric column.
+	Min, Max, Mean, StdDev float64
+	// Top is the most frequent non-empty value of a non-numeric column (the smallest one in case of a tie)
+	// and TopCount is the number of its occurrences. They are empty for a numeric column.
+	Top      string
+	TopCount int
+}
+
+// Describe returns a summary of values of every column except the ID column keyed by the column name.
+// Values are trimmed of surrounding whitespace, a column is numeric if all its non-empty values
+// can be parsed as finite floating-point numbers.
+func (t *CSVTable) Describe() map[string]ColumnStats {
+	out := make(map[string]ColumnStats, max(len(t.headers)-1, 0))
+	for colIndex := 1; colIndex < len(t.headers); colIndex++ {
+		out[t.headers[colIndex]] = t.describeColumn(colIndex)
+	}
+	return out
+}
+
+func (t *CSVTable) describeColumn(colIndex int) ColumnStats {
+	var (
+		stats   = ColumnStats{Numeric: true}
+		counts  = make(map[string]int)
+		numbers = make([]float64, 0, len(t.rows))
+	)
+	for _, row := range t.rows {
+		var value string
+		if colIndex < len(row) {
+			value = strings.TrimSpace(row[colIndex])
+		}
+		if value == "" {
+			stats.Empty++
+			continue
+		}
+		stats.Count++
+		counts[value]++
+
+		if !stats.Numeric {
+			continue
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			stats.Numeric = false
+			continue
+		}
+		numbers = append(numbers, f)
+	}
+	stats.Distinct = len(counts)
+
+	if stats.Count == 0 {
+		stats.Numeric = false
+		return stats
+	}
+
+	if !stats.Numeric {
+		for value, n := range counts {
+			if n > stats.TopCount || (n == stats.TopCount && value < stats.Top) {
+				stats.Top, stats.TopCount = value, n
+			}
+		}
+		return stats
+	}
+
+	stats.Min, stats.Max = numbers[0], numbers[0]
+	var sum float64
+	for _, f := range numbers {
+		stats.Min = min(stats.Min, f)
+		stats.Max = max(stats.Max, f)
+		sum += f
+	}
+	stats.Mean = sum / float64(len(numbers))
+	if len(numbers) > 1 {
+		var squares float64
+		for _, f := range numbers {
+			squares += (f - stats.Mean) * (f - stats.Mean)
 		}
+		stats.StdDev = math.Sqrt(squares / float64(len(numbers)-1))
 	}
+	return stats
 }
 
 // Row returns the data for the row with the given ID.
@@ -400,6 +1110,12 @@ func (t *CSVTable) Copy() *CSVTable {
 		copy(table.rows[i], row)
 	}
 
+	// Copy tracked changes
+	if t.dirty != nil {
+		table.dirty = maps.Clone(t.dirty)
+	}
+	table.formatters = maps.Clone(t.formatters)
+
 	return table
 }
 
@@ -421,21 +1137,24 @@ func (t *CSVTable) Headers() []string {
 // If no row with that ID exists, or if the key doesn't exist in that row,
 // returns an empty string.
 func (t *CSVTable) Value(slug, key string) string {
-	rowIndex, ok := t.idIndex[slug]
-	if !ok {
-		return ""
-	}
+	value, _ := t.LookupValue(slug, key)
+	return value
+}
 
-	colIndex, ok := t.headerIndex[key]
+// LookupValue returns the value for the given ID and column and true if the cell is present,
+// i.e. the row and the column exist. It allows to distinguish an absent cell from an empty value.
+func (t *CSVTable) LookupValue(id, column string) (string, bool) {
+	rowIndex, ok := t.idIndex[id]
 	if !ok {
-		return ""
+		return "", false
 	}
 
-	if colIndex < len(t.rows[rowIndex]) {
-		return t.rows[rowIndex][colIndex]
+	colIndex, ok := t.headerIndex[column]
+	if !ok || colIndex >= len(t.rows[rowIndex]) {
+		return "", false
 	}
 
-	return ""
+	return t.rows[rowIndex][colIndex], true
 }
 
 // Has returns true if a row with the given ID exists in the table.
@@ -444,6 +1163,25 @@ func (t *CSVTable) Has(slug string) bool {
 	return ok
 }
 
+// HasAll returns true if rows with all the given IDs exist in the table.
+func (t *CSVTable) HasAll(ids ...string) bool {
+	for _, id := range ids {
+		if _, ok := t.idIndex[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// MissingIDs returns the given IDs that are absent in the table in the order they are provided.
+// Returns nil if all rows exist.
+func (t *CSVTable) MissingIDs(ids ...string) []string {
+	return Filter(ids, func(id string) bool {
+		_, ok := t.idIndex[id]
+		return !ok
+	})
+}
+
 // FindRow finds the first row that matches the given criteria.
 // The criteria is a map of column names to values that must match.
 // Returns the row ID and data if found, empty string and nil if not found.
@@ -506,6 +1244,39 @@ func (t *CSVTable) Find(criteria map[string]string) map[string]map[string]string
 
 // Bytes returns the table as a CSV-formatted byte slice.
 func (t *CSVTable) Bytes() []byte {
+	return t.bytes(nil)
+}
+
+// FormatColumn registers a formatter of the column that is applied to its values by [CSVTable.BytesFormatted],
+// e.g. to render fixed decimals or currency. Stored values are not changed. A nil formatter removes the registered one.
+func (t *CSVTable) FormatColumn(column string, f func(raw string) string) {
+	if f == nil {
+		delete(t.formatters, column)
+		return
+	}
+	if t.formatters == nil {
+		t.formatters = make(map[string]func(string) string)
+	}
+	t.formatters[column] = f
+}
+
+// BytesFormatted returns the table as a CSV-formatted byte slice like [CSVTable.Bytes],
+// but values of columns are passed through their formatters. The provided formatters override
+// the ones registered with [CSVTable.FormatColumn]. Headers and stored values are not changed.
+func (t *CSVTable) BytesFormatted(formatters map[string]func(string) string) []byte {
+	byIndex := make([]func(string) string, len(t.headers))
+	for _, fmts := range []map[string]func(string) string{t.formatters, formatters} {
+		for column, f := range fmts {
+			if colIndex, ok := t.headerIndex[column]; ok && f != nil {
+				byIndex[colIndex] = f
+			}
+		}
+	}
+	return t.bytes(byIndex)
+}
+
+// bytes returns the table as a CSV-formatted byte slice applying formatters of columns by their indexes.
+func (t *CSVTable) bytes(formatters []func(string) string) []byte {
 	var buf strings.Builder
 
 	// Write headers
@@ -523,6 +1294,9 @@ func (t *CSVTable) Bytes() []byte {
 			if i > 0 {
 				buf.WriteString(",")
 			}
+			if i < len(formatters) && formatters[i] != nil {
+				value = formatters[i](value)
+			}
 			buf.WriteString("\"" + strings.ReplaceAll(value, "\"", "\"\"") + "\"")
 		}
 		buf.WriteString("\n")
@@ -531,8 +1305,111 @@ func (t *CSVTable) Bytes() []byte {
 	return []byte(buf.String())
 }
 
-// DeleteColumn removes the specified column from the table.
-// This affects both the headers and the data in each row.
+// WriteTo writes the table in CSV format to w, the same as returned by [CSVTable.Bytes].
+// It implements the [io.WriterTo] interface.
+func (t *CSVTable) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(t.Bytes())
+	return int64(n), err
+}
+
+// WriteNDJSON writes the table to w as newline-delimited JSON, one object per row.
+// Objects have a field for every column in the column order, including the ID column, all values are strings.
+func (t *CSVTable) WriteNDJSON(w io.Writer) error {
+	keys := make([][]byte, len(t.headers))
+	for i, header := range t.headers {
+		keys[i], _ = json.Marshal(header)
+	}
+
+	var buf bytes.Buffer
+	for _, row := range t.rows {
+		buf.Reset()
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			var value string
+			if i < len(row) {
+				value = row[i]
+			}
+			encoded, _ := json.Marshal(value)
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(encoded)
+		}
+		buf.WriteString("}\n")
+
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CSVEncoding is a character encoding of the CSV output.
+type CSVEncoding int
+
+const (
+	// CSVEncodingUTF8 is UTF-8 without byte order mark, the same as returned by [CSVTable.Bytes].
+	CSVEncodingUTF8 CSVEncoding = iota
+	// CSVEncodingUTF8BOM is UTF-8 with byte order mark, it makes Excel detect the encoding correctly.
+	CSVEncodingUTF8BOM
+	// CSVEncodingWindows1252 is Windows-1252 (a superset of Latin-1) used by legacy tools.
+	CSVEncodingWindows1252
+)
+
+// BytesEncoded returns the table as a CSV-formatted byte slice in the provided encoding.
+// Returns an error if the encoding is unknown or a value cannot be represented in the encoding.
+func (t *CSVTable) BytesEncoded(enc CSVEncoding) ([]byte, error) {
+	data := t.Bytes()
+
+	switch enc {
+	case CSVEncodingUTF8:
+		return data, nil
+
+	case CSVEncodingUTF8BOM:
+		return append([]byte{0xEF, 0xBB, 0xBF}, data...), nil
+
+	case CSVEncodingWindows1252:
+		return encodeWindows1252(data)
+	}
+
+	return nil, fmt.Errorf("unknown CSV encoding %d", enc)
+}
+
+// windows1252Specials maps runes to bytes of the 0x80-0x9F range of Windows-1252,
+// which differs from Latin-1 control characters.
+var windows1252Specials = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// encodeWindows1252 transcodes UTF-8 data to Windows-1252 returning an error on unmappable runes.
+func encodeWindows1252(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			return nil, fmt.Errorf("invalid UTF-8 at byte %d", i)
+		case r < 0x80 || (r >= 0xA0 && r <= 0xFF):
+			out = append(out, byte(r))
+		default:
+			b, ok := windows1252Specials[r]
+			if !ok {
+				return nil, fmt.Errorf("cannot encode %q at byte %d in Windows-1252", r, i)
+			}
+			out = append(out, b)
+		}
+		i += size
+	}
+	return out, nil
+}
+
+// DeleteColumn removes the specified column from the table.
+// This affects both the headers and the data in each row.
 func (t *CSVTable) DeleteColumn(column string) {
 	t.DeleteColumns(column)
 }
@@ -554,6 +1431,13 @@ func (t *CSVTable) DeleteRow(id string) bool {
 	// Remove from idIndex
 	delete(t.idIndex, id)
 
+	// Forget changed cells of the deleted row
+	for ref := range t.dirty {
+		if ref.ID == id {
+			delete(t.dirty, ref)
+		}
+	}
+
 	// Update indices for all rows after the deleted one
 	for i := rowIndex; i < len(t.ids); i++ {
 		t.idIndex[t.ids[i]] = i
@@ -562,6 +1446,95 @@ func (t *CSVTable) DeleteRow(id string) bool {
 	return true
 }
 
+// MergeFunc combines the current value of a cell with the value of the same cell from a duplicate row.
+type MergeFunc func(current, incoming string) string
+
+// MergeKeepFirst keeps the value of the first row.
+func MergeKeepFirst(current, _ string) string {
+	return current
+}
+
+// MergeKeepLast keeps the value of the last row.
+func MergeKeepLast(_, incoming string) string {
+	return incoming
+}
+
+// MergeKeepNonEmpty keeps the first non-empty value, it is the default strategy of [CSVTable.MergeRows].
+func MergeKeepNonEmpty(current, incoming string) string {
+	if current == "" {
+		return incoming
+	}
+	return current
+}
+
+// MergeConcat returns a [MergeFunc] that joins non-empty values with the separator.
+func MergeConcat(sep string) MergeFunc {
+	return func(current, incoming string) string {
+		switch {
+		case current == "":
+			return incoming
+		case incoming == "":
+			return current
+		}
+		return current + sep + incoming
+	}
+}
+
+// MergeSum adds numeric values. If any of the values is not a number, it keeps the first non-empty value.
+func MergeSum(current, incoming string) string {
+	a, errA := strconv.ParseFloat(current, 64)
+	b, errB := strconv.ParseFloat(incoming, 64)
+	if errA != nil || errB != nil {
+		return MergeKeepNonEmpty(current, incoming)
+	}
+	return strconv.FormatFloat(a+b, 'f', -1, 64)
+}
+
+// MergeRows merges rows that share the same ID (e.g. after reading concatenated sources with [NewCSVTable])
+// into the first of them. Values of every column are combined in row order using the strategy for this column,
+// columns without a strategy use [MergeKeepNonEmpty]. The ID column is never changed.
+// Returns the number of removed duplicate rows.
+func (t *CSVTable) MergeRows(strategy map[string]MergeFunc) int {
+	first := make(map[string]int, len(t.ids))
+	keep := make([]bool, len(t.ids))
+	var removed int
+
+	for i, id := range t.ids {
+		target, ok := first[id]
+		if !ok {
+			first[id] = i
+			keep[i] = true
+			continue
+		}
+
+		for colIndex := 1; colIndex < len(t.headers); colIndex++ {
+			merge, ok := strategy[t.headers[colIndex]]
+			if !ok || merge == nil {
+				merge = MergeKeepNonEmpty
+			}
+			t.setCell(target, colIndex, merge(t.rows[target][colIndex], t.rows[i][colIndex]))
+		}
+		removed++
+	}
+	if removed == 0 {
+		return 0
+	}
+
+	ids := make([]string, 0, len(t.ids)-removed)
+	rows := make([][]string, 0, len(t.rows)-removed)
+	for i := range t.ids {
+		if !keep[i] {
+			continue
+		}
+		t.idIndex[t.ids[i]] = len(ids)
+		ids = append(ids, t.ids[i])
+		rows = append(rows, t.rows[i])
+	}
+	t.ids, t.rows = ids, rows
+
+	return removed
+}
+
 // DeleteColumns removes the specified columns from the table.
 // This affects both the headers and the data in each row.
 func (t *CSVTable) DeleteColumns(columns ...string) {
@@ -578,6 +1551,13 @@ func (t *CSVTable) DeleteColumns(columns ...string) {
 		return
 	}
 
+	// Forget changed cells of the deleted columns
+	for ref := range t.dirty {
+		if _, ok := t.headerIndex[ref.Column]; !ok {
+			delete(t.dirty, ref)
+		}
+	}
+
 	// Create new headers without deleted columns
 	newHeaders := make([]string, 0, len(t.headers)-len(colIndicesToDelete))
 	for i, header := range t.headers {
@@ -607,6 +1587,176 @@ func (t *CSVTable) DeleteColumns(columns ...string) {
 	}
 }
 
+// ReorderColumns rearranges the columns of the table to the provided order,
+// which changes the output of [CSVTable.Headers], [CSVTable.RowSorted] and [CSVTable.Bytes].
+// The ID column always stays first, it can be omitted from order or be its first element.
+// Returns an error if order contains unknown or duplicate columns or does not contain all columns.
+// Use [CSVTable.ReorderColumnsPartial] to order only some of the columns.
+func (t *CSVTable) ReorderColumns(order []string) error {
+	return t.reorderColumns(order, false)
+}
+
+// ReorderColumnsPartial rearranges the columns of the table like [CSVTable.ReorderColumns],
+// but the columns missing from order are appended after the ordered ones in their current order.
+func (t *CSVTable) ReorderColumnsPartial(order []string) error {
+	return t.reorderColumns(order, true)
+}
+
+// reorderColumns rearranges the columns, the missing ones are appended if appendRest is true
+// and cause an error otherwise.
+func (t *CSVTable) reorderColumns(order []string, appendRest bool) error {
+	if len(t.headers) == 0 {
+		if len(order) > 0 {
+			return fmt.Errorf("unknown column %q", order[0])
+		}
+		return nil
+	}
+
+	newOrder := make([]int, 1, len(t.headers))
+	used := make([]bool, len(t.headers))
+	used[0] = true
+	for i, column := range order {
+		colIndex, ok := t.headerIndex[column]
+		switch {
+		case !ok:
+			return fmt.Errorf("unknown column %q", column)
+		case colIndex == 0 && i == 0:
+			continue
+		case colIndex == 0:
+			return fmt.Errorf("ID column %q must be first", column)
+		case used[colIndex]:
+			return fmt.Errorf("duplicate column %q", column)
+		}
+		used[colIndex] = true
+		newOrder = append(newOrder, colIndex)
+	}
+
+	for colIndex, isUsed := range used {
+		if isUsed {
+			continue
+		}
+		if !appendRest {
+			return fmt.Errorf("missing column %q", t.headers[colIndex])
+		}
+		newOrder = append(newOrder, colIndex)
+	}
+
+	headers := make([]string, len(newOrder))
+	for i, colIndex := range newOrder {
+		headers[i] = t.headers[colIndex]
+		t.headerIndex[headers[i]] = i
+	}
+	t.headers = headers
+
+	for i, row := range t.rows {
+		newRow := make([]string, len(newOrder))
+		for j, colIndex := range newOrder {
+			if colIndex < len(row) {
+				newRow[j] = row[colIndex]
+			}
+		}
+		t.rows[i] = newRow
+	}
+
+	return nil
+}
+
+// RenameColumns renames columns of the table according to the mapping of old names to new names.
+// The ID column can be renamed as well. Columns can swap names, e.g. {"a": "b", "b": "a"}.
+// Returns an error and leaves the table unchanged if an old column does not exist, a new name is empty
+// or the renaming results in duplicate column names.
+func (t *CSVTable) RenameColumns(mapping map[string]string) error {
+	headers := slices.Clone(t.headers)
+	for oldName, newName := range mapping {
+		colIndex, ok := t.headerIndex[oldName]
+		if !ok {
+			return fmt.Errorf("unknown column %q", oldName)
+		}
+		if newName == "" {
+			return fmt.Errorf("empty new name for column %q", oldName)
+		}
+		headers[colIndex] = newName
+	}
+
+	seen := make(map[string]struct{}, len(headers))
+	for _, header := range headers {
+		if _, ok := seen[header]; ok {
+			return fmt.Errorf("duplicate column %q", header)
+		}
+		seen[header] = struct{}{}
+	}
+
+	if t.dirty != nil {
+		dirty := make(map[CSVCellRef]struct{}, len(t.dirty))
+		for ref := range t.dirty {
+			if newName, ok := mapping[ref.Column]; ok {
+				ref.Column = newName
+			}
+			dirty[ref] = struct{}{}
+		}
+		t.dirty = dirty
+	}
+	if t.formatters != nil {
+		// Build a new map, so swapped columns do not overwrite each other's formatters
+		formatters := make(map[string]func(string) string, len(t.formatters))
+		for name, f := range t.formatters {
+			if newName, ok := mapping[name]; ok {
+				name = newName
+			}
+			formatters[name] = f
+		}
+		t.formatters = formatters
+	}
+	t.setHeaders(headers)
+
+	return nil
+}
+
+// DedupeHeaders makes duplicate column names unique by adding a numeric suffix to the second and next
+// occurrences, e.g. "Name", "Name" becomes "Name", "Name_2". Suffixes that clash with existing names are skipped.
+// Without deduplication, only the last of the columns with the same name is accessible by name.
+// Returns the number of renamed columns.
+func (t *CSVTable) DedupeHeaders() int {
+	used := make(map[string]struct{}, len(t.headers))
+	for _, header := range t.headers {
+		used[header] = struct{}{}
+	}
+
+	headers := slices.Clone(t.headers)
+	seen := make(map[string]struct{}, len(headers))
+	var renamed int
+	for i, header := range headers {
+		if _, ok := seen[header]; !ok {
+			seen[header] = struct{}{}
+			continue
+		}
+		for n := 2; ; n++ {
+			candidate := header + "_" + strconv.Itoa(n)
+			if _, ok := used[candidate]; !ok {
+				headers[i] = candidate
+				used[candidate] = struct{}{}
+				seen[candidate] = struct{}{}
+				break
+			}
+		}
+		renamed++
+	}
+	if renamed > 0 {
+		t.setHeaders(headers)
+	}
+
+	return renamed
+}
+
+// setHeaders replaces the headers and rebuilds the header index.
+func (t *CSVTable) setHeaders(headers []string) {
+	t.headers = headers
+	t.headerIndex = make(map[string]int, len(headers))
+	for i, header := range headers {
+		t.headerIndex[header] = i
+	}
+}
+
 // SortDirection represents the sorting direction (ascending or descending)
 type SortDirection int
 
@@ -647,6 +1797,314 @@ func (t *CSVTable) Sort(column string, direction SortDirection) *CSVTable {
 	return t
 }
 
+// MoveRow relocates the row with the specified ID to toIndex in the row order,
+// shifting the rows in between. The order is reflected in [CSVTable.AllSorted] and [CSVTable.Bytes].
+// Out-of-range indices are clamped to the first or last position.
+// It returns false if the row does not exist.
+func (t *CSVTable) MoveRow(id string, toIndex int) bool {
+	from, exists := t.idIndex[id]
+	if !exists {
+		return false
+	}
+	toIndex = max(0, min(toIndex, len(t.rows)-1))
+	if from == toIndex {
+		return true
+	}
+
+	row := t.rows[from]
+	t.rows = slices.Insert(slices.Delete(t.rows, from, from+1), toIndex, row)
+	t.ids = slices.Insert(slices.Delete(t.ids, from, from+1), toIndex, id)
+
+	// Only the rows between the old and the new position have changed their index
+	for i := min(from, toIndex); i <= max(from, toIndex); i++ {
+		t.idIndex[t.ids[i]] = i
+	}
+
+	return true
+}
+
+// SortRowsByIDs reorders the rows so that the rows with the provided IDs come first in the given order.
+// Rows sharing a listed ID are placed together in their previous relative order.
+// Unknown and repeated IDs are ignored, rows not listed keep their relative order after the listed ones.
+func (t *CSVTable) SortRowsByIDs(ids []string) *CSVTable {
+	listed := make(map[string]int, len(ids))
+	for _, id := range ids {
+		if _, ok := listed[id]; !ok {
+			listed[id] = len(listed)
+		}
+	}
+
+	// Group row indexes by the position of their ID in the list, the last group holds the rest
+	groups := make([][]int, len(listed)+1)
+	for i, id := range t.ids {
+		pos, ok := listed[id]
+		if !ok {
+			pos = len(listed)
+		}
+		groups[pos] = append(groups[pos], i)
+	}
+
+	newIDs := make([]string, 0, len(t.ids))
+	rows := make([][]string, 0, len(t.rows))
+	for _, group := range groups {
+		for _, i := range group {
+			newIDs = append(newIDs, t.ids[i])
+			rows = append(rows, t.rows[i])
+		}
+	}
+	t.ids, t.rows = newIDs, rows
+
+	t.idIndex = make(map[string]int, len(t.ids))
+	for i, id := range t.ids {
+		t.idIndex[id] = i
+	}
+
+	return t
+}
+
+// IterColumn returns an iterator over the values of the column yielding row ID and cell value in row order.
+// If the column does not exist, the iterator yields nothing.
+func (t *CSVTable) IterColumn(name string) iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		if _, ok := t.headerIndex[name]; !ok {
+			return
+		}
+		for i, id := range t.ids {
+			if !yield(id, t.cell(i, name)) {
+				return
+			}
+		}
+	}
+}
+
+// IterColumns returns an iterator over the columns yielding each header with the values of the column in row order.
+// The yielded slices are copies and can be modified freely.
+func (t *CSVTable) IterColumns() iter.Seq2[string, []string] {
+	return func(yield func(string, []string) bool) {
+		for _, header := range t.headers {
+			if !yield(header, t.column(header)) {
+				return
+			}
+		}
+	}
+}
+
+// column returns a copy of the values of the column in row order.
+func (t *CSVTable) column(name string) []string {
+	values := make([]string, len(t.rows))
+	for i := range t.rows {
+		values[i] = t.cell(i, name)
+	}
+	return values
+}
+
+// CSVCellChange describes a change of a single cell value.
+type CSVCellChange struct {
+	Old string
+	New string
+}
+
+// CSVRowChange describes changes of a row that is present in both compared tables.
+type CSVRowChange struct {
+	// ID is the row ID.
+	ID string
+	// Columns maps column names to the changes of the cell values.
+	Columns map[string]CSVCellChange
+}
+
+// CSVDiff is the result of comparing two tables with [CSVTable.Diff].
+type CSVDiff struct {
+	// Added contains IDs of rows that are present only in the other table, in its row order.
+	Added []string
+	// Removed contains IDs of rows that are present only in the original table, in its row order.
+	Removed []string
+	// Changed contains rows that are present in both tables but have different values, in the original row order.
+	Changed []CSVRowChange
+}
+
+// IsEmpty returns true if there are no differences between the tables.
+func (d CSVDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares the table with the other one, treating the current table as the old state
+// and the other table as the new state. Rows are matched by ID.
+// Columns are compared by name, a column that is missing in one of the tables is treated as empty values.
+// The ID column itself is not compared. A nil other is treated as an empty table, so all rows are removed.
+func (t *CSVTable) Diff(other *CSVTable) CSVDiff {
+	if other == nil {
+		other = NewCSVTable(nil)
+	}
+
+	var diff CSVDiff
+
+	columns := make([]string, 0, len(t.headers)+len(other.headers))
+	seen := make(map[string]bool, len(t.headers)+len(other.headers))
+	for _, headers := range [][]string{t.headers, other.headers} {
+		for j := 1; j < len(headers); j++ {
+			if !seen[headers[j]] {
+				seen[headers[j]] = true
+				columns = append(columns, headers[j])
+			}
+		}
+	}
+
+	for i, id := range t.ids {
+		otherIndex, ok := other.idIndex[id]
+		if !ok {
+			diff.Removed = append(diff.Removed, id)
+			continue
+		}
+
+		var changes map[string]CSVCellChange
+		for _, column := range columns {
+			oldValue := t.cell(i, column)
+			newValue := other.cell(otherIndex, column)
+			if oldValue == newValue {
+				continue
+			}
+			if changes == nil {
+				changes = make(map[string]CSVCellChange)
+			}
+			changes[column] = CSVCellChange{Old: oldValue, New: newValue}
+		}
+		if changes != nil {
+			diff.Changed = append(diff.Changed, CSVRowChange{ID: id, Columns: changes})
+		}
+	}
+
+	for _, id := range other.ids {
+		if _, ok := t.idIndex[id]; !ok {
+			diff.Added = append(diff.Added, id)
+		}
+	}
+
+	return diff
+}
+
+// cell returns the value of the column in the row with the provided index or empty string if there is no such column.
+func (t *CSVTable) cell(rowIndex int, column string) string {
+	colIndex, ok := t.headerIndex[column]
+	if !ok || colIndex >= len(t.rows[rowIndex]) {
+		return ""
+	}
+	return t.rows[rowIndex][colIndex]
+}
+
+// BindRow populates the struct pointed to by out with the values of the row with the given ID.
+// Struct fields are matched to columns by the `csv:"Column"` tag or by the field name if there is no tag,
+// fields with `csv:"-"` tag and unexported fields are skipped, as well as fields without a matching column.
+// A field matching the ID column receives the row ID.
+// Supported field types are string, bool, integers, floats and types implementing [encoding.TextUnmarshaler].
+// Empty cells leave the field with its zero value.
+// Returns an error if the row does not exist, out is not a pointer to a struct or a value cannot be converted.
+func (t *CSVTable) BindRow(id string, out any) error {
+	rowIndex, ok := t.idIndex[id]
+	if !ok {
+		return fmt.Errorf("row %q not found", id)
+	}
+
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("out must be a non-nil pointer to a struct, got %T", out)
+	}
+
+	return t.bindRow(rowIndex, v.Elem())
+}
+
+// BindAll returns all rows of the table converted to structs of type T in row order.
+// See [CSVTable.BindRow] for the rules of matching columns to struct fields.
+// Returns an error if T is not a struct or a value cannot be converted.
+func BindAll[T any](t *CSVTable) ([]T, error) {
+	if typ := reflect.TypeFor[T](); typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("type must be a struct, got %s", typ)
+	}
+
+	out := make([]T, len(t.rows))
+	for i := range t.rows {
+		if err := t.bindRow(i, reflect.ValueOf(&out[i]).Elem()); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (t *CSVTable) bindRow(rowIndex int, v reflect.Value) error {
+	rowData := t.rows[rowIndex]
+	typ := v.Type()
+
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		column := field.Name
+		if tag, ok := field.Tag.Lookup("csv"); ok {
+			if tag == "-" {
+				continue
+			}
+			column = tag
+		}
+
+		colIndex, ok := t.headerIndex[column]
+		if !ok || colIndex >= len(rowData) || rowData[colIndex] == "" {
+			continue
+		}
+
+		if err := setFieldFromString(v.Field(i), rowData[colIndex]); err != nil {
+			return fmt.Errorf("row %q: field %q: %w", t.ids[rowIndex], field.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func setFieldFromString(field reflect.Value, value string) error {
+	if field.CanAddr() {
+		if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(value))
+		}
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return nil
+}
+
 // CSVTableSafe is a thread-safe wrapper around CSVTable that provides
 // synchronized access to the underlying data using a mutex.
 type CSVTableSafe struct {
@@ -672,6 +2130,16 @@ func NewCSVTableSafeFromReader(reader io.Reader) (*CSVTableSafe, error) {
 	return &CSVTableSafe{table: table}, nil
 }
 
+// NewCSVTableSafeFromReaders creates a new thread-safe CSVTable from several readers.
+// See [NewCSVTableFromReaders] for details.
+func NewCSVTableSafeFromReaders(readers []io.Reader, opts CSVReadersOptions) (*CSVTableSafe, CSVReadersReport, error) {
+	table, report, err := NewCSVTableFromReaders(readers, opts)
+	if err != nil {
+		return nil, report, err
+	}
+	return &CSVTableSafe{table: table}, report, nil
+}
+
 // NewCSVTableSafe creates a new thread-safe CSVTable from records.
 func NewCSVTableSafe(records [][]string) *CSVTableSafe {
 	return &CSVTableSafe{
@@ -686,6 +2154,26 @@ func NewCSVTableSafeFromMap(data map[string]map[string]string, idColumnName ...s
 	}
 }
 
+// NewCSVTableSafeFromNDJSON creates a new thread-safe CSVTable from newline-delimited JSON.
+// See [NewCSVTableFromNDJSON] for details.
+func NewCSVTableSafeFromNDJSON(r io.Reader, idField string) (*CSVTableSafe, error) {
+	table, err := NewCSVTableFromNDJSON(r, idField)
+	if err != nil {
+		return nil, err
+	}
+	return &CSVTableSafe{
+		table: table,
+	}, nil
+}
+
+// NewCSVTableSafeFromMapWithOptions creates a new thread-safe CSVTable from a map structure with the provided options.
+// See [NewCSVTableFromMapWithOptions] for details.
+func NewCSVTableSafeFromMapWithOptions(data map[string]map[string]string, opts CSVMapOptions) *CSVTableSafe {
+	return &CSVTableSafe{
+		table: NewCSVTableFromMapWithOptions(data, opts),
+	}
+}
+
 // AddRow adds a new row to the table in a thread-safe manner.
 func (t *CSVTableSafe) AddRow(id string, row map[string]string) {
 	t.mu.Lock()
@@ -758,6 +2246,14 @@ func (t *CSVTableSafe) Value(slug, key string) string {
 	return t.table.Value(slug, key)
 }
 
+// LookupValue returns the value for the given ID and column and true if the cell is present.
+// See [CSVTable.LookupValue] for details.
+func (t *CSVTableSafe) LookupValue(id, column string) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.table.LookupValue(id, column)
+}
+
 // Has returns true if a row with the given ID exists in the table.
 func (t *CSVTableSafe) Has(slug string) bool {
 	t.mu.RLock()
@@ -765,6 +2261,20 @@ func (t *CSVTableSafe) Has(slug string) bool {
 	return t.table.Has(slug)
 }
 
+// HasAll returns true if rows with all the given IDs exist in the table.
+func (t *CSVTableSafe) HasAll(ids ...string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.table.HasAll(ids...)
+}
+
+// MissingIDs returns the given IDs that are absent in the table.
+func (t *CSVTableSafe) MissingIDs(ids ...string) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.table.MissingIDs(ids...)
+}
+
 // Bytes returns the table as a CSV-formatted byte slice.
 func (t *CSVTableSafe) Bytes() []byte {
 	t.mu.RLock()
@@ -772,6 +2282,45 @@ func (t *CSVTableSafe) Bytes() []byte {
 	return t.table.Bytes()
 }
 
+// FormatColumn registers a formatter of the column in a thread-safe manner.
+// See [CSVTable.FormatColumn] for details.
+func (t *CSVTableSafe) FormatColumn(column string, f func(raw string) string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.table.FormatColumn(column, f)
+}
+
+// BytesFormatted returns the table as a CSV-formatted byte slice with formatted values in a thread-safe manner.
+// See [CSVTable.BytesFormatted] for details.
+func (t *CSVTableSafe) BytesFormatted(formatters map[string]func(string) string) []byte {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.table.BytesFormatted(formatters)
+}
+
+// BytesEncoded returns the table as a CSV-formatted byte slice in the provided encoding in a thread-safe manner.
+// See [CSVTable.BytesEncoded] for details.
+func (t *CSVTableSafe) BytesEncoded(enc CSVEncoding) ([]byte, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.table.BytesEncoded(enc)
+}
+
+// WriteNDJSON writes the table to w as newline-delimited JSON in a thread-safe manner.
+// See [CSVTable.WriteNDJSON] for details.
+func (t *CSVTableSafe) WriteNDJSON(w io.Writer) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.table.WriteNDJSON(w)
+}
+
+// WriteTo writes the table in CSV format to w in a thread-safe manner.
+// The lock is released before writing, so a slow writer does not block other operations.
+func (t *CSVTableSafe) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(t.Bytes())
+	return int64(n), err
+}
+
 // DeleteColumn removes the specified column from the table.
 func (t *CSVTableSafe) DeleteColumn(column string) {
 	t.mu.Lock()
@@ -800,6 +2349,69 @@ func (t *CSVTableSafe) UpdateColumn(column string, values []string) {
 	t.table.UpdateColumn(column, values)
 }
 
+// ReplaceRegex replaces matches of the pattern in every cell of the column in a thread-safe manner.
+// See [CSVTable.ReplaceRegex] for details.
+func (t *CSVTableSafe) ReplaceRegex(column, pattern, replacement string) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.table.ReplaceRegex(column, pattern, replacement)
+}
+
+// ReplaceRegexAll replaces matches of the pattern in every cell of all columns except the ID column
+// in a thread-safe manner. See [CSVTable.ReplaceRegexAll] for details.
+func (t *CSVTableSafe) ReplaceRegexAll(pattern, replacement string) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.table.ReplaceRegexAll(pattern, replacement)
+}
+
+// ConvertColumns validates and canonicalizes values of the columns in a thread-safe manner.
+// See [CSVTable.ConvertColumns] for details.
+func (t *CSVTableSafe) ConvertColumns(types map[string]ColumnType) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.table.ConvertColumns(types)
+}
+
+// Describe returns a summary of values of every column in a thread-safe manner.
+// See [CSVTable.Describe] for details.
+func (t *CSVTableSafe) Describe() map[string]ColumnStats {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.table.Describe()
+}
+
+// SetValue sets the value of the cell in a thread-safe manner.
+// See [CSVTable.SetValue] for details.
+func (t *CSVTableSafe) SetValue(id, column, value string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.table.SetValue(id, column, value)
+}
+
+// TrackChanges enables or disables recording of changed cells in a thread-safe manner.
+// See [CSVTable.TrackChanges] for details.
+func (t *CSVTableSafe) TrackChanges(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.table.TrackChanges(enabled)
+}
+
+// DirtyCells returns the changed cells in a thread-safe manner.
+// See [CSVTable.DirtyCells] for details.
+func (t *CSVTableSafe) DirtyCells() []CSVCellRef {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.table.DirtyCells()
+}
+
+// ClearDirty forgets the recorded changed cells in a thread-safe manner.
+func (t *CSVTableSafe) ClearDirty() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.table.ClearDirty()
+}
+
 // UpdateRow updates an existing row with the given ID and data.
 func (t *CSVTableSafe) UpdateRow(id string, row map[string]string) bool {
 	t.mu.Lock()
@@ -828,6 +2440,62 @@ func (t *CSVTableSafe) Sort(column string, direction SortDirection) {
 	t.table.Sort(column, direction)
 }
 
+// MoveRow relocates the row with the specified ID to toIndex in a thread-safe manner.
+// See [CSVTable.MoveRow] for details.
+func (t *CSVTableSafe) MoveRow(id string, toIndex int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.table.MoveRow(id, toIndex)
+}
+
+// SortRowsByIDs reorders the rows by the provided IDs in a thread-safe manner.
+// See [CSVTable.SortRowsByIDs] for details.
+func (t *CSVTableSafe) SortRowsByIDs(ids []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.table.SortRowsByIDs(ids)
+}
+
+// ReorderColumns rearranges the columns of the table in a thread-safe manner.
+// See [CSVTable.ReorderColumns] for details.
+func (t *CSVTableSafe) ReorderColumns(order []string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.table.ReorderColumns(order)
+}
+
+// ReorderColumnsPartial rearranges some of the columns of the table in a thread-safe manner.
+// See [CSVTable.ReorderColumnsPartial] for details.
+func (t *CSVTableSafe) ReorderColumnsPartial(order []string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.table.ReorderColumnsPartial(order)
+}
+
+// MergeRows merges rows that share the same ID in a thread-safe manner.
+// See [CSVTable.MergeRows] for details.
+func (t *CSVTableSafe) MergeRows(strategy map[string]MergeFunc) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.table.MergeRows(strategy)
+}
+
+// RenameColumns renames columns of the table in a thread-safe manner.
+// See [CSVTable.RenameColumns] for details.
+func (t *CSVTableSafe) RenameColumns(mapping map[string]string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.table.RenameColumns(mapping)
+}
+
+// DedupeHeaders makes duplicate column names unique in a thread-safe manner.
+// See [CSVTable.DedupeHeaders] for details.
+func (t *CSVTableSafe) DedupeHeaders() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.table.DedupeHeaders()
+}
+
 // Unwrap returns the underlying CSVTable.
 // WARNING: This breaks thread safety. Only use when you're sure no other
 // goroutines are accessing the table.
@@ -855,3 +2523,72 @@ func (t *CSVTableSafe) LookupRowSorted(id string) ([]string, bool) {
 	defer t.mu.RUnlock()
 	return t.table.LookupRowSorted(id)
 }
+
+// Diff compares the table with the other one in a thread-safe manner.
+// See [CSVTable.Diff] for details.
+func (t *CSVTableSafe) Diff(other *CSVTableSafe) CSVDiff {
+	if t == other {
+		return CSVDiff{}
+	}
+
+	// Take a snapshot of the other table to avoid holding two locks at once
+	var otherTable *CSVTable
+	if other != nil {
+		otherTable = other.Copy().table
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.table.Diff(otherTable)
+}
+
+// BindRow populates the struct pointed to by out with the values of the row with the given ID.
+// See [CSVTable.BindRow] for details.
+func (t *CSVTableSafe) BindRow(id string, out any) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.table.BindRow(id, out)
+}
+
+// IterColumn returns an iterator over the values of the column yielding row ID and cell value in row order.
+// It iterates over a snapshot of the column taken under a read lock when the iteration starts,
+// so it is safe to modify the table inside the loop.
+func (t *CSVTableSafe) IterColumn(name string) iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		t.mu.RLock()
+		_, ok := t.table.headerIndex[name]
+		ids := t.table.AllIDs()
+		values := t.table.column(name)
+		t.mu.RUnlock()
+
+		if !ok {
+			return
+		}
+		for i, id := range ids {
+			if !yield(id, values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// IterColumns returns an iterator over the columns yielding each header with the values of the column in row order.
+// It iterates over a snapshot of the table taken under a read lock when the iteration starts,
+// so it is safe to modify the table inside the loop.
+func (t *CSVTableSafe) IterColumns() iter.Seq2[string, []string] {
+	return func(yield func(string, []string) bool) {
+		t.mu.RLock()
+		headers := t.table.Headers()
+		columns := make([][]string, len(headers))
+		for i, header := range headers {
+			columns[i] = t.table.column(header)
+		}
+		t.mu.RUnlock()
+
+		for i, header := range headers {
+			if !yield(header, columns[i]) {
+				return
+			}
+		}
+	}
+}
@@ -1,7 +1,12 @@
 package abstract_test
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -1615,3 +1620,1283 @@ func TestCSVTableSafeFind(t *testing.T) {
 		t.Errorf("Expected original data to be unchanged, got %s", got)
 	}
 }
+
+func TestCSVTableDiff(t *testing.T) {
+	oldTable := abstract.NewCSVTable([][]string{
+		{"ID", "Name", "Value"},
+		{"row1", "Test1", "100"},
+		{"row2", "Test2", "200"},
+		{"row3", "Test3", "300"},
+	})
+	newTable := abstract.NewCSVTable([][]string{
+		{"ID", "Name", "Value", "Extra"},
+		{"row4", "Test4", "400", ""},
+		{"row2", "Test2", "250", ""},
+		{"row1", "Test1", "100", "x"},
+	})
+
+	diff := oldTable.Diff(newTable)
+	if diff.IsEmpty() {
+		t.Fatal("Expected non-empty diff")
+	}
+	if !reflect.DeepEqual(diff.Added, []string{"row4"}) {
+		t.Errorf("Expected Added = [row4], got %v", diff.Added)
+	}
+	if !reflect.DeepEqual(diff.Removed, []string{"row3"}) {
+		t.Errorf("Expected Removed = [row3], got %v", diff.Removed)
+	}
+
+	expectedChanged := []abstract.CSVRowChange{
+		{ID: "row1", Columns: map[string]abstract.CSVCellChange{"Extra": {Old: "", New: "x"}}},
+		{ID: "row2", Columns: map[string]abstract.CSVCellChange{"Value": {Old: "200", New: "250"}}},
+	}
+	if !reflect.DeepEqual(diff.Changed, expectedChanged) {
+		t.Errorf("Expected Changed = %v, got %v", expectedChanged, diff.Changed)
+	}
+
+	if d := oldTable.Diff(oldTable.Copy()); !d.IsEmpty() {
+		t.Errorf("Expected empty diff for equal tables, got %+v", d)
+	}
+
+	nilDiff := oldTable.Diff(nil)
+	if !reflect.DeepEqual(nilDiff.Removed, []string{"row1", "row2", "row3"}) || len(nilDiff.Added) != 0 || len(nilDiff.Changed) != 0 {
+		t.Errorf("Expected all rows to be removed when diffing with nil, got %+v", nilDiff)
+	}
+}
+
+func TestCSVTableSafeDiff(t *testing.T) {
+	oldTable := abstract.NewCSVTableSafe([][]string{
+		{"ID", "Name"},
+		{"row1", "Test1"},
+	})
+	newTable := abstract.NewCSVTableSafe([][]string{
+		{"ID", "Name"},
+		{"row1", "Changed"},
+		{"row2", "Test2"},
+	})
+
+	diff := oldTable.Diff(newTable)
+	if !reflect.DeepEqual(diff.Added, []string{"row2"}) {
+		t.Errorf("Expected Added = [row2], got %v", diff.Added)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Columns["Name"].New != "Changed" {
+		t.Errorf("Expected row1 Name to be changed, got %v", diff.Changed)
+	}
+
+	if d := oldTable.Diff(oldTable); !d.IsEmpty() {
+		t.Errorf("Expected empty diff with itself, got %+v", d)
+	}
+	if d := newTable.Diff(nil); !reflect.DeepEqual(d.Removed, []string{"row1", "row2"}) {
+		t.Errorf("Expected all rows to be removed when diffing with nil, got %+v", d)
+	}
+}
+
+type csvTestLevel int
+
+func (l *csvTestLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+type csvTestRecord struct {
+	ID      string       `csv:"ID"`
+	Name    string       `csv:"Name"`
+	Age     int          `csv:"Age"`
+	Score   float64      `csv:"Score"`
+	Active  bool         `csv:"Active"`
+	Level   csvTestLevel `csv:"Level"`
+	Comment string
+	Ignored string `csv:"-"`
+}
+
+func TestBindRow(t *testing.T) {
+	table := abstract.NewCSVTable([][]string{
+		{"ID", "Name", "Age", "Score", "Active", "Level", "Comment", "Ignored"},
+		{"row1", "Alice", "30", "9.5", "true", "high", "hello", "x"},
+		{"row2", "Bob", "", "", "", "", "", ""},
+		{"row3", "Carol", "old", "1", "false", "low", "", ""},
+	})
+
+	var rec csvTestRecord
+	if err := table.BindRow("row1", &rec); err != nil {
+		t.Fatalf("BindRow failed: %v", err)
+	}
+	expected := csvTestRecord{ID: "row1", Name: "Alice", Age: 30, Score: 9.5, Active: true, Level: 2, Comment: "hello"}
+	if rec != expected {
+		t.Errorf("Expected %+v, got %+v", expected, rec)
+	}
+
+	rec = csvTestRecord{}
+	if err := table.BindRow("row2", &rec); err != nil {
+		t.Fatalf("BindRow failed: %v", err)
+	}
+	if rec.Name != "Bob" || rec.Age != 0 || rec.Active {
+		t.Errorf("Expected empty cells to leave zero values, got %+v", rec)
+	}
+
+	err := table.BindRow("row3", &rec)
+	if err == nil {
+		t.Fatal("Expected conversion error")
+	}
+	if !strings.Contains(err.Error(), "row3") || !strings.Contains(err.Error(), "Age") {
+		t.Errorf("Expected error to contain row id and field, got %v", err)
+	}
+
+	if err := table.BindRow("missing", &rec); err == nil {
+		t.Error("Expected error for missing row")
+	}
+	if err := table.BindRow("row1", rec); err == nil {
+		t.Error("Expected error for non-pointer")
+	}
+	var nilRec *csvTestRecord
+	if err := table.BindRow("row1", nilRec); err == nil {
+		t.Error("Expected error for nil pointer")
+	}
+
+	safe := abstract.NewCSVTableSafe([][]string{{"ID", "Name"}, {"row1", "Alice"}})
+	rec = csvTestRecord{}
+	if err := safe.BindRow("row1", &rec); err != nil || rec.Name != "Alice" {
+		t.Errorf("Expected Alice, got %+v (err %v)", rec, err)
+	}
+}
+
+func TestBindAll(t *testing.T) {
+	table := abstract.NewCSVTable([][]string{
+		{"ID", "Name", "Age"},
+		{"row2", "Bob", "25"},
+		{"row1", "Alice", "30"},
+	})
+
+	records, err := abstract.BindAll[csvTestRecord](table)
+	if err != nil {
+		t.Fatalf("BindAll failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+	if records[0].ID != "row2" || records[0].Age != 25 || records[1].Name != "Alice" {
+		t.Errorf("Unexpected records: %+v", records)
+	}
+
+	table.AddRow("row3", map[string]string{"Age": "abc"})
+	if _, err := abstract.BindAll[csvTestRecord](table); err == nil || !strings.Contains(err.Error(), "row3") {
+		t.Errorf("Expected conversion error for row3, got %v", err)
+	}
+
+	if _, err := abstract.BindAll[int](table); err == nil {
+		t.Error("Expected error for non-struct type")
+	}
+}
+
+func TestNewCSVTableFromReaders(t *testing.T) {
+	readers := func() []io.Reader {
+		return []io.Reader{
+			strings.NewReader("ID,Name,Age\nrow1,Alice,30\nrow2,Bob,25"),
+			strings.NewReader("Key,Name,City\nrow3,Carol,Paris\nrow1,Alice2,Berlin"),
+		}
+	}
+
+	table, report, err := abstract.NewCSVTableFromReaders(readers(), abstract.CSVReadersOptions{})
+	if err != nil {
+		t.Fatalf("NewCSVTableFromReaders failed: %v", err)
+	}
+	if !reflect.DeepEqual(table.Headers(), []string{"ID", "Name", "Age", "City"}) {
+		t.Errorf("Unexpected headers: %v", table.Headers())
+	}
+	if !reflect.DeepEqual(table.AllIDs(), []string{"row1", "row2", "row3"}) {
+		t.Errorf("Unexpected ids: %v", table.AllIDs())
+	}
+	if table.Value("row1", "Name") != "Alice" || table.Value("row1", "City") != "" {
+		t.Errorf("Expected first row1 to be kept, got %v", table.Row("row1"))
+	}
+	if table.Value("row3", "City") != "Paris" || table.Value("row3", "Age") != "" {
+		t.Errorf("Unexpected row3: %v", table.Row("row3"))
+	}
+	if !reflect.DeepEqual(report.Columns, [][]string{{"Name", "Age"}, {"Name", "City"}}) {
+		t.Errorf("Unexpected report columns: %v", report.Columns)
+	}
+	if !reflect.DeepEqual(report.Rows, []int{2, 2}) {
+		t.Errorf("Unexpected report rows: %v", report.Rows)
+	}
+
+	table, _, err = abstract.NewCSVTableFromReaders(readers(), abstract.CSVReadersOptions{Conflict: abstract.CSVConflictKeepLast})
+	if err != nil {
+		t.Fatalf("NewCSVTableFromReaders failed: %v", err)
+	}
+	if table.Value("row1", "Name") != "Alice2" || table.Value("row1", "Age") != "" || table.AllIDs()[0] != "row1" {
+		t.Errorf("Expected last row1 at first position, got %v", table.Row("row1"))
+	}
+
+	if _, _, err = abstract.NewCSVTableFromReaders(readers(), abstract.CSVReadersOptions{Conflict: abstract.CSVConflictError}); err == nil {
+		t.Error("Expected duplicate id error")
+	}
+
+	table, _, err = abstract.NewCSVTableFromReaders(readers(), abstract.CSVReadersOptions{
+		Conflict:   abstract.CSVConflictError,
+		IDPrefixes: []string{"a/", "b/"},
+	})
+	if err != nil {
+		t.Fatalf("NewCSVTableFromReaders failed: %v", err)
+	}
+	if !reflect.DeepEqual(table.AllIDs(), []string{"a/row1", "a/row2", "b/row3", "b/row1"}) {
+		t.Errorf("Unexpected prefixed ids: %v", table.AllIDs())
+	}
+
+	_, _, err = abstract.NewCSVTableFromReaders([]io.Reader{strings.NewReader("a,b\n\"bad")}, abstract.CSVReadersOptions{})
+	if err == nil {
+		t.Error("Expected parse error")
+	}
+
+	safe, _, err := abstract.NewCSVTableSafeFromReaders(readers(), abstract.CSVReadersOptions{})
+	if err != nil || len(safe.AllIDs()) != 3 {
+		t.Errorf("Expected 3 rows in safe table, got %v (err %v)", safe, err)
+	}
+
+	// A later column named like the ID column must not overwrite row IDs
+	table, report, err = abstract.NewCSVTableFromReaders([]io.Reader{
+		strings.NewReader("ID,Name\nrow1,Alice"),
+		strings.NewReader("Key,ID,Name\nrow2,other,Bob"),
+	}, abstract.CSVReadersOptions{})
+	if err != nil {
+		t.Fatalf("NewCSVTableFromReaders failed: %v", err)
+	}
+	if !reflect.DeepEqual(table.AllIDs(), []string{"row1", "row2"}) || table.Value("row2", "Name") != "Bob" {
+		t.Errorf("Unexpected rows: %v", table.AllSorted())
+	}
+	if !reflect.DeepEqual(table.AllSorted(), [][]string{{"row1", "Alice"}, {"row2", "Bob"}}) {
+		t.Errorf("Expected row IDs to stay in sync, got %v", table.AllSorted())
+	}
+	if !reflect.DeepEqual(report.Skipped, [][]string{nil, {"ID"}}) || !reflect.DeepEqual(report.Columns[1], []string{"Name"}) {
+		t.Errorf("Expected clashing column to be reported as skipped, got %v %v", report.Skipped, report.Columns)
+	}
+}
+
+func TestAggregateStream(t *testing.T) {
+	data := "ID,Category,Amount\n1,food,10\n2,rent,500\n3,food,15\n4,fun,7\n5,food,5\n"
+
+	sum := func(acc, v string) string {
+		a, _ := strconv.Atoi(acc)
+		b, _ := strconv.Atoi(v)
+		return strconv.Itoa(a + b)
+	}
+	result, err := abstract.AggregateStream(strings.NewReader(data), "Category", "Amount", sum)
+	if err != nil {
+		t.Fatalf("AggregateStream failed: %v", err)
+	}
+	expected := map[string]string{"food": "30", "rent": "500", "fun": "7"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+
+	count := func(acc, _ string) string {
+		n, _ := strconv.Atoi(acc)
+		return strconv.Itoa(n + 1)
+	}
+	result, err = abstract.AggregateStream(strings.NewReader(data), "Category", "ID", count)
+	if err != nil {
+		t.Fatalf("AggregateStream failed: %v", err)
+	}
+	if result["food"] != "3" || result["fun"] != "1" {
+		t.Errorf("Unexpected counts: %v", result)
+	}
+
+	if _, err := abstract.AggregateStream(strings.NewReader(data), "Missing", "Amount", sum); err == nil {
+		t.Error("Expected error for missing group column")
+	}
+	if _, err := abstract.AggregateStream(strings.NewReader(data), "Category", "Missing", sum); err == nil {
+		t.Error("Expected error for missing value column")
+	}
+	if _, err := abstract.AggregateStream(strings.NewReader(""), "Category", "Amount", sum); err == nil {
+		t.Error("Expected error for empty input")
+	}
+	if _, err := abstract.AggregateStream(strings.NewReader("Category,Amount\nfood,\"1"), "Category", "Amount", sum); err == nil {
+		t.Error("Expected error for malformed input")
+	}
+}
+
+func TestCSVTableIterColumn(t *testing.T) {
+	table := abstract.NewCSVTable([][]string{
+		{"ID", "Name", "Value"},
+		{"row1", "Test1", "100"},
+		{"row2", "Test2", "200"},
+		{"row3", "Test3", "300"},
+	})
+
+	var ids, values []string
+	for id, value := range table.IterColumn("Value") {
+		ids = append(ids, id)
+		values = append(values, value)
+	}
+	if !reflect.DeepEqual(ids, []string{"row1", "row2", "row3"}) {
+		t.Errorf("Unexpected ids: %v", ids)
+	}
+	if !reflect.DeepEqual(values, []string{"100", "200", "300"}) {
+		t.Errorf("Unexpected values: %v", values)
+	}
+
+	for id := range table.IterColumn("Missing") {
+		t.Errorf("Unexpected row %q for missing column", id)
+	}
+
+	count := 0
+	for range table.IterColumn("Name") {
+		count++
+		if count == 2 {
+			break
+		}
+	}
+	if count != 2 {
+		t.Errorf("Expected iteration to stop after 2 rows, got %d", count)
+	}
+
+	columns := map[string][]string{}
+	var headers []string
+	for header, column := range table.IterColumns() {
+		headers = append(headers, header)
+		columns[header] = column
+	}
+	if !reflect.DeepEqual(headers, []string{"ID", "Name", "Value"}) {
+		t.Errorf("Unexpected headers: %v", headers)
+	}
+	if !reflect.DeepEqual(columns["Name"], []string{"Test1", "Test2", "Test3"}) {
+		t.Errorf("Unexpected Name column: %v", columns["Name"])
+	}
+	columns["Name"][0] = "changed"
+	if got := table.Value("row1", "Name"); got != "Test1" {
+		t.Errorf("Expected column to be a copy, got %q", got)
+	}
+}
+
+func TestCSVTableSafeIterColumn(t *testing.T) {
+	table := abstract.NewCSVTableSafe([][]string{
+		{"ID", "Name", "Value"},
+		{"row1", "Test1", "100"},
+		{"row2", "Test2", "200"},
+	})
+
+	var values []string
+	for id, value := range table.IterColumn("Value") {
+		values = append(values, value)
+		table.UpdateRow(id, map[string]string{"Value": value + "0"})
+	}
+	if !reflect.DeepEqual(values, []string{"100", "200"}) {
+		t.Errorf("Unexpected values: %v", values)
+	}
+	if got := table.Value("row2", "Value"); got != "2000" {
+		t.Errorf("Expected value updated in loop, got %q", got)
+	}
+
+	var headers []string
+	for header, column := range table.IterColumns() {
+		headers = append(headers, header)
+		table.DeleteColumn(header)
+		if len(column) != 2 {
+			t.Errorf("Expected 2 values in column %s, got %d", header, len(column))
+		}
+	}
+	if !reflect.DeepEqual(headers, []string{"ID", "Name", "Value"}) {
+		t.Errorf("Unexpected headers: %v", headers)
+	}
+}
+
+func TestNewCSVTableFromMapWithColumnOrder(t *testing.T) {
+	data := map[string]map[string]string{
+		"row2": {"Zeta": "z2", "Alpha": "a2", "Mid": "m2"},
+		"row1": {"Zeta": "z1", "Alpha": "a1", "Extra": "e1"},
+	}
+
+	table := abstract.NewCSVTableFromMapWithOptions(data, abstract.CSVMapOptions{
+		IDColumn:    "Key",
+		ColumnOrder: []string{"Zeta", "Missing", "Key", "Zeta", "Alpha"},
+	})
+
+	expectedHeaders := []string{"Key", "Zeta", "Missing", "Alpha", "Extra", "Mid"}
+	if !reflect.DeepEqual(table.Headers(), expectedHeaders) {
+		t.Fatalf("Expected headers %v, got %v", expectedHeaders, table.Headers())
+	}
+	if !reflect.DeepEqual(table.AllIDs(), []string{"row1", "row2"}) {
+		t.Errorf("Unexpected ids: %v", table.AllIDs())
+	}
+
+	var buf strings.Builder
+	n, err := table.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(n) != buf.Len() || buf.String() != string(table.Bytes()) {
+		t.Errorf("WriteTo output differs from Bytes: %q", buf.String())
+	}
+	if !strings.HasPrefix(buf.String(), `"Key","Zeta","Missing","Alpha","Extra","Mid"`+"\n") {
+		t.Errorf("Unexpected header line: %q", buf.String())
+	}
+
+	restored, err := abstract.NewCSVTableFromReader(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restored.Headers(), expectedHeaders) {
+		t.Errorf("Expected headers %v after round-trip, got %v", expectedHeaders, restored.Headers())
+	}
+	if !reflect.DeepEqual(restored.AllSorted(), table.AllSorted()) {
+		t.Errorf("Round-trip changed table:\n%v\n%v", table.AllSorted(), restored.AllSorted())
+	}
+
+	again := abstract.NewCSVTableFromMapWithOptions(restored.All(), abstract.CSVMapOptions{
+		IDColumn:    "Key",
+		ColumnOrder: restored.Headers(),
+	})
+	if !bytes.Equal(again.Bytes(), table.Bytes()) {
+		t.Errorf("Expected stable layout after map round-trip:\n%s\n%s", table.Bytes(), again.Bytes())
+	}
+
+	empty := abstract.NewCSVTableFromMapWithOptions(nil, abstract.CSVMapOptions{ColumnOrder: []string{"A", "B"}})
+	if !reflect.DeepEqual(empty.Headers(), []string{"id", "A", "B"}) {
+		t.Errorf("Expected headers for empty data, got %v", empty.Headers())
+	}
+
+	safe := abstract.NewCSVTableSafeFromMapWithOptions(data, abstract.CSVMapOptions{ColumnOrder: []string{"Mid"}})
+	buf.Reset()
+	if _, err := safe.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), `"id","Mid","Alpha","Extra","Zeta"`+"\n") {
+		t.Errorf("Unexpected header line: %q", buf.String())
+	}
+}
+
+func TestCSVTableTrackChanges(t *testing.T) {
+	table := abstract.NewCSVTable([][]string{
+		{"ID", "Name", "Value"},
+		{"row1", "Test1", "100"},
+		{"row2", "Test2", "200"},
+	})
+
+	table.SetValue("row1", "Name", "untracked")
+	if cells := table.DirtyCells(); cells != nil {
+		t.Errorf("Expected no dirty cells without tracking, got %v", cells)
+	}
+
+	table.TrackChanges(true)
+	if !table.SetValue("row2", "Value", "201") {
+		t.Error("Expected SetValue to succeed")
+	}
+	if table.SetValue("row3", "Value", "1") || table.SetValue("row1", "Missing", "1") {
+		t.Error("Expected SetValue to fail for missing row or column")
+	}
+	table.UpdateRow("row1", map[string]string{"Name": "untracked", "Value": "101"})
+	table.UpdateColumn("Name", []string{"untracked", "New2"})
+
+	expected := []abstract.CSVCellRef{
+		{ID: "row1", Column: "Value"},
+		{ID: "row2", Column: "Name"},
+		{ID: "row2", Column: "Value"},
+	}
+	if got := table.DirtyCells(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected dirty cells %v, got %v", expected, got)
+	}
+	if got := table.Value("row2", "Value"); got != "201" {
+		t.Errorf("Expected value 201, got %q", got)
+	}
+
+	copied := table.Copy()
+	table.ClearDirty()
+	if got := table.DirtyCells(); len(got) != 0 || got == nil {
+		t.Errorf("Expected empty dirty cells after ClearDirty, got %v", got)
+	}
+	if got := copied.DirtyCells(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected copy to keep dirty cells, got %v", got)
+	}
+
+	table.SetValue("row1", "Name", "again")
+	if got := table.DirtyCells(); len(got) != 1 {
+		t.Errorf("Expected 1 dirty cell, got %v", got)
+	}
+	table.TrackChanges(false)
+	if got := table.DirtyCells(); got != nil {
+		t.Errorf("Expected nil dirty cells after disabling tracking, got %v", got)
+	}
+}
+
+func TestCSVTableTrackChangesRowsAndColumns(t *testing.T) {
+	table := abstract.NewCSVTable([][]string{
+		{"ID", "Name", "Value"},
+		{"row1", "Test1", "100"},
+		{"row2", "Test2", "200"},
+	})
+	table.TrackChanges(true)
+
+	if table.SetValue("row1", "ID", "renamed") {
+		t.Error("Expected SetValue to fail for the ID column")
+	}
+	table.AddRow("row1", map[string]string{"Name": "Test1", "Value": "101"})
+	table.AddRow("row3", map[string]string{"Name": "Test3"})
+	table.SetValue("row2", "Name", "New2")
+
+	expected := []abstract.CSVCellRef{
+		{ID: "row1", Column: "Value"},
+		{ID: "row2", Column: "Name"},
+		{ID: "row3", Column: "Name"},
+	}
+	if got := table.DirtyCells(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected dirty cells %v, got %v", expected, got)
+	}
+
+	table.DeleteRow("row2")
+	table.DeleteColumn("Value")
+	expected = []abstract.CSVCellRef{{ID: "row3", Column: "Name"}}
+	if got := table.DirtyCells(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected dirty cells %v, got %v", expected, got)
+	}
+	if got := table.Value("row1", "ID"); got != "row1" {
+		t.Errorf("Expected ID cell to stay row1, got %q", got)
+	}
+}
+
+func TestCSVTableSafeTrackChanges(t *testing.T) {
+	table := abstract.NewCSVTableSafe([][]string{
+		{"ID", "Name"},
+		{"row1", "Test1"},
+	})
+	table.TrackChanges(true)
+	table.SetValue("row1", "Name", "changed")
+
+	expected := []abstract.CSVCellRef{{ID: "row1", Column: "Name"}}
+	if got := table.DirtyCells(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected dirty cells %v, got %v", expected, got)
+	}
+	table.ClearDirty()
+	if got := table.DirtyCells(); len(got) != 0 {
+		t.Errorf("Expected no dirty cells, got %v", got)
+	}
+}
+
+func TestCSVTableBytesEncoded(t *testing.T) {
+	table := abstract.NewCSVTable([][]string{
+		{"ID", "Name", "Price"},
+		{"row1", "Café", "5€"},
+		{"row2", "Plain", "10"},
+	})
+
+	utf8Data, err := table.BytesEncoded(abstract.CSVEncodingUTF8)
+	if err != nil || !bytes.Equal(utf8Data, table.Bytes()) {
+		t.Errorf("Expected UTF-8 output equal to Bytes, got %q %v", utf8Data, err)
+	}
+
+	bomData, err := table.BytesEncoded(abstract.CSVEncodingUTF8BOM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(bomData, []byte{0xEF, 0xBB, 0xBF}) || !bytes.Equal(bomData[3:], table.Bytes()) {
+		t.Errorf("Unexpected UTF-8 BOM output: %q", bomData)
+	}
+
+	cp1252, err := table.BytesEncoded(abstract.CSVEncodingWindows1252)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(cp1252, []byte("\"Caf\xe9\"")) || !bytes.Contains(cp1252, []byte("\"5\x80\"")) {
+		t.Errorf("Unexpected Windows-1252 output: %q", cp1252)
+	}
+	if len(cp1252) != len(table.Bytes())-1-2 {
+		t.Errorf("Expected multi-byte runes to be encoded as single bytes, got %d bytes", len(cp1252))
+	}
+
+	table.AddRow("row3", map[string]string{"Name": "日本"})
+	if _, err := table.BytesEncoded(abstract.CSVEncodingWindows1252); err == nil {
+		t.Error("Expected error for unmappable rune")
+	}
+	if _, err := table.BytesEncoded(abstract.CSVEncoding(100)); err == nil {
+		t.Error("Expected error for unknown encoding")
+	}
+
+	safe := abstract.NewCSVTableSafe([][]string{{"ID", "Name"}, {"row1", "Ürün"}})
+	data, err := safe.BytesEncoded(abstract.CSVEncodingWindows1252)
+	if err != nil || !bytes.Contains(data, []byte("\xdcr\xfcn")) {
+		t.Errorf("Unexpected Windows-1252 output: %q %v", data, err)
+	}
+}
+
+func TestCSVTableNDJSON(t *testing.T) {
+	input := `{"id":"a","level":"info","count":3,"ok":true}
+{"level":"warn","id":"b","meta":{"k": [1, 2]},"msg":null}
+
+{"id":"c","msg":"quote \" and\nnewline"}
+`
+	table, err := abstract.NewCSVTableFromNDJSON(strings.NewReader(input), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedHeaders := []string{"id", "level", "count", "ok", "meta", "msg"}
+	if !reflect.DeepEqual(table.Headers(), expectedHeaders) {
+		t.Errorf("Expected headers %v, got %v", expectedHeaders, table.Headers())
+	}
+	if !reflect.DeepEqual(table.AllIDs(), []string{"a", "b", "c"}) {
+		t.Errorf("Unexpected ids: %v", table.AllIDs())
+	}
+	checks := map[[2]string]string{
+		{"a", "count"}: "3",
+		{"a", "ok"}:    "true",
+		{"a", "msg"}:   "",
+		{"b", "level"}: "warn",
+		{"b", "meta"}:  `{"k":[1,2]}`,
+		{"b", "msg"}:   "",
+		{"c", "msg"}:   "quote \" and\nnewline",
+		{"c", "level"}: "",
+	}
+	for cell, expected := range checks {
+		if got := table.Value(cell[0], cell[1]); got != expected {
+			t.Errorf("Expected Value(%s, %s) = %q, got %q", cell[0], cell[1], expected, got)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := table.WriteNDJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d: %q", len(lines), buf.String())
+	}
+	if lines[0] != `{"id":"a","level":"info","count":"3","ok":"true","meta":"","msg":""}` {
+		t.Errorf("Unexpected first line: %s", lines[0])
+	}
+
+	restored, err := abstract.NewCSVTableFromNDJSON(&buf, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restored.AllSorted(), table.AllSorted()) {
+		t.Errorf("Round-trip changed table:\n%v\n%v", table.AllSorted(), restored.AllSorted())
+	}
+}
+
+func TestCSVTableNDJSONErrors(t *testing.T) {
+	cases := map[string]string{
+		"invalid json": `{"id":"a"` + "\n" + `{`,
+		"not object":   `["a"]`,
+		"missing id":   `{"name":"a"}`,
+		"empty id":     `{"key":""}`,
+		"duplicate id": `{"key":"a"}` + "\n" + `{"key":"a"}`,
+	}
+	for name, input := range cases {
+		if _, err := abstract.NewCSVTableFromNDJSON(strings.NewReader(input), "key"); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	safe, err := abstract.NewCSVTableSafeFromNDJSON(strings.NewReader(`{"key":"x","v":1}`), "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := safe.WriteNDJSON(&buf); err != nil || buf.String() != `{"key":"x","v":"1"}`+"\n" {
+		t.Errorf("Unexpected output %q %v", buf.String(), err)
+	}
+	if _, err := abstract.NewCSVTableSafeFromNDJSON(strings.NewReader(`{}`), "key"); err == nil {
+		t.Error("Expected error for missing id")
+	}
+}
+
+func TestCSVTableReorderColumns(t *testing.T) {
+	newTable := func() *abstract.CSVTable {
+		return abstract.NewCSVTable([][]string{
+			{"ID", "A", "B", "C"},
+			{"row1", "a1", "b1", "c1"},
+			{"row2", "a2", "b2", "c2"},
+		})
+	}
+
+	table := newTable()
+	if err := table.ReorderColumns([]string{"C", "A", "B"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(table.Headers(), []string{"ID", "C", "A", "B"}) {
+		t.Errorf("Unexpected headers: %v", table.Headers())
+	}
+	if !reflect.DeepEqual(table.RowSorted("row2"), []string{"row2", "c2", "a2", "b2"}) {
+		t.Errorf("Unexpected row: %v", table.RowSorted("row2"))
+	}
+	if got := table.Value("row1", "A"); got != "a1" {
+		t.Errorf("Expected Value(row1, A) = a1, got %q", got)
+	}
+	if !strings.HasPrefix(string(table.Bytes()), `"ID","C","A","B"`+"\n"+`"row1","c1","a1","b1"`) {
+		t.Errorf("Unexpected bytes: %s", table.Bytes())
+	}
+
+	table = newTable()
+	if err := table.ReorderColumnsPartial([]string{"ID", "B"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(table.Headers(), []string{"ID", "B", "A", "C"}) {
+		t.Errorf("Unexpected headers with appended rest: %v", table.Headers())
+	}
+
+	errCases := map[string][]string{
+		"missing":        {"B", "A"},
+		"unknown":        {"A", "B", "C", "D"},
+		"duplicate":      {"A", "A", "B", "C"},
+		"id not first":   {"A", "ID", "B", "C"},
+		"unknown append": {"X"},
+	}
+	for name, order := range errCases {
+		table := newTable()
+		reorder := table.ReorderColumns
+		if name == "unknown append" {
+			reorder = table.ReorderColumnsPartial
+		}
+		if err := reorder(order); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+		if !reflect.DeepEqual(table.Headers(), []string{"ID", "A", "B", "C"}) {
+			t.Errorf("%s: expected table to stay unchanged, got %v", name, table.Headers())
+		}
+	}
+
+	safe := abstract.NewCSVTableSafe([][]string{{"ID", "A", "B"}, {"row1", "a", "b"}})
+	if err := safe.ReorderColumns([]string{"B", "A"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(safe.RowSorted("row1"), []string{"row1", "b", "a"}) {
+		t.Errorf("Unexpected row: %v", safe.RowSorted("row1"))
+	}
+	if err := safe.ReorderColumnsPartial([]string{"A"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(safe.Headers(), []string{"ID", "A", "B"}) {
+		t.Errorf("Unexpected headers: %v", safe.Headers())
+	}
+}
+
+func TestCSVTableMergeRows(t *testing.T) {
+	table := abstract.NewCSVTable([][]string{
+		{"id", "amount", "tags", "name", "city"},
+		{"1", "10", "a", "", "Paris"},
+		{"2", "5", "x", "Bob", ""},
+		{"1", "2.5", "b", "Alice", "London"},
+		{"3", "1", "", "Eve", ""},
+		{"1", "oops", "", "Ann", ""},
+	})
+
+	removed := table.MergeRows(map[string]abstract.MergeFunc{
+		"amount": abstract.MergeSum,
+		"tags":   abstract.MergeConcat(";"),
+	})
+	if removed != 2 {
+		t.Errorf("Expected 2 removed rows, got %d", removed)
+	}
+	if !reflect.DeepEqual(table.AllIDs(), []string{"1", "2", "3"}) {
+		t.Errorf("Unexpected ids: %v", table.AllIDs())
+	}
+
+	want := map[string]string{"amount": "12.5", "tags": "a;b", "name": "Alice", "city": "Paris"}
+	if row := table.Row("1"); !reflect.DeepEqual(row, want) {
+		t.Errorf("Expected merged row %v, got %v", want, row)
+	}
+	if table.Value("3", "name") != "Eve" || table.Value("2", "name") != "Bob" {
+		t.Error("Expected other rows to be kept")
+	}
+	if table.MergeRows(nil) != 0 {
+		t.Error("Expected no rows to merge")
+	}
+
+	safe := abstract.NewCSVTableSafe([][]string{
+		{"id", "name"},
+		{"1", "first"},
+		{"1", "last"},
+	})
+	if safe.MergeRows(map[string]abstract.MergeFunc{"name": abstract.MergeKeepLast}) != 1 {
+		t.Error("Expected 1 removed row")
+	}
+	if safe.Value("1", "name") != "last" {
+		t.Errorf("Expected last value, got %q", safe.Value("1", "name"))
+	}
+}
+
+func TestCSVTableHasAll(t *testing.T) {
+	records := [][]string{
+		{"id", "name"},
+		{"1", "Alice"},
+		{"2", "Bob"},
+	}
+	table := abstract.NewCSVTable(records)
+	if !table.HasAll("1", "2") || !table.HasAll() {
+		t.Error("Expected all rows to exist")
+	}
+	if table.HasAll("1", "3") {
+		t.Error("Expected missing row")
+	}
+	if missing := table.MissingIDs("3", "1", "0"); !reflect.DeepEqual(missing, []string{"3", "0"}) {
+		t.Errorf("Expected missing ids [3 0], got %v", missing)
+	}
+	if missing := table.MissingIDs("1", "2"); missing != nil {
+		t.Errorf("Expected no missing ids, got %v", missing)
+	}
+
+	safe := abstract.NewCSVTableSafe(records)
+	if !safe.HasAll("2") || safe.HasAll("2", "5") {
+		t.Error("Unexpected HasAll result for safe table")
+	}
+	if missing := safe.MissingIDs("5"); !reflect.DeepEqual(missing, []string{"5"}) {
+		t.Errorf("Expected missing ids [5], got %v", missing)
+	}
+}
+
+func TestCSVTableRenameColumns(t *testing.T) {
+	table := abstract.NewCSVTable([][]string{
+		{"id", "first", "second", "third"},
+		{"1", "a", "b", "c"},
+	})
+
+	if err := table.RenameColumns(map[string]string{"first": "second", "second": "first", "id": "key"}); err != nil {
+		t.Fatalf("RenameColumns failed: %v", err)
+	}
+	if !reflect.DeepEqual(table.Headers(), []string{"key", "second", "first", "third"}) {
+		t.Errorf("Unexpected headers: %v", table.Headers())
+	}
+	if table.Value("1", "first") != "b" || table.Value("1", "second") != "a" {
+		t.Error("Expected values to follow renamed columns")
+	}
+
+	for name, mapping := range map[string]map[string]string{
+		"unknown":   {"missing": "x"},
+		"empty":     {"first": ""},
+		"duplicate": {"first": "third"},
+	} {
+		if err := table.RenameColumns(mapping); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if !reflect.DeepEqual(table.Headers(), []string{"key", "second", "first", "third"}) {
+		t.Errorf("Expected headers to be unchanged after error, got %v", table.Headers())
+	}
+
+	safe := abstract.NewCSVTableSafe([][]string{{"id", "a"}, {"1", "x"}})
+	if err := safe.RenameColumns(map[string]string{"a": "b"}); err != nil || safe.Value("1", "b") != "x" {
+		t.Errorf("Expected column to be renamed, err: %v", err)
+	}
+}
+
+func TestCSVTableDedupeHeaders(t *testing.T) {
+	table := abstract.NewCSVTable([][]string{
+		{"id", "Name", "Name", "Name_2", "Name"},
+		{"1", "a", "b", "c", "d"},
+	})
+	if table.Value("1", "Name") != "d" {
+		t.Fatal("Expected duplicate columns to collapse before deduplication")
+	}
+
+	if n := table.DedupeHeaders(); n != 2 {
+		t.Errorf("Expected 2 renamed columns, got %d", n)
+	}
+	if !reflect.DeepEqual(table.Headers(), []string{"id", "Name", "Name_3", "Name_2", "Name_4"}) {
+		t.Errorf("Unexpected headers: %v", table.Headers())
+	}
+	want := map[string]string{"Name": "a", "Name_3": "b", "Name_2": "c", "Name_4": "d"}
+	if row := table.Row("1"); !reflect.DeepEqual(row, want) {
+		t.Errorf("Expected row %v, got %v", want, row)
+	}
+	if n := table.DedupeHeaders(); n != 0 {
+		t.Errorf("Expected no renamed columns, got %d", n)
+	}
+
+	safe := abstract.NewCSVTableSafe([][]string{{"id", "a", "a"}, {"1", "x", "y"}})
+	if n := safe.DedupeHeaders(); n != 1 || safe.Value("1", "a_2") != "y" {
+		t.Errorf("Expected duplicate column to be renamed, got %d", n)
+	}
+}
+
+func TestCSVTableBytesFormatted(t *testing.T) {
+	table := abstract.NewCSVTable([][]string{
+		{"id", "price", "name"},
+		{"1", "3.14159", "apple"},
+	})
+	fixed := func(raw string) string {
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return raw
+		}
+		return strconv.FormatFloat(f, 'f', 2, 64)
+	}
+
+	table.FormatColumn("price", fixed)
+	want := "\"id\",\"price\",\"name\"\n\"1\",\"3.14\",\"apple\"\n"
+	if got := string(table.BytesFormatted(nil)); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	want = "\"id\",\"price\",\"name\"\n\"1\",\"3.14\",\"APPLE\"\n"
+	if got := string(table.BytesFormatted(map[string]func(string) string{"name": strings.ToUpper})); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if table.Value("1", "price") != "3.14159" || !strings.Contains(string(table.Bytes()), "3.14159") {
+		t.Error("Expected stored values and Bytes to stay raw")
+	}
+
+	if err := table.RenameColumns(map[string]string{"price": "cost"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(table.BytesFormatted(nil)), "\"3.14\"") {
+		t.Error("Expected formatter to follow the renamed column")
+	}
+	table.FormatColumn("cost", nil)
+	if !strings.Contains(string(table.BytesFormatted(nil)), "3.14159") {
+		t.Error("Expected formatter to be removed")
+	}
+
+	// Swapped columns must keep both formatters
+	swapped := abstract.NewCSVTable([][]string{{"id", "a", "b"}, {"1", "x", "y"}})
+	swapped.FormatColumn("a", strings.ToUpper)
+	swapped.FormatColumn("b", func(raw string) string { return raw + "!" })
+	if err := swapped.RenameColumns(map[string]string{"a": "b", "b": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	want = "\"id\",\"b\",\"a\"\n\"1\",\"X\",\"y!\"\n"
+	if got := string(swapped.BytesFormatted(nil)); got != want {
+		t.Errorf("Expected %q after swap, got %q", want, got)
+	}
+
+	safe := abstract.NewCSVTableSafe([][]string{{"id", "v"}, {"1", "x"}})
+	safe.FormatColumn("v", func(raw string) string { return "<" + raw + ">" })
+	if !strings.Contains(string(safe.BytesFormatted(nil)), "\"<x>\"") {
+		t.Error("Expected formatted value in safe table output")
+	}
+}
+
+func TestCSVTableLookupValue(t *testing.T) {
+	records := [][]string{
+		{"id", "name", "email"},
+		{"1", "Alice", ""},
+	}
+	table := abstract.NewCSVTable(records)
+
+	if v, ok := table.LookupValue("1", "name"); !ok || v != "Alice" {
+		t.Errorf("Expected Alice, got %q, %v", v, ok)
+	}
+	if v, ok := table.LookupValue("1", "email"); !ok || v != "" {
+		t.Errorf("Expected present empty value, got %q, %v", v, ok)
+	}
+	if _, ok := table.LookupValue("1", "phone"); ok {
+		t.Error("Expected missing column")
+	}
+	if _, ok := table.LookupValue("2", "name"); ok {
+		t.Error("Expected missing row")
+	}
+
+	safe := abstract.NewCSVTableSafe(records)
+	if _, ok := safe.LookupValue("1", "email"); !ok {
+		t.Error("Expected present empty value in safe table")
+	}
+	if _, ok := safe.LookupValue("1", "phone"); ok {
+		t.Error("Expected missing column in safe table")
+	}
+}
+
+func TestCSVTableReplaceRegex(t *testing.T) {
+	records := [][]string{
+		{"id", "phone", "name"},
+		{"1", "+1 (555) 123-4567", "  Alice  "},
+		{"2", "5551234567", "Bob"},
+	}
+	table := abstract.NewCSVTable(records)
+
+	changed, err := table.ReplaceRegex("phone", `[^\d]`, "")
+	if err != nil || changed != 1 {
+		t.Errorf("Expected 1 changed cell, got %d, %v", changed, err)
+	}
+	if table.Value("1", "phone") != "15551234567" {
+		t.Errorf("Unexpected phone: %q", table.Value("1", "phone"))
+	}
+
+	changed, err = table.ReplaceRegexAll(`^\s+|\s+$`, "")
+	if err != nil || changed != 1 || table.Value("1", "name") != "Alice" {
+		t.Errorf("Expected trimmed name, got %q, %d, %v", table.Value("1", "name"), changed, err)
+	}
+	if changed, _ := table.ReplaceRegex("name", `^(\w)`, "[$1]"); changed != 2 || table.Value("2", "name") != "[B]ob" {
+		t.Errorf("Expected capture group replacement, got %q", table.Value("2", "name"))
+	}
+
+	if _, err := table.ReplaceRegex("phone", `(`, ""); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+	if _, err := table.ReplaceRegexAll(`(`, ""); err == nil {
+		t.Error("Expected error for invalid pattern")
+	}
+	if _, err := table.ReplaceRegex("missing", `a`, ""); err == nil {
+		t.Error("Expected error for unknown column")
+	}
+	if _, err := table.ReplaceRegex("id", `1`, "3"); err == nil || !table.Has("1") {
+		t.Error("Expected error for ID column")
+	}
+
+	safe := abstract.NewCSVTableSafe(records)
+	if changed, err := safe.ReplaceRegex("name", `\s`, ""); err != nil || changed != 1 {
+		t.Errorf("Expected 1 changed cell in safe table, got %d, %v", changed, err)
+	}
+	if changed, err := safe.ReplaceRegexAll(`5`, "0"); err != nil || changed != 2 {
+		t.Errorf("Expected 2 changed cells in safe table, got %d, %v", changed, err)
+	}
+}
+
+func TestCSVTableConvertColumns(t *testing.T) {
+	records := [][]string{
+		{"id", "count", "price", "active", "name"},
+		{"1", " 007 ", "1.50", "TRUE", "  Alice "},
+		{"2", "+3", "2e2", "0", "Bob"},
+		{"3", "", "", "", ""},
+	}
+	table := abstract.NewCSVTable(records)
+	err := table.ConvertColumns(map[string]abstract.ColumnType{
+		"count":  abstract.ColumnTypeInt,
+		"price":  abstract.ColumnTypeFloat,
+		"active": abstract.ColumnTypeBool,
+		"name":   abstract.ColumnTypeString,
+	})
+	if err != nil {
+		t.Fatalf("ConvertColumns failed: %v", err)
+	}
+	want := [][]string{
+		{"1", "7", "1.5", "true", "Alice"},
+		{"2", "3", "200", "false", "Bob"},
+		{"3", "", "", "", ""},
+	}
+	if got := table.AllSorted(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	bad := abstract.NewCSVTable([][]string{
+		{"id", "count", "price"},
+		{"1", "x", "1"},
+		{"2", "2", "y"},
+	})
+	err = bad.ConvertColumns(map[string]abstract.ColumnType{
+		"count": abstract.ColumnTypeInt,
+		"price": abstract.ColumnTypeFloat,
+	})
+	if err == nil {
+		t.Fatal("Expected error for unparsable cells")
+	}
+	if msg := err.Error(); !strings.Contains(msg, `row "1", column "count"`) || !strings.Contains(msg, `row "2", column "price"`) {
+		t.Errorf("Expected error with row and column context, got %v", err)
+	}
+	if bad.Value("1", "price") != "1" || bad.Value("2", "count") != "2" {
+		t.Error("Expected table to be unchanged after error")
+	}
+	if err := bad.ConvertColumns(map[string]abstract.ColumnType{"missing": abstract.ColumnTypeInt}); err == nil {
+		t.Error("Expected error for unknown column")
+	}
+	if err := bad.ConvertColumns(map[string]abstract.ColumnType{"id": abstract.ColumnTypeInt}); err == nil {
+		t.Error("Expected error for ID column")
+	}
+
+	safe := abstract.NewCSVTableSafe(records)
+	if err := safe.ConvertColumns(map[string]abstract.ColumnType{"count": abstract.ColumnTypeInt}); err != nil || safe.Value("1", "count") != "7" {
+		t.Errorf("Expected converted value in safe table, got %q, %v", safe.Value("1", "count"), err)
+	}
+}
+
+func TestTransformStream(t *testing.T) {
+	data := "ID,Name,Amount\n1,alice,10\n2,bob,0\n3,carol,15\n"
+
+	var out bytes.Buffer
+	err := abstract.TransformStream(strings.NewReader(data), &out, func(row map[string]string) (map[string]string, bool) {
+		if row["Amount"] == "0" {
+			return nil, false
+		}
+		row["Name"] = strings.ToUpper(row["Name"])
+		row["Extra"] = "ignored"
+		return row, true
+	})
+	if err != nil {
+		t.Fatalf("TransformStream failed: %v", err)
+	}
+	expected := "ID,Name,Amount\n1,ALICE,10\n3,CAROL,15\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+
+	out.Reset()
+	err = abstract.TransformStream(strings.NewReader(data), &out, func(row map[string]string) (map[string]string, bool) {
+		return map[string]string{"ID": row["ID"]}, true
+	})
+	if err != nil {
+		t.Fatalf("TransformStream failed: %v", err)
+	}
+	if expected := "ID,Name,Amount\n1,,\n2,,\n3,,\n"; out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+
+	keep := func(row map[string]string) (map[string]string, bool) { return row, true }
+	if err := abstract.TransformStream(strings.NewReader(""), &out, keep); err == nil {
+		t.Error("Expected error for empty input")
+	}
+	if err := abstract.TransformStream(strings.NewReader("ID,Name\n1,\"a"), &out, keep); err == nil {
+		t.Error("Expected error for malformed input")
+	}
+}
+
+func TestCSVTableDescribe(t *testing.T) {
+	table := abstract.NewCSVTable([][]string{
+		{"ID", "Amount", "City", "Note"},
+		{"1", "10", "Paris", ""},
+		{"2", " 20 ", "Berlin", ""},
+		{"3", "", "Paris", ""},
+		{"4", "30", "Berlin", ""},
+		{"5", "20", "Rome", ""},
+	})
+
+	stats := table.Describe()
+	if len(stats) != 3 {
+		t.Fatalf("Expected stats for 3 columns, got %v", stats)
+	}
+
+	amount := stats["Amount"]
+	if !amount.Numeric || amount.Count != 4 || amount.Empty != 1 || amount.Distinct != 3 {
+		t.Errorf("Unexpected counts for Amount: %+v", amount)
+	}
+	if amount.Min != 10 || amount.Max != 30 || amount.Mean != 20 {
+		t.Errorf("Unexpected min/max/mean for Amount: %+v", amount)
+	}
+	if math.Abs(amount.StdDev-math.Sqrt(200.0/3)) > 1e-9 {
+		t.Errorf("Unexpected stddev for Amount: %v", amount.StdDev)
+	}
+	if amount.Top != "" || amount.TopCount != 0 {
+		t.Errorf("Expected no top value for numeric column: %+v", amount)
+	}
+
+	city := stats["City"]
+	if city.Numeric || city.Count != 5 || city.Distinct != 3 || city.Empty != 0 {
+		t.Errorf("Unexpected counts for City: %+v", city)
+	}
+	if city.Top != "Berlin" || city.TopCount != 2 {
+		t.Errorf("Expected Berlin to be the top value on tie, got %+v", city)
+	}
+	if city.Min != 0 || city.Max != 0 || city.Mean != 0 {
+		t.Errorf("Expected no numeric stats for City: %+v", city)
+	}
+
+	note := stats["Note"]
+	if note.Numeric || note.Count != 0 || note.Empty != 5 || note.Distinct != 0 {
+		t.Errorf("Unexpected stats for empty column: %+v", note)
+	}
+
+	safe := abstract.NewCSVTableSafe([][]string{{"ID", "X"}, {"a", "NaN"}})
+	if x := safe.Describe()["X"]; x.Numeric || x.Top != "NaN" {
+		t.Errorf("Expected NaN to be non-numeric, got %+v", x)
+	}
+}
+
+func TestCSVTableMoveRow(t *testing.T) {
+	records := [][]string{
+		{"ID", "Name"},
+		{"row1", "Alpha"},
+		{"row2", "Bravo"},
+		{"row3", "Charlie"},
+		{"row4", "Delta"},
+	}
+
+	table := abstract.NewCSVTable(records)
+
+	if !table.MoveRow("row4", 1) {
+		t.Fatal("Expected MoveRow to succeed for existing row")
+	}
+	if ids := table.AllIDs(); !reflect.DeepEqual(ids, []string{"row1", "row4", "row2", "row3"}) {
+		t.Errorf("Unexpected order after move: %v", ids)
+	}
+
+	table.MoveRow("row1", 100)
+	table.MoveRow("row3", -5)
+	if ids := table.AllIDs(); !reflect.DeepEqual(ids, []string{"row3", "row4", "row2", "row1"}) {
+		t.Errorf("Unexpected order after clamped moves: %v", ids)
+	}
+	if table.MoveRow("missing", 0) {
+		t.Error("Expected MoveRow to fail for unknown row")
+	}
+
+	// Lookups by ID must follow the new positions
+	if v := table.Value("row2", "Name"); v != "Bravo" {
+		t.Errorf("Expected Bravo for row2, got %s", v)
+	}
+	if rows := table.AllSorted(); rows[0][1] != "Charlie" || rows[3][1] != "Alpha" {
+		t.Errorf("Unexpected AllSorted order: %v", rows)
+	}
+
+	table.SortRowsByIDs([]string{"row2", "missing", "row1", "row2"})
+	if ids := table.AllIDs(); !reflect.DeepEqual(ids, []string{"row2", "row1", "row3", "row4"}) {
+		t.Errorf("Unexpected order after SortRowsByIDs: %v", ids)
+	}
+	out := string(table.Bytes())
+	if !(strings.Index(out, "row2") < strings.Index(out, "row1") &&
+		strings.Index(out, "row1") < strings.Index(out, "row3") &&
+		strings.Index(out, "row3") < strings.Index(out, "row4")) {
+		t.Errorf("Expected Bytes to follow row order, got %q", out)
+	}
+	if v := table.Value("row4", "Name"); v != "Delta" {
+		t.Errorf("Expected Delta for row4, got %s", v)
+	}
+
+	safe := abstract.NewCSVTableSafe(records)
+	safe.MoveRow("row1", 3)
+	safe.SortRowsByIDs([]string{"row3"})
+	if ids := safe.AllIDs(); !reflect.DeepEqual(ids, []string{"row3", "row2", "row4", "row1"}) {
+		t.Errorf("Unexpected safe table order: %v", ids)
+	}
+}
+
+func TestCSVTableSortRowsByIDsDuplicates(t *testing.T) {
+	table := abstract.NewCSVTable([][]string{
+		{"ID", "Value"},
+		{"a", "1"},
+		{"b", "2"},
+		{"a", "3"},
+	})
+
+	table.SortRowsByIDs([]string{"b"})
+	if ids := table.AllIDs(); !reflect.DeepEqual(ids, []string{"b", "a", "a"}) {
+		t.Errorf("Unexpected order after SortRowsByIDs: %v", ids)
+	}
+	if rows := table.AllSorted(); !reflect.DeepEqual(rows, [][]string{{"b", "2"}, {"a", "1"}, {"a", "3"}}) {
+		t.Errorf("Expected all rows to be kept, got %v", rows)
+	}
+
+	table.SortRowsByIDs([]string{"a"})
+	if ids := table.AllIDs(); !reflect.DeepEqual(ids, []string{"a", "a", "b"}) {
+		t.Errorf("Unexpected order after SortRowsByIDs: %v", ids)
+	}
+	if out := string(table.Bytes()); out != "\"ID\",\"Value\"\n\"a\",\"1\"\n\"a\",\"3\"\n\"b\",\"2\"\n" {
+		t.Errorf("Expected all rows to be kept, got %q", out)
+	}
+	if v := table.Value("b", "Value"); v != "2" {
+		t.Errorf("Expected 2 for b, got %s", v)
+	}
+}
+
+func TestCSVTableSortRowsByIDsKeepsIndex(t *testing.T) {
+	table := abstract.NewCSVTable([][]string{
+		{"id", "a"},
+		{"x", "1"},
+		{"y", "2"},
+	})
+	// The first cell differs from the row ID, the index must still follow the ID list
+	table.UpdateRow("x", map[string]string{"id": "z"})
+
+	table.SortRowsByIDs([]string{"y"})
+	if ids := table.AllIDs(); !reflect.DeepEqual(ids, []string{"y", "x"}) {
+		t.Errorf("Unexpected order after SortRowsByIDs: %v", ids)
+	}
+	if v := table.Value("x", "a"); v != "1" {
+		t.Errorf("Expected 1 for x, got %s", v)
+	}
+	if v := table.Value("y", "a"); v != "2" {
+		t.Errorf("Expected 2 for y, got %s", v)
+	}
+	if table.Has("z") {
+		t.Error("Expected no row with ID z")
+	}
+}
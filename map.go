@@ -2,12 +2,19 @@ package abstract
 
 import (
 	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"hash/maphash"
 	"iter"
 	"maps"
+	"math"
 	"math/big"
+	"reflect"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/maxbolgarin/lang"
 )
@@ -77,6 +84,20 @@ func (m *Map[K, V]) Lookup(key K) (V, bool) {
 	return v, ok
 }
 
+// GetOr returns the value for the provided key or def if the key is not present in the map.
+func (m *Map[K, V]) GetOr(key K, def V) V {
+	v, _ := m.LookupOr(key, def)
+	return v
+}
+
+// LookupOr returns the value for the provided key and true if the key is present in the map, def and false otherwise.
+func (m *Map[K, V]) LookupOr(key K, def V) (V, bool) {
+	if v, ok := m.Lookup(key); ok {
+		return v, true
+	}
+	return def, false
+}
+
 // Has returns true if the key is present in the map, false otherwise.
 func (m *Map[K, V]) Has(key K) bool {
 	if m.items == nil {
@@ -119,6 +140,21 @@ func (m *Map[K, V]) SetIfNotPresent(key K, value V) V {
 	return m.items[key]
 }
 
+// GetOrCompute returns the value for the provided key if it is present,
+// otherwise it calls compute, sets its result to the map and returns it.
+// Unlike [Map.SetIfNotPresent], the value is created only if the key is missing.
+func (m *Map[K, V]) GetOrCompute(key K, compute func() V) V {
+	if m.items == nil {
+		m.items = make(map[K]V)
+	}
+	if v, ok := m.items[key]; ok {
+		return v
+	}
+	v := compute()
+	m.items[key] = v
+	return v
+}
+
 // Swap swaps the values for the provided keys and returns the old value.
 func (m *Map[K, V]) Swap(key K, value V) V {
 	if m.items == nil {
@@ -168,6 +204,20 @@ func (m *Map[K, V]) Keys() []K {
 	return lang.Keys(m.items)
 }
 
+// SortedKeys returns a slice of keys of the map sorted using less.
+// The map has no order of its own, so keys that are equal according to less are returned in arbitrary order,
+// less should order all keys to get the same result on every call.
+// Use [SortedKeysOrdered] for keys with natural order.
+func (m *Map[K, V]) SortedKeys(less func(a, b K) bool) []K {
+	return sortedKeys(m.items, less)
+}
+
+func sortedKeys[K comparable, V any](items map[K]V, less func(a, b K) bool) []K {
+	keys := lang.Keys(items)
+	sort.Slice(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+	return keys
+}
+
 // Values returns a slice of values of the map.
 func (m *Map[K, V]) Values() []V {
 	if m.items == nil {
@@ -176,6 +226,206 @@ func (m *Map[K, V]) Values() []V {
 	return lang.Values(m.items)
 }
 
+// KeysWhere returns a slice of keys of the map for which pred returns true, in arbitrary order.
+func (m *Map[K, V]) KeysWhere(pred func(K, V) bool) []K {
+	return keysWhere(m.items, pred)
+}
+
+// ValuesWhere returns a slice of values of the map for which pred returns true, in arbitrary order.
+func (m *Map[K, V]) ValuesWhere(pred func(K, V) bool) []V {
+	return valuesWhere(m.items, pred)
+}
+
+// Filter returns a new map with the entries for which keep returns true.
+// The map is not modified, the returned map is never nil.
+func (m *Map[K, V]) Filter(keep func(K, V) bool) map[K]V {
+	if m.items == nil {
+		m.items = make(map[K]V)
+	}
+	return filterMap(m.items, keep)
+}
+
+// FilterInto returns a new [Map] with the entries for which keep returns true.
+// The returned map does not share storage with the map.
+func (m *Map[K, V]) FilterInto(keep func(K, V) bool) *Map[K, V] {
+	return &Map[K, V]{items: filterMap(m.items, keep)}
+}
+
+// FilterInPlace deletes all entries of the map for which keep returns false.
+// Unlike [Map.Filter], it does not allocate a new map.
+func (m *Map[K, V]) FilterInPlace(keep func(K, V) bool) {
+	maps.DeleteFunc(m.items, func(k K, v V) bool { return !keep(k, v) })
+}
+
+// Compact deletes all entries with zero values (e.g. 0, "", nil pointers and zero structs)
+// and returns the number of deleted entries.
+func (m *Map[K, V]) Compact() int {
+	return compact(m.items)
+}
+
+// Count returns the number of entries of the map for which pred returns true.
+func (m *Map[K, V]) Count(pred func(K, V) bool) int {
+	return countWhere(m.items, pred)
+}
+
+// Any returns true if f returns true for at least one entry of the map, it stops at the first match.
+// It returns false for an empty map.
+func (m *Map[K, V]) Any(f func(K, V) bool) bool {
+	return anyMatch(m.items, f)
+}
+
+// All returns true if f returns true for every entry of the map, it stops at the first mismatch.
+// It returns true for an empty map.
+func (m *Map[K, V]) All(f func(K, V) bool) bool {
+	return !anyMatch(m.items, func(k K, v V) bool { return !f(k, v) })
+}
+
+// IntersectKeys returns a new map with entries of the map whose keys exist in other.
+// Values are taken from the map.
+func (m *Map[K, V]) IntersectKeys(other map[K]V) map[K]V {
+	return intersectKeys(m.items, other)
+}
+
+// UnionKeys returns a new map with entries of both the map and other.
+// For keys that exist in both maps, the value is resolve(key, mapValue, otherValue).
+// If resolve is nil, the value of the map is kept.
+func (m *Map[K, V]) UnionKeys(other map[K]V, resolve func(k K, a, b V) V) map[K]V {
+	return unionKeys(m.items, other, resolve)
+}
+
+// Diff compares the map with other and returns new maps with the entries whose keys exist only in the map,
+// with the entries whose keys exist only in other and with the pairs of values of the map and other for common keys.
+func (m *Map[K, V]) Diff(other map[K]V) (onlyInSelf, onlyInOther *Map[K, V], both map[K][2]V) {
+	self, rest, both := MapDiff(m.items, other)
+	return &Map[K, V]{items: self}, &Map[K, V]{items: rest}, both
+}
+
+// Equal returns true if the map contains the same keys as other and their values are deeply equal
+// according to [reflect.DeepEqual]. Use [Map.EqualFunc] with a custom comparator or [EqualToComparable]
+// to avoid reflection for known value types.
+func (m *Map[K, V]) Equal(other map[K]V) bool {
+	return maps.EqualFunc(m.items, other, deepEqual[V])
+}
+
+// EqualFunc returns true if the map contains the same keys as other and their values are equal according to eq.
+func (m *Map[K, V]) EqualFunc(other map[K]V, eq func(V, V) bool) bool {
+	return maps.EqualFunc(m.items, other, eq)
+}
+
+func deepEqual[V any](a, b V) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// EqualTo returns true if the map contains the same keys as other and their values are equal according to eq.
+// It is the same as [Map.EqualFunc].
+func (m *Map[K, V]) EqualTo(other map[K]V, eq func(V, V) bool) bool {
+	return m.EqualFunc(other, eq)
+}
+
+func intersectKeys[K comparable, V any](items, other map[K]V) map[K]V {
+	out := make(map[K]V, min(len(items), len(other)))
+	for k, v := range items {
+		if _, ok := other[k]; ok {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func unionKeys[K comparable, V any](items, other map[K]V, resolve func(k K, a, b V) V) map[K]V {
+	out := make(map[K]V, max(len(items), len(other)))
+	for k, v := range other {
+		out[k] = v
+	}
+	for k, v := range items {
+		if otherV, ok := other[k]; ok && resolve != nil {
+			v = resolve(k, v, otherV)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func keysWhere[K comparable, V any](items map[K]V, pred func(K, V) bool) []K {
+	var out []K
+	for k, v := range items {
+		if pred(k, v) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+func filterMap[K comparable, V any](items map[K]V, keep func(K, V) bool) map[K]V {
+	out := make(map[K]V)
+	for k, v := range items {
+		if keep(k, v) {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func anyMatch[K comparable, V any](items map[K]V, f func(K, V) bool) bool {
+	for k, v := range items {
+		if f(k, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func compact[K comparable, V any](items map[K]V) int {
+	before := len(items)
+	maps.DeleteFunc(items, func(_ K, v V) bool {
+		return reflect.ValueOf(&v).Elem().IsZero()
+	})
+	return before - len(items)
+}
+
+func countWhere[K comparable, V any](items map[K]V, pred func(K, V) bool) int {
+	var n int
+	for k, v := range items {
+		if pred(k, v) {
+			n++
+		}
+	}
+	return n
+}
+
+func valuesWhere[K comparable, V any](items map[K]V, pred func(K, V) bool) []V {
+	var out []V
+	for k, v := range items {
+		if pred(k, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Entries returns a slice of key-value pairs of the map in arbitrary order.
+// Use [SortedEntries] to get them sorted by key.
+func (m *Map[K, V]) Entries() []Entry[K, V] {
+	return entries(m.items)
+}
+
+// KeySet returns a new [Set] with the keys of the map.
+func (m *Map[K, V]) KeySet() *Set[K] {
+	return keySet(m.items)
+}
+
+// Sample returns a new map with up to n randomly chosen pairs of the map without repetitions.
+// It uses crypto/rand, so it is suitable for unbiased bucketing.
+func (m *Map[K, V]) Sample(n int) map[K]V {
+	return samplePairs(m.items, n)
+}
+
+// SampleKeys returns up to n randomly chosen keys of the map without repetitions in random order.
+// It uses crypto/rand, so it is suitable for unbiased bucketing.
+func (m *Map[K, V]) SampleKeys(n int) []K {
+	return sampleKeys(m.items, n)
+}
+
 // Change changes the value for the provided key using provided function.
 func (m *Map[K, V]) Change(key K, f func(K, V) V) {
 	if m.items == nil {
@@ -194,6 +444,25 @@ func (m *Map[K, V]) Transform(f func(K, V) V) {
 	}
 }
 
+// TransformFilter transforms all values of the map using provided function
+// and deletes the keys for which the function returns false.
+func (m *Map[K, V]) TransformFilter(f func(K, V) (V, bool)) {
+	if m.items == nil {
+		m.items = make(map[K]V)
+	}
+	transformFilter(m.items, f)
+}
+
+func transformFilter[K comparable, V any](items map[K]V, f func(K, V) (V, bool)) {
+	for k, v := range items {
+		if newV, ok := f(k, v); ok {
+			items[k] = newV
+		} else {
+			delete(items, k)
+		}
+	}
+}
+
 // Range calls the provided function for each key-value pair in the map.
 func (m *Map[K, V]) Range(f func(K, V) bool) bool {
 	if m.items == nil {
@@ -207,6 +476,18 @@ func (m *Map[K, V]) Range(f func(K, V) bool) bool {
 	return true
 }
 
+// Partition splits the map in a single pass into a new map with the pairs for which pred returns true
+// and a new map with the rest of the pairs.
+func (m *Map[K, V]) Partition(pred func(K, V) bool) (matched, rest map[K]V) {
+	return partitionMap(m.items, pred)
+}
+
+// PartitionInto splits the map like [Map.Partition] and returns the parts as new [Map] instances.
+func (m *Map[K, V]) PartitionInto(pred func(K, V) bool) (matched, rest *Map[K, V]) {
+	matchedItems, restItems := partitionMap(m.items, pred)
+	return &Map[K, V]{items: matchedItems}, &Map[K, V]{items: restItems}
+}
+
 // Copy returns another map that is a copy of the underlying map.
 func (m *Map[K, V]) Copy() map[K]V {
 	if m.items == nil {
@@ -215,6 +496,52 @@ func (m *Map[K, V]) Copy() map[K]V {
 	return lang.CopyMap(m.items)
 }
 
+// CopyInto clears dst and copies all pairs of the map into it, returns dst.
+// It allows reusing an allocated map instead of creating a new one with [Map.Copy].
+// If dst is nil, a new map is created.
+func (m *Map[K, V]) CopyInto(dst map[K]V) map[K]V {
+	return copyInto(dst, m.items)
+}
+
+// Merge copies all key-value pairs from each of the provided maps into the map.
+// If a key exists in several maps, the value from the later one is kept.
+func (m *Map[K, V]) Merge(others ...map[K]V) {
+	if m.items == nil {
+		m.items = make(map[K]V)
+	}
+	for _, other := range others {
+		maps.Copy(m.items, other)
+	}
+}
+
+// MergeFrom copies all key-value pairs from the other [Map] into the map, overwriting existing values.
+func (m *Map[K, V]) MergeFrom(other *Map[K, V]) {
+	if other == nil {
+		return
+	}
+	m.Merge(other.items)
+}
+
+// MergeWith copies all key-value pairs from other into the map.
+// For keys that already exist in the map, the stored value is onConflict(key, existing, incoming).
+// If onConflict is nil, incoming values overwrite existing ones like in [Map.Merge].
+func (m *Map[K, V]) MergeWith(other map[K]V, onConflict func(key K, existing, incoming V) V) {
+	if m.items == nil {
+		m.items = make(map[K]V, len(other))
+	}
+	mergeWith(m.items, other, onConflict)
+}
+
+func mergeWith[K comparable, V any](items, other map[K]V, onConflict func(key K, existing, incoming V) V) {
+	for k, incoming := range other {
+		if existing, ok := items[k]; ok && onConflict != nil {
+			items[k] = onConflict(k, existing, incoming)
+			continue
+		}
+		items[k] = incoming
+	}
+}
+
 // Raw returns the underlying map.
 func (m *Map[K, V]) Raw() map[K]V {
 	if m.items == nil {
@@ -228,6 +555,22 @@ func (m *Map[K, V]) Clear() {
 	m.items = make(map[K]V)
 }
 
+// ReplaceAll replaces the underlying map with a copy of the provided one and returns the previous map.
+func (m *Map[K, V]) ReplaceAll(newItems map[K]V) map[K]V {
+	old := m.items
+	m.items = lang.CopyMap(newItems)
+	return old
+}
+
+// Adopt replaces the underlying map with the provided one without copying it, the map takes ownership of it.
+// The provided map must not be used by the caller after this call.
+func (m *Map[K, V]) Adopt(items map[K]V) {
+	if items == nil {
+		items = make(map[K]V)
+	}
+	m.items = items
+}
+
 // IterKeys returns an iterator over the map keys.
 func (m *Map[K, V]) IterKeys() iter.Seq[K] {
 	if m.items == nil {
@@ -254,8 +597,16 @@ func (m *Map[K, V]) Iter() iter.Seq2[K, V] {
 
 // SafeMap is used like a common map, but it is protected with RW mutex, so it can be used in many goroutines.
 type SafeMap[K comparable, V any] struct {
-	items map[K]V
-	mu    sync.RWMutex
+	items   map[K]V
+	mu      sync.RWMutex
+	version atomic.Uint64
+	stats   atomic.Pointer[safeMapStats]
+}
+
+// safeMapStats holds hit and miss counters of [SafeMap] lookups.
+type safeMapStats struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
 }
 
 // NewSafeMap returns a new [SafeMap] with empty map.
@@ -303,7 +654,9 @@ func (m *SafeMap[K, V]) Get(key K) V {
 		m.mu.RLock()
 	}
 
-	return m.items[key]
+	value, ok := m.items[key]
+	m.recordLookup(ok)
+	return value
 }
 
 // Lookup returns the value for the provided key and true if key is present in the map, default value and false otherwise.
@@ -321,9 +674,69 @@ func (m *SafeMap[K, V]) Lookup(key K) (V, bool) {
 	}
 
 	v, ok := m.items[key]
+	m.recordLookup(ok)
 	return v, ok
 }
 
+// GetOr returns the value for the provided key or def if the key is not present in the map.
+// It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) GetOr(key K, def V) V {
+	v, _ := m.LookupOr(key, def)
+	return v
+}
+
+// LookupOr returns the value for the provided key and true if the key is present in the map, def and false otherwise.
+// It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) LookupOr(key K, def V) (V, bool) {
+	if v, ok := m.Lookup(key); ok {
+		return v, true
+	}
+	return def, false
+}
+
+// TrackStats enables or disables counting of hits and misses of [SafeMap.Get] and [SafeMap.Lookup].
+// It is disabled by default to avoid the overhead. Disabling it discards the counters.
+// It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) TrackStats(enabled bool) {
+	if !enabled {
+		m.stats.Store(nil)
+		return
+	}
+	m.stats.CompareAndSwap(nil, &safeMapStats{})
+}
+
+// CacheStats returns the number of hits and misses of [SafeMap.Get] and [SafeMap.Lookup]
+// since the stats tracking was enabled or reset. Returns zeros if tracking is disabled.
+// It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) CacheStats() (hits, misses uint64) {
+	stats := m.stats.Load()
+	if stats == nil {
+		return 0, 0
+	}
+	return stats.hits.Load(), stats.misses.Load()
+}
+
+// ResetStats sets the hit and miss counters to zero, tracking stays enabled.
+// It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) ResetStats() {
+	if stats := m.stats.Load(); stats != nil {
+		stats.hits.Store(0)
+		stats.misses.Store(0)
+	}
+}
+
+func (m *SafeMap[K, V]) recordLookup(hit bool) {
+	stats := m.stats.Load()
+	if stats == nil {
+		return
+	}
+	if hit {
+		stats.hits.Add(1)
+	} else {
+		stats.misses.Add(1)
+	}
+}
+
 // Has returns true if key is present in the map, false otherwise. It is safe for concurrent/parallel use.
 func (m *SafeMap[K, V]) Has(key K) bool {
 	m.mu.RLock()
@@ -344,20 +757,17 @@ func (m *SafeMap[K, V]) Has(key K) bool {
 // Pop returns the value for the provided key and deletes it from map or default type value if key is not present.
 // It is safe for concurrent/parallel use.
 func (m *SafeMap[K, V]) Pop(key K) V {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
 	if m.items == nil {
-		m.mu.RUnlock()
-		m.mu.Lock()
 		m.items = make(map[K]V)
-		m.mu.Unlock()
-		m.mu.RLock()
 	}
 
 	val, ok := m.items[key]
 	if ok {
 		delete(m.items, key)
+		m.version.Add(1)
 	}
 	return val
 }
@@ -372,6 +782,7 @@ func (m *SafeMap[K, V]) Set(key K, value V) {
 	}
 
 	m.items[key] = value
+	m.version.Add(1)
 }
 
 // SetIfNotPresent sets the value to the map if the key is not present,
@@ -386,11 +797,41 @@ func (m *SafeMap[K, V]) SetIfNotPresent(key K, value V) V {
 
 	if _, ok := m.items[key]; !ok {
 		m.items[key] = value
+		m.version.Add(1)
 		return value
 	}
 	return m.items[key]
 }
 
+// GetOrCompute returns the value for the provided key if it is present,
+// otherwise it calls compute, sets its result to the map and returns it.
+// Unlike [SafeMap.SetIfNotPresent], the value is created only if the key is missing.
+// compute is called holding the write lock, so concurrent callers with a missing key compute the value only once.
+// It is safe for concurrent/parallel use.
+// DON'T USE SAFE MAP METHOD INSIDE compute TO PREVENT FROM DEADLOCK!
+func (m *SafeMap[K, V]) GetOrCompute(key K, compute func() V) V {
+	m.mu.RLock()
+	v, ok := m.items[key]
+	m.mu.RUnlock()
+	if ok {
+		return v
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.items == nil {
+		m.items = make(map[K]V)
+	}
+	if v, ok := m.items[key]; ok {
+		return v
+	}
+	v = compute()
+	m.items[key] = v
+	m.version.Add(1)
+	return v
+}
+
 // Swap swaps the values for the provided keys and returns the old value. It is safe for concurrent/parallel use.
 func (m *SafeMap[K, V]) Swap(key K, value V) V {
 	m.mu.Lock()
@@ -402,9 +843,58 @@ func (m *SafeMap[K, V]) Swap(key K, value V) V {
 
 	old := m.items[key]
 	m.items[key] = value
+	m.version.Add(1)
 	return old
 }
 
+// ReplaceIf atomically reads the current value for the key and calls cond with it and a flag
+// whether the key is present. If cond returns true, the returned value is set for the key.
+// It returns the resulting value for the key and true if it was replaced.
+// It is safe for concurrent/parallel use.
+// DON'T USE SAFE MAP METHOD INSIDE cond TO PREVENT FROM DEADLOCK!
+func (m *SafeMap[K, V]) ReplaceIf(key K, cond func(old V, exists bool) (newV V, replace bool)) (V, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.items == nil {
+		m.items = make(map[K]V)
+	}
+
+	old, exists := m.items[key]
+	newValue, replace := cond(old, exists)
+	if !replace {
+		return old, false
+	}
+	m.items[key] = newValue
+	m.version.Add(1)
+	return newValue, true
+}
+
+// CompareAndSwapMany atomically checks that every key of expected is present in the map
+// and its value is equal to the expected one according to eq. If all keys match, it sets all values
+// from desired and returns true, otherwise it changes nothing and returns false.
+// Use [SafeMapCompareAndSwapMany] for comparable values.
+// It is safe for concurrent/parallel use.
+// DON'T USE SAFE MAP METHOD INSIDE eq TO PREVENT FROM DEADLOCK!
+func (m *SafeMap[K, V]) CompareAndSwapMany(expected, desired map[K]V, eq func(V, V) bool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.items == nil {
+		m.items = make(map[K]V)
+	}
+
+	for k, expectedV := range expected {
+		v, ok := m.items[k]
+		if !ok || !eq(v, expectedV) {
+			return false
+		}
+	}
+	maps.Copy(m.items, desired)
+	m.version.Add(1)
+	return true
+}
+
 // Delete removes keys and associated values from map, does nothing if key is not present in map,
 // returns true if key was deleted. It is safe for concurrent/parallel use.
 func (m *SafeMap[K, V]) Delete(keys ...K) (deleted bool) {
@@ -421,6 +911,9 @@ func (m *SafeMap[K, V]) Delete(keys ...K) (deleted bool) {
 			delete(m.items, key)
 		}
 	}
+	if deleted {
+		m.version.Add(1)
+	}
 
 	return deleted
 }
@@ -473,6 +966,19 @@ func (m *SafeMap[K, V]) Keys() []K {
 	return lang.Keys(m.items)
 }
 
+// SortedKeys returns a slice of keys of the map sorted using less.
+// The map has no order of its own, so keys that are equal according to less are returned in arbitrary order,
+// less should order all keys to get the same result on every call.
+// Use [SafeMapSortedKeysOrdered] for keys with natural order. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) SortedKeys(less func(a, b K) bool) []K {
+	m.mu.RLock()
+	keys := lang.Keys(m.items)
+	m.mu.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool { return less(keys[i], keys[j]) })
+	return keys
+}
+
 // Values returns a slice of values of the map. It is safe for concurrent/parallel use.
 func (m *SafeMap[K, V]) Values() []V {
 	m.mu.RLock()
@@ -489,39 +995,237 @@ func (m *SafeMap[K, V]) Values() []V {
 	return lang.Values(m.items)
 }
 
-// Change changes the value for the provided key using provided function. It is safe for concurrent/parallel use.
-func (m *SafeMap[K, V]) Change(key K, f func(K, V) V) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
-	if m.items == nil {
-		m.items = make(map[K]V)
-	}
+// KeysWhere returns a slice of keys of the map for which pred returns true, in arbitrary order.
+// It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) KeysWhere(pred func(K, V) bool) []K {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	m.items[key] = f(key, m.items[key])
+	return keysWhere(m.items, pred)
 }
 
-// Update updates the map using provided function. It is safe for concurrent/parallel use.
-func (m *SafeMap[K, V]) Transform(upd func(K, V) V) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// ValuesWhere returns a slice of values of the map for which pred returns true, in arbitrary order.
+// It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) ValuesWhere(pred func(K, V) bool) []V {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	if m.items == nil {
-		m.items = make(map[K]V)
-	}
+	return valuesWhere(m.items, pred)
+}
 
-	for k, v := range m.items {
-		m.items[k] = upd(k, v)
-	}
+// Filter returns a new map with the entries for which keep returns true.
+// The map is not modified, the returned map is never nil. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) Filter(keep func(K, V) bool) map[K]V {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return filterMap(m.items, keep)
 }
 
-// Range calls the provided function for each key-value pair in the map. It is safe for concurrent/parallel use.
-func (m *SafeMap[K, V]) Range(f func(K, V) bool) bool {
+// FilterInto returns a new [Map] with the entries for which keep returns true.
+// The returned map does not share storage with the map.
+// It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) FilterInto(keep func(K, V) bool) *Map[K, V] {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	if m.items == nil {
-		m.mu.RUnlock()
+	return &Map[K, V]{items: filterMap(m.items, keep)}
+}
+
+// FilterInPlace deletes all entries of the map for which keep returns false holding the write lock
+// for the whole operation. Unlike [SafeMap.Filter], it does not allocate a new map.
+// It is safe for concurrent/parallel use.
+// DON'T USE SAFE MAP METHOD INSIDE keep TO PREVENT FROM DEADLOCK!
+func (m *SafeMap[K, V]) FilterInPlace(keep func(K, V) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.items)
+	maps.DeleteFunc(m.items, func(k K, v V) bool { return !keep(k, v) })
+	if len(m.items) != before {
+		m.version.Add(1)
+	}
+}
+
+// Compact deletes all entries with zero values (e.g. 0, "", nil pointers and zero structs)
+// holding the write lock and returns the number of deleted entries. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) Compact() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := compact(m.items)
+	if n > 0 {
+		m.version.Add(1)
+	}
+	return n
+}
+
+// Count returns the number of entries of the map for which pred returns true.
+// It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) Count(pred func(K, V) bool) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return countWhere(m.items, pred)
+}
+
+// Any returns true if f returns true for at least one entry of the map, it stops at the first match.
+// It returns false for an empty map. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) Any(f func(K, V) bool) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return anyMatch(m.items, f)
+}
+
+// All returns true if f returns true for every entry of the map, it stops at the first mismatch.
+// It returns true for an empty map. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) All(f func(K, V) bool) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return !anyMatch(m.items, func(k K, v V) bool { return !f(k, v) })
+}
+
+// IntersectKeys returns a new map with entries of the map whose keys exist in other.
+// Values are taken from the map. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) IntersectKeys(other map[K]V) map[K]V {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return intersectKeys(m.items, other)
+}
+
+// UnionKeys returns a new map with entries of both the map and other.
+// For keys that exist in both maps, the value is resolve(key, mapValue, otherValue).
+// If resolve is nil, the value of the map is kept. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) UnionKeys(other map[K]V, resolve func(k K, a, b V) V) map[K]V {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return unionKeys(m.items, other, resolve)
+}
+
+// Diff compares the map with other and returns new maps with the entries whose keys exist only in the map,
+// with the entries whose keys exist only in other and with the pairs of values of the map and other for common keys.
+// It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) Diff(other map[K]V) (onlyInSelf, onlyInOther *Map[K, V], both map[K][2]V) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	self, rest, both := MapDiff(m.items, other)
+	return &Map[K, V]{items: self}, &Map[K, V]{items: rest}, both
+}
+
+// Equal returns true if the map contains the same keys as other and their values are deeply equal
+// according to [reflect.DeepEqual]. Use [SafeMap.EqualFunc] with a custom comparator or [SafeMapEqualToComparable]
+// to avoid reflection for known value types. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) Equal(other map[K]V) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return maps.EqualFunc(m.items, other, deepEqual[V])
+}
+
+// EqualFunc returns true if the map contains the same keys as other and their values are equal according to eq.
+// It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) EqualFunc(other map[K]V, eq func(V, V) bool) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return maps.EqualFunc(m.items, other, eq)
+}
+
+// EqualTo returns true if the map contains the same keys as other and their values are equal according to eq.
+// It is the same as [SafeMap.EqualFunc]. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) EqualTo(other map[K]V, eq func(V, V) bool) bool {
+	return m.EqualFunc(other, eq)
+}
+
+// Entries returns a slice of key-value pairs of the map in arbitrary order.
+// Use [SafeMapSortedEntries] to get them sorted by key. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) Entries() []Entry[K, V] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return entries(m.items)
+}
+
+// KeySet returns a new [Set] with the keys of the map. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) KeySet() *Set[K] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return keySet(m.items)
+}
+
+// Sample returns a new map with up to n randomly chosen pairs of the map without repetitions.
+// It uses crypto/rand, so it is suitable for unbiased bucketing. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) Sample(n int) map[K]V {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return samplePairs(m.items, n)
+}
+
+// SampleKeys returns up to n randomly chosen keys of the map without repetitions in random order.
+// It uses crypto/rand, so it is suitable for unbiased bucketing. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) SampleKeys(n int) []K {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return sampleKeys(m.items, n)
+}
+
+// Change changes the value for the provided key using provided function. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) Change(key K, f func(K, V) V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.items == nil {
+		m.items = make(map[K]V)
+	}
+
+	m.items[key] = f(key, m.items[key])
+	m.version.Add(1)
+}
+
+// Update updates the map using provided function. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) Transform(upd func(K, V) V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.items == nil {
+		m.items = make(map[K]V)
+	}
+
+	for k, v := range m.items {
+		m.items[k] = upd(k, v)
+	}
+	m.version.Add(1)
+}
+
+// TransformFilter transforms all values of the map using provided function
+// and deletes the keys for which the function returns false. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) TransformFilter(f func(K, V) (V, bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.items == nil {
+		m.items = make(map[K]V)
+	}
+
+	transformFilter(m.items, f)
+	m.version.Add(1)
+}
+
+// Range calls the provided function for each key-value pair in the map. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) Range(f func(K, V) bool) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.items == nil {
+		m.mu.RUnlock()
 		m.mu.Lock()
 		m.items = make(map[K]V)
 		m.mu.Unlock()
@@ -536,6 +1240,48 @@ func (m *SafeMap[K, V]) Range(f func(K, V) bool) bool {
 	return true
 }
 
+// RangeMutable calls the provided function for each key-value pair in the map holding the write lock.
+// The value is replaced with the first returned value, the key is deleted if the second returned value is false.
+// DON'T USE SAFE MAP METHODS INSIDE f TO PREVENT FROM DEADLOCK!
+// It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) RangeMutable(f func(K, V) (V, bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.items) == 0 {
+		return
+	}
+
+	for k, v := range m.items {
+		newValue, keep := f(k, v)
+		if keep {
+			m.items[k] = newValue
+		} else {
+			delete(m.items, k)
+		}
+	}
+	m.version.Add(1)
+}
+
+// Partition splits the map in a single pass into a new map with the pairs for which pred returns true
+// and a new map with the rest of the pairs.
+// It is safe for concurrent/parallel use.
+// DON'T USE SAFE MAP METHOD INSIDE pred TO PREVENT FROM DEADLOCK!
+func (m *SafeMap[K, V]) Partition(pred func(K, V) bool) (matched, rest map[K]V) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return partitionMap(m.items, pred)
+}
+
+// PartitionInto splits the map like [SafeMap.Partition] and returns the parts as new [Map] instances.
+// It is safe for concurrent/parallel use.
+// DON'T USE SAFE MAP METHOD INSIDE pred TO PREVENT FROM DEADLOCK!
+func (m *SafeMap[K, V]) PartitionInto(pred func(K, V) bool) (matched, rest *Map[K, V]) {
+	matchedItems, restItems := m.Partition(pred)
+	return &Map[K, V]{items: matchedItems}, &Map[K, V]{items: restItems}
+}
+
 // Copy returns a new map that is a copy of the underlying map. It is safe for concurrent/parallel use.
 func (m *SafeMap[K, V]) Copy() map[K]V {
 	m.mu.RLock()
@@ -552,12 +1298,67 @@ func (m *SafeMap[K, V]) Copy() map[K]V {
 	return lang.CopyMap(m.items)
 }
 
+// CopyInto clears dst and copies all pairs of the map into it, returns dst.
+// It allows reusing an allocated map instead of creating a new one with [SafeMap.Copy].
+// If dst is nil, a new map is created. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) CopyInto(dst map[K]V) map[K]V {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return copyInto(dst, m.items)
+}
+
+// Merge copies all key-value pairs from each of the provided maps into the map holding the write lock
+// for the whole operation, so the merge is atomic for other goroutines.
+// If a key exists in several maps, the value from the later one is kept.
+// It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) Merge(others ...map[K]V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.items == nil {
+		m.items = make(map[K]V)
+	}
+	for _, other := range others {
+		maps.Copy(m.items, other)
+	}
+	m.version.Add(1)
+}
+
+// MergeFrom copies all key-value pairs from the other [SafeMap] into the map, overwriting existing values.
+// A snapshot of other is taken before locking the map, so merging two maps into each other
+// concurrently does not deadlock. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) MergeFrom(other *SafeMap[K, V]) {
+	if other == nil {
+		return
+	}
+	m.Merge(other.Copy())
+}
+
+// MergeWith copies all key-value pairs from other into the map holding the write lock
+// for the whole operation, so the merge is atomic for other goroutines.
+// For keys that already exist in the map, the stored value is onConflict(key, existing, incoming).
+// If onConflict is nil, incoming values overwrite existing ones like in [SafeMap.Merge].
+// It is safe for concurrent/parallel use.
+// DON'T USE SAFE MAP METHOD INSIDE onConflict TO PREVENT FROM DEADLOCK!
+func (m *SafeMap[K, V]) MergeWith(other map[K]V, onConflict func(key K, existing, incoming V) V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.items == nil {
+		m.items = make(map[K]V, len(other))
+	}
+	mergeWith(m.items, other, onConflict)
+	m.version.Add(1)
+}
+
 // Clear creates a new map using make without size.
 func (m *SafeMap[K, V]) Clear() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	m.items = make(map[K]V)
+	m.version.Add(1)
 }
 
 // Refill creates a new map with values from the provided one.
@@ -570,6 +1371,55 @@ func (m *SafeMap[K, V]) Refill(raw map[K]V) {
 	}
 
 	m.items = lang.CopyMap(raw)
+	m.version.Add(1)
+}
+
+// ReplaceAll replaces the underlying map with a copy of the provided one under the write lock
+// and returns the previous map. The copy is made before acquiring the lock.
+// It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) ReplaceAll(newItems map[K]V) map[K]V {
+	items := lang.CopyMap(newItems)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old := m.items
+	m.items = items
+	m.version.Add(1)
+	return old
+}
+
+// Adopt replaces the underlying map with the provided one without copying it, the map takes ownership of it.
+// The provided map must not be used by the caller after this call.
+// It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) Adopt(items map[K]V) {
+	if items == nil {
+		items = make(map[K]V)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.items = items
+	m.version.Add(1)
+}
+
+// Version returns the current version of the map. The version is incremented on every mutation
+// made through the map methods, so it can be used to cheaply detect that the map has changed.
+// Changes made directly to the map returned by [SafeMap.Raw] are not tracked.
+// It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) Version() uint64 {
+	return m.version.Load()
+}
+
+// GetWithVersion returns the value for the provided key, true if the key is present in the map
+// and the version of the map at the moment of reading. It is safe for concurrent/parallel use.
+func (m *SafeMap[K, V]) GetWithVersion(key K) (V, bool, uint64) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	v, ok := m.items[key]
+	return v, ok, m.version.Load()
 }
 
 // Raw returns the underlying map.
@@ -642,66 +1492,447 @@ func (m *SafeMap[K, V]) Iter() iter.Seq2[K, V] {
 	return maps.All(m.items)
 }
 
-func getMapsLength[K comparable, V any](maps ...map[K]V) int {
-	length := 0
-	for _, m := range maps {
-		length += len(m)
+// MapAddAll adds the deltas to the values of the [Map], keys that are not present are inserted as zero before adding.
+// It returns the resulting values for the keys from deltas.
+func MapAddAll[K comparable, V Number](m *Map[K, V], deltas map[K]V) map[K]V {
+	if m.items == nil {
+		m.items = make(map[K]V)
 	}
-	return length
+	return addAll(m.items, deltas)
 }
 
-// Entity is an interface for an object that has an ID, a name, and an order.
-type Entity[K comparable] interface {
-	GetID() K
-	GetName() string
-	GetOrder() int
-	SetOrder(int) Entity[K]
+// SafeMapAddAll adds the deltas to the values of the [SafeMap], keys that are not present are inserted as zero before adding.
+// All deltas are applied under a single write lock.
+// It returns the resulting values for the keys from deltas.
+// It is safe for concurrent/parallel use.
+func SafeMapAddAll[K comparable, V Number](m *SafeMap[K, V], deltas map[K]V) map[K]V {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.items == nil {
+		m.items = make(map[K]V)
+	}
+	totals := addAll(m.items, deltas)
+	if len(deltas) > 0 {
+		m.version.Add(1)
+	}
+	return totals
 }
 
-// EntityMap is a map of entities. It has all methods of Map with some new ones.
-// It is not safe for concurrent/parallel, use [SafeEntityMap] if you need it.
-// This map MUST be initialized with NewEntityMap or NewEntityMapWithSize.
-// Otherwise, it will panic.
-type EntityMap[K comparable, T Entity[K]] struct {
-	*Map[K, T]
+// MapReduce folds all entries of the [Map] into a single value, starting with initial
+// and calling f with the accumulator and every entry in arbitrary order.
+func MapReduce[K comparable, V any, R any](m *Map[K, V], initial R, f func(R, K, V) R) R {
+	return reduceMap(m.items, initial, f)
 }
 
-// NewEntityMap returns a new EntityMap from the provided map.
-func NewEntityMap[K comparable, T Entity[K]](raw ...map[K]T) *EntityMap[K, T] {
-	return &EntityMap[K, T]{
-		Map: NewMap(raw...),
+// InvertMap returns a new map where every value of m is a key mapped to its key in m.
+// If several keys have the same value, only one of them is kept and it is unspecified which one,
+// because maps are iterated in arbitrary order.
+func InvertMap[K, V comparable](m map[K]V) map[V]K {
+	out := make(map[V]K, len(m))
+	for k, v := range m {
+		out[v] = k
 	}
+	return out
 }
 
-// NewEntityMapWithSize returns a new EntityMap with the provided size.
-func NewEntityMapWithSize[K comparable, T Entity[K]](size int) *EntityMap[K, T] {
-	return &EntityMap[K, T]{
-		Map: NewMapWithSize[K, T](size),
-	}
+// MapInvert returns a new [Map] with the keys and values of the [Map] swapped, see [InvertMap] for details.
+func MapInvert[K, V comparable](m *Map[K, V]) *Map[V, K] {
+	return &Map[V, K]{items: InvertMap(m.items)}
 }
 
-// LookupByName returns the value for the provided name.
-// It is not case-sensetive according to name.
-func (s *EntityMap[K, T]) LookupByName(name string) (T, bool) {
-	name = strings.ToLower(name)
+// SafeMapInvert returns a new [Map] with the keys and values of the [SafeMap] swapped holding the read lock,
+// see [InvertMap] for details. It is safe for concurrent/parallel use.
+func SafeMapInvert[K, V comparable](m *SafeMap[K, V]) *Map[V, K] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 
-	for _, h := range s.Map.items {
-		if strings.ToLower(h.GetName()) == name {
-			return h, true
+	return &Map[V, K]{items: InvertMap(m.items)}
+}
+
+// MapDiff compares the maps a and b and returns new maps with the entries whose keys exist only in a,
+// with the entries whose keys exist only in b and with the pairs of values of a and b for common keys.
+// Values are not compared, use the pairs to find changed values.
+func MapDiff[K comparable, V any](a, b map[K]V) (onlyInA, onlyInB map[K]V, both map[K][2]V) {
+	onlyInA, onlyInB, both = make(map[K]V), make(map[K]V), make(map[K][2]V)
+	for k, va := range a {
+		if vb, ok := b[k]; ok {
+			both[k] = [2]V{va, vb}
+		} else {
+			onlyInA[k] = va
+		}
+	}
+	for k, vb := range b {
+		if _, ok := a[k]; !ok {
+			onlyInB[k] = vb
 		}
 	}
+	return onlyInA, onlyInB, both
+}
 
-	var zero T
-	return zero, false
+// MapValues returns a new [Map] with the same keys and the values transformed using f,
+// so the values can have another type. It returns an empty map if m is nil or empty.
+func MapValues[K comparable, V1, V2 any](m *Map[K, V1], f func(K, V1) V2) *Map[K, V2] {
+	if m == nil {
+		return NewMap[K, V2]()
+	}
+	out := make(map[K]V2, len(m.items))
+	for k, v := range m.items {
+		out[k] = f(k, v)
+	}
+	return &Map[K, V2]{items: out}
 }
 
-// Set sets the value for the provided key.
-// It sets last order to the entity's order, so it adds to the end of the list.
-// It sets the same order of existing entity in case of conflict.
-// If the entity is not valid, it returns -1.
-// It returns the order of the entity.
-func (s *EntityMap[K, T]) Set(info T) int {
-	id := info.GetID()
+// SafeMapReduce folds all entries of the [SafeMap] into a single value holding the read lock,
+// starting with initial and calling f with the accumulator and every entry in arbitrary order.
+// It is safe for concurrent/parallel use.
+// DON'T USE SAFE MAP METHOD INSIDE f TO PREVENT FROM DEADLOCK!
+func SafeMapReduce[K comparable, V any, R any](m *SafeMap[K, V], initial R, f func(R, K, V) R) R {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return reduceMap(m.items, initial, f)
+}
+
+func reduceMap[K comparable, V any, R any](items map[K]V, acc R, f func(R, K, V) R) R {
+	for k, v := range items {
+		acc = f(acc, k, v)
+	}
+	return acc
+}
+
+// SafeMapCompareAndSwapMany is [SafeMap.CompareAndSwapMany] for comparable values.
+// It is safe for concurrent/parallel use.
+func SafeMapCompareAndSwapMany[K, V comparable](m *SafeMap[K, V], expected, desired map[K]V) bool {
+	return m.CompareAndSwapMany(expected, desired, func(a, b V) bool { return a == b })
+}
+
+// SafeMapDo calls f with the underlying map of the [SafeMap] holding the write lock and returns its result.
+// It allows to read, compute and update the map atomically. The map must not be retained after f returns.
+// DON'T USE SAFE MAP METHODS INSIDE f TO PREVENT FROM DEADLOCK!
+// It is safe for concurrent/parallel use.
+func SafeMapDo[K comparable, V any, R any](m *SafeMap[K, V], f func(map[K]V) R) R {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.items == nil {
+		m.items = make(map[K]V)
+	}
+	defer m.version.Add(1)
+
+	return f(m.items)
+}
+
+// EqualToComparable returns true if the [Map] contains the same keys and values as other.
+func EqualToComparable[K, V comparable](m *Map[K, V], other map[K]V) bool {
+	return maps.Equal(m.items, other)
+}
+
+// SafeMapEqualToComparable returns true if the [SafeMap] contains the same keys and values as other.
+// It is safe for concurrent/parallel use.
+func SafeMapEqualToComparable[K, V comparable](m *SafeMap[K, V], other map[K]V) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return maps.Equal(m.items, other)
+}
+
+// ValueCounts returns how many keys of the [Map] map to each distinct value.
+func ValueCounts[K, V comparable](m *Map[K, V]) map[V]int {
+	return valueCounts(m.items)
+}
+
+// MostCommonValue returns the value that most keys of the [Map] map to and the number of such keys.
+// If several values have the same count, any of them is returned. Returns false if the map is empty.
+func MostCommonValue[K, V comparable](m *Map[K, V]) (V, int, bool) {
+	return mostCommonValue(valueCounts(m.items))
+}
+
+// SafeMapValueCounts returns how many keys of the [SafeMap] map to each distinct value.
+// It is safe for concurrent/parallel use.
+func SafeMapValueCounts[K, V comparable](m *SafeMap[K, V]) map[V]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return valueCounts(m.items)
+}
+
+// SafeMapMostCommonValue returns the value that most keys of the [SafeMap] map to and the number of such keys.
+// If several values have the same count, any of them is returned. Returns false if the map is empty.
+// It is safe for concurrent/parallel use.
+func SafeMapMostCommonValue[K, V comparable](m *SafeMap[K, V]) (V, int, bool) {
+	return mostCommonValue(SafeMapValueCounts(m))
+}
+
+// Entry is a key-value pair of a map.
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// FromEntries creates a new [Map] from the provided key-value pairs.
+// If there are duplicate keys, the last value wins.
+func FromEntries[K comparable, V any](entries []Entry[K, V]) *Map[K, V] {
+	return &Map[K, V]{
+		items: entriesToMap(entries),
+	}
+}
+
+// SafeMapFromEntries creates a new [SafeMap] from the provided key-value pairs.
+// If there are duplicate keys, the last value wins.
+func SafeMapFromEntries[K comparable, V any](entries []Entry[K, V]) *SafeMap[K, V] {
+	return &SafeMap[K, V]{
+		items: entriesToMap(entries),
+	}
+}
+
+// SortedKeysOrdered returns a slice of keys of the [Map] sorted in ascending order.
+func SortedKeysOrdered[K Ordered, V any](m *Map[K, V]) []K {
+	return sortedKeys(m.items, func(a, b K) bool { return a < b })
+}
+
+// SafeMapSortedKeysOrdered returns a slice of keys of the [SafeMap] sorted in ascending order.
+// It is safe for concurrent/parallel use.
+func SafeMapSortedKeysOrdered[K Ordered, V any](m *SafeMap[K, V]) []K {
+	return m.SortedKeys(func(a, b K) bool { return a < b })
+}
+
+// SortedEntries returns a slice of key-value pairs of the [Map] sorted by key.
+func SortedEntries[K Ordered, V any](m *Map[K, V]) []Entry[K, V] {
+	return sortEntries(entries(m.items))
+}
+
+// SafeMapSortedEntries returns a slice of key-value pairs of the [SafeMap] sorted by key.
+// It is safe for concurrent/parallel use.
+func SafeMapSortedEntries[K Ordered, V any](m *SafeMap[K, V]) []Entry[K, V] {
+	return sortEntries(m.Entries())
+}
+
+func entries[K comparable, V any](items map[K]V) []Entry[K, V] {
+	out := make([]Entry[K, V], 0, len(items))
+	for k, v := range items {
+		out = append(out, Entry[K, V]{Key: k, Value: v})
+	}
+	return out
+}
+
+func sortEntries[K Ordered, V any](entries []Entry[K, V]) []Entry[K, V] {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+func entriesToMap[K comparable, V any](entries []Entry[K, V]) map[K]V {
+	items := make(map[K]V, len(entries))
+	for _, e := range entries {
+		items[e.Key] = e.Value
+	}
+	return items
+}
+
+// ZipMap creates a new [Map] from parallel slices of keys and values.
+// It returns an error if the slices have different lengths.
+// If there are duplicate keys, the last value wins.
+func ZipMap[K comparable, V any](keys []K, values []V) (*Map[K, V], error) {
+	if len(keys) != len(values) {
+		return nil, fmt.Errorf("length mismatch: %d keys and %d values", len(keys), len(values))
+	}
+	return &Map[K, V]{
+		items: zipToMap(keys, values),
+	}, nil
+}
+
+// SafeZipMap creates a new [SafeMap] from parallel slices of keys and values.
+// It returns an error if the slices have different lengths.
+// If there are duplicate keys, the last value wins.
+func SafeZipMap[K comparable, V any](keys []K, values []V) (*SafeMap[K, V], error) {
+	if len(keys) != len(values) {
+		return nil, fmt.Errorf("length mismatch: %d keys and %d values", len(keys), len(values))
+	}
+	return &SafeMap[K, V]{
+		items: zipToMap(keys, values),
+	}, nil
+}
+
+// ZipMapTrunc creates a new [Map] from parallel slices of keys and values.
+// It uses only the first min(len(keys), len(values)) elements and ignores the rest.
+func ZipMapTrunc[K comparable, V any](keys []K, values []V) *Map[K, V] {
+	n := min(len(keys), len(values))
+	return &Map[K, V]{
+		items: zipToMap(keys[:n], values[:n]),
+	}
+}
+
+func zipToMap[K comparable, V any](keys []K, values []V) map[K]V {
+	items := make(map[K]V, len(keys))
+	for i, k := range keys {
+		items[k] = values[i]
+	}
+	return items
+}
+
+// ValueSet returns a new [Set] with the distinct values of the [Map].
+func ValueSet[K, V comparable](m *Map[K, V]) *Set[V] {
+	return valueSet(m.items)
+}
+
+// SafeMapValueSet returns a new [Set] with the distinct values of the [SafeMap].
+// It is safe for concurrent/parallel use.
+func SafeMapValueSet[K, V comparable](m *SafeMap[K, V]) *Set[V] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return valueSet(m.items)
+}
+
+func copyInto[K comparable, V any](dst, src map[K]V) map[K]V {
+	if dst == nil {
+		dst = make(map[K]V, len(src))
+	}
+	clear(dst)
+	maps.Copy(dst, src)
+	return dst
+}
+
+func partitionMap[K comparable, V any](items map[K]V, pred func(K, V) bool) (matched, rest map[K]V) {
+	matched = make(map[K]V)
+	rest = make(map[K]V)
+	for k, v := range items {
+		if pred(k, v) {
+			matched[k] = v
+		} else {
+			rest[k] = v
+		}
+	}
+	return matched, rest
+}
+
+// sampleKeys chooses up to n random keys using a partial Fisher-Yates shuffle.
+func sampleKeys[K comparable, V any](items map[K]V, n int) []K {
+	if n <= 0 || len(items) == 0 {
+		return []K{}
+	}
+	keys := lang.Keys(items)
+	n = min(n, len(keys))
+	for i := range n {
+		j := i + int(getRand(len(keys)-i))
+		keys[i], keys[j] = keys[j], keys[i]
+	}
+	return keys[:n]
+}
+
+func samplePairs[K comparable, V any](items map[K]V, n int) map[K]V {
+	keys := sampleKeys(items, n)
+	out := make(map[K]V, len(keys))
+	for _, k := range keys {
+		out[k] = items[k]
+	}
+	return out
+}
+
+func keySet[K comparable, V any](items map[K]V) *Set[K] {
+	out := NewSetWithSize[K](len(items))
+	for k := range items {
+		out.items[k] = struct{}{}
+	}
+	return out
+}
+
+func valueSet[K, V comparable](items map[K]V) *Set[V] {
+	out := NewSetWithSize[V](len(items))
+	for _, v := range items {
+		out.items[v] = struct{}{}
+	}
+	return out
+}
+
+func valueCounts[K, V comparable](items map[K]V) map[V]int {
+	counts := make(map[V]int)
+	for _, v := range items {
+		counts[v]++
+	}
+	return counts
+}
+
+func mostCommonValue[V comparable](counts map[V]int) (value V, count int, ok bool) {
+	for v, c := range counts {
+		if c > count {
+			value, count, ok = v, c, true
+		}
+	}
+	return value, count, ok
+}
+
+func addAll[K comparable, V Number](items map[K]V, deltas map[K]V) map[K]V {
+	totals := make(map[K]V, len(deltas))
+	for k, delta := range deltas {
+		items[k] += delta
+		totals[k] = items[k]
+	}
+	return totals
+}
+
+func getMapsLength[K comparable, V any](maps ...map[K]V) int {
+	length := 0
+	for _, m := range maps {
+		length += len(m)
+	}
+	return length
+}
+
+// Entity is an interface for an object that has an ID, a name, and an order.
+type Entity[K comparable] interface {
+	GetID() K
+	GetName() string
+	GetOrder() int
+	SetOrder(int) Entity[K]
+}
+
+// EntityMap is a map of entities. It has all methods of Map with some new ones.
+// It is not safe for concurrent/parallel, use [SafeEntityMap] if you need it.
+// This map MUST be initialized with NewEntityMap or NewEntityMapWithSize.
+// Otherwise, it will panic.
+type EntityMap[K comparable, T Entity[K]] struct {
+	*Map[K, T]
+}
+
+// NewEntityMap returns a new EntityMap from the provided map.
+func NewEntityMap[K comparable, T Entity[K]](raw ...map[K]T) *EntityMap[K, T] {
+	return &EntityMap[K, T]{
+		Map: NewMap(raw...),
+	}
+}
+
+// NewEntityMapWithSize returns a new EntityMap with the provided size.
+func NewEntityMapWithSize[K comparable, T Entity[K]](size int) *EntityMap[K, T] {
+	return &EntityMap[K, T]{
+		Map: NewMapWithSize[K, T](size),
+	}
+}
+
+// LookupByName returns the value for the provided name.
+// It is not case-sensetive according to name.
+func (s *EntityMap[K, T]) LookupByName(name string) (T, bool) {
+	name = strings.ToLower(name)
+
+	for _, h := range s.Map.items {
+		if strings.ToLower(h.GetName()) == name {
+			return h, true
+		}
+	}
+
+	var zero T
+	return zero, false
+}
+
+// Set sets the value for the provided key.
+// It sets last order to the entity's order, so it adds to the end of the list.
+// It sets the same order of existing entity in case of conflict.
+// If the entity is not valid, it returns -1.
+// It returns the order of the entity.
+func (s *EntityMap[K, T]) Set(info T) int {
+	id := info.GetID()
 	old, ok := s.Map.items[id]
 	if ok {
 		info, ok = info.SetOrder(old.GetOrder()).(T)
@@ -778,6 +2009,46 @@ func handleBrokenOrder[K comparable, T Entity[K]](out []T, broken []T, seen []bo
 	return out
 }
 
+// IsOrderValid returns true if orders of the entities are exactly 0..n-1 without duplicates.
+// It can be used to detect broken orders, e.g. after misuse of [EntityMap.SetManualOrder].
+func (s *EntityMap[K, T]) IsOrderValid() bool {
+	return len(orderErrors(s.Map.items)) == 0
+}
+
+// OrderErrors returns keys of entities with out-of-range or duplicate orders.
+// All entities sharing a duplicate order are returned. Keys are sorted by order.
+func (s *EntityMap[K, T]) OrderErrors() []K {
+	return orderErrors(s.Map.items)
+}
+
+func orderErrors[K comparable, T Entity[K]](items map[K]T) []K {
+	var (
+		nOfItems = len(items)
+		counts   = make([]int, nOfItems)
+		broken   []T
+	)
+	for _, h := range items {
+		if order := h.GetOrder(); order >= 0 && order < nOfItems {
+			counts[order]++
+		}
+	}
+	for _, h := range items {
+		order := h.GetOrder()
+		if order < 0 || order >= nOfItems || counts[order] > 1 {
+			broken = append(broken, h)
+		}
+	}
+	sort.SliceStable(broken, func(i, j int) bool {
+		return broken[i].GetOrder() < broken[j].GetOrder()
+	})
+
+	out := make([]K, 0, len(broken))
+	for _, h := range broken {
+		out = append(out, h.GetID())
+	}
+	return out
+}
+
 // NextOrder returns the next order.
 func (s *EntityMap[K, T]) NextOrder() int {
 	return len(s.Map.items)
@@ -788,6 +2059,39 @@ func (s *EntityMap[K, T]) ChangeOrder(draft map[K]int) {
 	changeOrder(s.Map.items, s.AllOrdered(), draft)
 }
 
+// OrderMap returns the current order of every entity by its key.
+// It can be persisted and restored later with [EntityMap.RestoreOrder].
+func (s *EntityMap[K, T]) OrderMap() map[K]int {
+	return orderMap(s.Map.items)
+}
+
+// RestoreOrder changes the order of the values like [EntityMap.ChangeOrder],
+// but returns an error without changing anything if the order map contains an unknown key.
+func (s *EntityMap[K, T]) RestoreOrder(orders map[K]int) error {
+	if err := checkOrderKeys(s.Map.items, orders); err != nil {
+		return err
+	}
+	s.ChangeOrder(orders)
+	return nil
+}
+
+func orderMap[K comparable, T Entity[K]](items map[K]T) map[K]int {
+	out := make(map[K]int, len(items))
+	for k, v := range items {
+		out[k] = v.GetOrder()
+	}
+	return out
+}
+
+func checkOrderKeys[K comparable, T Entity[K]](items map[K]T, orders map[K]int) error {
+	for k := range orders {
+		if _, ok := items[k]; !ok {
+			return fmt.Errorf("unknown entity %v", k)
+		}
+	}
+	return nil
+}
+
 func changeOrder[K comparable, T Entity[K]](items map[K]T, ordered []T, draft map[K]int) {
 	maxOrder := len(draft)
 	for _, item := range ordered {
@@ -807,34 +2111,43 @@ func changeOrder[K comparable, T Entity[K]](items map[K]T, ordered []T, draft ma
 // Delete deletes values for the provided keys.
 // It reorders all remaining values.
 func (s *EntityMap[K, T]) Delete(keys ...K) (deleted bool) {
-	return deleteEntity(s.Map.items, allOrdered[K, T], keys...)
+	return deleteEntities(s.Map.items, keys...) > 0
 }
 
-func deleteEntity[K comparable, T Entity[K]](items map[K]T, ordered func(map[K]T) []T, keys ...K) (deleted bool) {
+// DeleteMany deletes values for the provided keys and returns the number of deleted values.
+// It removes all keys first and then reorders remaining values in a single pass,
+// so orders stay contiguous starting from zero.
+func (s *EntityMap[K, T]) DeleteMany(keys ...K) int {
+	return deleteEntities(s.Map.items, keys...)
+}
+
+func deleteEntities[K comparable, T Entity[K]](items map[K]T, keys ...K) int {
+	ordered := allOrdered(items)
+
+	var deleted int
 	for _, key := range keys {
-		toDelete, ok := items[key]
-		if !ok {
-			continue
+		if _, ok := items[key]; ok {
+			delete(items, key)
+			deleted++
 		}
+	}
+	if deleted == 0 {
+		return 0
+	}
 
-		deleteOrder := toDelete.GetOrder()
-		ordered := ordered(items)
-
-		for i, h := range ordered {
-			if i == deleteOrder {
-				delete(items, key)
-				deleted = true
-				continue
-			}
-			if i > deleteOrder {
-				h, ok = h.SetOrder(h.GetOrder() - 1).(T)
-				if !ok {
-					continue
-				}
+	var order int
+	for _, h := range ordered {
+		if _, ok := items[h.GetID()]; !ok {
+			continue
+		}
+		if h.GetOrder() != order {
+			if updated, ok := h.SetOrder(order).(T); ok {
+				items[updated.GetID()] = updated
 			}
-			items[h.GetID()] = h
 		}
+		order++
 	}
+
 	return deleted
 }
 
@@ -903,6 +2216,7 @@ func (s *SafeEntityMap[K, T]) Set(info T) int {
 		}
 	}
 	s.SafeMap.items[id] = info
+	s.version.Add(1)
 
 	return info.GetOrder()
 }
@@ -916,11 +2230,15 @@ func (s *SafeEntityMap[K, T]) SetManualOrder(info T) int {
 	defer s.mu.Unlock()
 
 	s.SafeMap.items[info.GetID()] = info
+	s.version.Add(1)
 
 	return info.GetOrder()
 }
 
 // AllOrdered returns all values in the map sorted by their order.
+// Only building the slice is protected by the lock: if T is a pointer type, the returned values
+// alias the entities stored in the map, so they can be mutated by concurrent calls that change orders.
+// Use [SafeEntityMap.AllOrderedCopy] to get a stable snapshot.
 // It is safe for concurrent/parallel use.
 func (s *SafeEntityMap[K, T]) AllOrdered() []T {
 	s.mu.RLock()
@@ -929,33 +2247,108 @@ func (s *SafeEntityMap[K, T]) AllOrdered() []T {
 	return allOrdered(s.SafeMap.items)
 }
 
-// NextOrder returns the next order number.
+// AllOrderedCopy returns all values in the map sorted by their order like [SafeEntityMap.AllOrdered],
+// but every value is passed through the optional clone while the lock is held.
+// Value entities are copied anyway, so clone is needed only for pointer entities
+// to get independent copies that are not affected by concurrent changes of the map.
 // It is safe for concurrent/parallel use.
-func (s *SafeEntityMap[K, T]) NextOrder() int {
+func (s *SafeEntityMap[K, T]) AllOrderedCopy(cloneRaw ...func(T) T) []T {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	return len(s.SafeMap.items)
+	out := allOrdered(s.SafeMap.items)
+	if len(cloneRaw) > 0 && cloneRaw[0] != nil {
+		for i, v := range out {
+			out[i] = cloneRaw[0](v)
+		}
+	}
+	return out
 }
 
-// ChangeOrder changes the order of the values in the map based on the provided map.
+// IsOrderValid returns true if orders of the entities are exactly 0..n-1 without duplicates.
 // It is safe for concurrent/parallel use.
-func (s *SafeEntityMap[K, T]) ChangeOrder(draft map[K]int) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	ordered := allOrdered(s.SafeMap.items)
+func (s *SafeEntityMap[K, T]) IsOrderValid() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	changeOrder(s.SafeMap.items, ordered, draft)
+	return len(orderErrors(s.SafeMap.items)) == 0
 }
 
-// Delete deletes values for the provided keys.
-// It reorders all remaining values.
+// OrderErrors returns keys of entities with out-of-range or duplicate orders sorted by order.
 // It is safe for concurrent/parallel use.
-func (s *SafeEntityMap[K, T]) Delete(keys ...K) (deleted bool) {
+func (s *SafeEntityMap[K, T]) OrderErrors() []K {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return orderErrors(s.SafeMap.items)
+}
+
+// NextOrder returns the next order number.
+// It is safe for concurrent/parallel use.
+func (s *SafeEntityMap[K, T]) NextOrder() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.SafeMap.items)
+}
+
+// ChangeOrder changes the order of the values in the map based on the provided map.
+// It is safe for concurrent/parallel use.
+func (s *SafeEntityMap[K, T]) ChangeOrder(draft map[K]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ordered := allOrdered(s.SafeMap.items)
+
+	changeOrder(s.SafeMap.items, ordered, draft)
+	s.version.Add(1)
+}
+
+// OrderMap returns the current order of every entity by its key.
+// It is safe for concurrent/parallel use.
+func (s *SafeEntityMap[K, T]) OrderMap() map[K]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return orderMap(s.SafeMap.items)
+}
+
+// RestoreOrder changes the order of the values like [SafeEntityMap.ChangeOrder],
+// but returns an error without changing anything if the order map contains an unknown key.
+// It is safe for concurrent/parallel use.
+func (s *SafeEntityMap[K, T]) RestoreOrder(orders map[K]int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return deleteEntity(s.SafeMap.items, allOrdered[K, T], keys...)
+
+	if err := checkOrderKeys(s.SafeMap.items, orders); err != nil {
+		return err
+	}
+	changeOrder(s.SafeMap.items, allOrdered(s.SafeMap.items), orders)
+	s.version.Add(1)
+
+	return nil
+}
+
+// Delete deletes values for the provided keys.
+// It reorders all remaining values.
+// It is safe for concurrent/parallel use.
+func (s *SafeEntityMap[K, T]) Delete(keys ...K) (deleted bool) {
+	return s.DeleteMany(keys...) > 0
+}
+
+// DeleteMany deletes values for the provided keys and returns the number of deleted values.
+// It removes all keys first and then reorders remaining values in a single pass,
+// so orders stay contiguous starting from zero.
+// It is safe for concurrent/parallel use.
+func (s *SafeEntityMap[K, T]) DeleteMany(keys ...K) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := deleteEntities(s.SafeMap.items, keys...)
+	if deleted > 0 {
+		s.version.Add(1)
+	}
+	return deleted
 }
 
 // OrderedPairs is a data structure that behaves like a map but remembers
@@ -1012,6 +2405,20 @@ func (m *OrderedPairs[K, V]) Get(key K) (res V) {
 	return res
 }
 
+// GetOr returns the value associated with the key or def if the key is not present.
+func (m *OrderedPairs[K, V]) GetOr(key K, def V) V {
+	v, _ := m.LookupOr(key, def)
+	return v
+}
+
+// LookupOr returns the value associated with the key and true if the key is present, def and false otherwise.
+func (m *OrderedPairs[K, V]) LookupOr(key K, def V) (V, bool) {
+	if index, ok := m.indexes[key]; ok {
+		return m.elems[index], true
+	}
+	return def, false
+}
+
 // Keys returns a slice of all keys in the structure.
 func (m *OrderedPairs[K, V]) Keys() []K {
 	return m.keys
@@ -1033,6 +2440,69 @@ func (m *OrderedPairs[K, V]) RandKey() K {
 	return m.keys[getRand(len(m.keys))]
 }
 
+// RandWeighted returns a random value from the structure with probability proportional to its weight.
+// Pairs with zero, negative or non-finite weights are never selected. If there are no pairs with positive weight,
+// the value is selected uniformly like [OrderedPairs.Rand].
+func (m *OrderedPairs[K, V]) RandWeighted(weight func(K, V) float64) V {
+	if len(m.elems) == 0 {
+		return *new(V)
+	}
+
+	weights := make([]float64, len(m.elems))
+	var total float64
+	for i, key := range m.keys {
+		w := weight(key, m.elems[i])
+		if w > 0 && !math.IsInf(w, 0) {
+			weights[i] = w
+			total += w
+		}
+	}
+	if total == 0 || math.IsInf(total, 0) {
+		return m.Rand()
+	}
+
+	target := getRandFloat() * total
+	last := 0
+	for i, w := range weights {
+		if w == 0 {
+			continue
+		}
+		if target < w {
+			return m.elems[i]
+		}
+		target -= w
+		last = i
+	}
+	// Rounding errors can leave a tiny remainder, it belongs to the last selectable pair
+	return m.elems[last]
+}
+
+// Filter returns a new [OrderedPairs] with the pairs for which keep returns true, preserving their order.
+func (m *OrderedPairs[K, V]) Filter(keep func(K, V) bool) *OrderedPairs[K, V] {
+	out := &OrderedPairs[K, V]{
+		indexes: make(map[K]int),
+	}
+	for i, key := range m.keys {
+		if keep(key, m.elems[i]) {
+			out.Add(key, m.elems[i])
+		}
+	}
+	return out
+}
+
+// MapOrderedPairs returns a new [OrderedPairs] with the values transformed using f, preserving the order of pairs.
+func MapOrderedPairs[K Ordered, V, R any](m *OrderedPairs[K, V], f func(K, V) R) *OrderedPairs[K, R] {
+	out := &OrderedPairs[K, R]{
+		elems:   make([]R, 0, len(m.elems)),
+		keys:    make([]K, 0, len(m.keys)),
+		indexes: make(map[K]int, len(m.indexes)),
+	}
+	for i, key := range m.keys {
+		out.Add(key, f(key, m.elems[i]))
+	}
+	return out
+}
+
 func getRand(max int) int64 {
 	nBig, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
 	if err != nil {
@@ -1041,6 +2511,12 @@ func getRand(max int) int64 {
 	return nBig.Int64()
 }
 
+// getRandFloat returns a crypto random float64 in [0, 1).
+func getRandFloat() float64 {
+	const precision = 1 << 53
+	return float64(getRand(precision)) / precision
+}
+
 // SafeOrderedPairs is a thread-safe variant of the OrderedPairs type.
 // It uses a RW mutex to protect the underlying structure.
 // This map MUST be initialized with NewSafeOrderedPairs or NewSafeOrderedPairsWithSize.
@@ -1078,6 +2554,24 @@ func (s *SafeOrderedPairs[K, V]) Get(key K) (res V) {
 	return s.OrderedPairs.Get(key)
 }
 
+// GetOr returns the value associated with the key or def if the key is not present.
+// It is a thread-safe variant of the GetOr method.
+func (s *SafeOrderedPairs[K, V]) GetOr(key K, def V) V {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.OrderedPairs.GetOr(key, def)
+}
+
+// LookupOr returns the value associated with the key and true if the key is present, def and false otherwise.
+// It is a thread-safe variant of the LookupOr method.
+func (s *SafeOrderedPairs[K, V]) LookupOr(key K, def V) (V, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.OrderedPairs.LookupOr(key, def)
+}
+
 // Rand returns a random value from the structure.
 // It is a thread-safe variant of the Rand method.
 func (s *SafeOrderedPairs[K, V]) Rand() V {
@@ -1087,6 +2581,15 @@ func (s *SafeOrderedPairs[K, V]) Rand() V {
 	return s.OrderedPairs.Rand()
 }
 
+// RandWeighted returns a random value from the structure with probability proportional to its weight.
+// It is a thread-safe variant of the RandWeighted method.
+func (s *SafeOrderedPairs[K, V]) RandWeighted(weight func(K, V) float64) V {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.OrderedPairs.RandWeighted(weight)
+}
+
 // RandKey returns a random key from the structure.
 // It is a thread-safe variant of the RandKey method.
 func (s *SafeOrderedPairs[K, V]) RandKey() K {
@@ -1096,6 +2599,28 @@ func (s *SafeOrderedPairs[K, V]) RandKey() K {
 	return s.OrderedPairs.RandKey()
 }
 
+// Filter returns a new [SafeOrderedPairs] with the pairs for which keep returns true, preserving their order.
+// It is a thread-safe variant of the Filter method.
+func (s *SafeOrderedPairs[K, V]) Filter(keep func(K, V) bool) *SafeOrderedPairs[K, V] {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return &SafeOrderedPairs[K, V]{
+		OrderedPairs: s.OrderedPairs.Filter(keep),
+	}
+}
+
+// MapSafeOrderedPairs returns a new [SafeOrderedPairs] with the values transformed using f, preserving the order of pairs.
+// It is a thread-safe variant of the MapOrderedPairs function.
+func MapSafeOrderedPairs[K Ordered, V, R any](s *SafeOrderedPairs[K, V], f func(K, V) R) *SafeOrderedPairs[K, R] {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return &SafeOrderedPairs[K, R]{
+		OrderedPairs: MapOrderedPairs(s.OrderedPairs, f),
+	}
+}
+
 // MapOfMaps is a nested map structure that maps keys to maps.
 // It provides methods to work both at the outer level and with nested key-value pairs.
 type MapOfMaps[K1 comparable, K2 comparable, V comparable] struct {
@@ -1155,6 +2680,20 @@ func (m *MapOfMaps[K1, K2, V]) Lookup(outerKey K1, innerKey K2) (V, bool) {
 	return zero, false
 }
 
+// GetOr returns the value for the provided nested keys or def if they are not present.
+func (m *MapOfMaps[K1, K2, V]) GetOr(outerKey K1, innerKey K2, def V) V {
+	v, _ := m.LookupOr(outerKey, innerKey, def)
+	return v
+}
+
+// LookupOr returns the value for the provided nested keys and true if they are present, def and false otherwise.
+func (m *MapOfMaps[K1, K2, V]) LookupOr(outerKey K1, innerKey K2, def V) (V, bool) {
+	if v, ok := m.Lookup(outerKey, innerKey); ok {
+		return v, true
+	}
+	return def, false
+}
+
 // LookupMap returns the inner map for the provided outer key and true if present, nil and false otherwise.
 func (m *MapOfMaps[K1, K2, V]) LookupMap(outerKey K1) (map[K2]V, bool) {
 	if m.items == nil {
@@ -1390,6 +2929,26 @@ func (m *MapOfMaps[K1, K2, V]) Transform(f func(K1, K2, V) V) {
 	}
 }
 
+// TransformFilter transforms all values across all inner maps using the provided function
+// and deletes the nested keys for which the function returns false. Empty inner maps are removed.
+func (m *MapOfMaps[K1, K2, V]) TransformFilter(f func(K1, K2, V) (V, bool)) {
+	if m.items == nil {
+		m.items = make(map[K1]map[K2]V)
+	}
+	transformFilterNested(m.items, f)
+}
+
+func transformFilterNested[K1, K2 comparable, V any](items map[K1]map[K2]V, f func(K1, K2, V) (V, bool)) {
+	for outerKey, innerMap := range items {
+		transformFilter(innerMap, func(innerKey K2, value V) (V, bool) {
+			return f(outerKey, innerKey, value)
+		})
+		if len(innerMap) == 0 {
+			delete(items, outerKey)
+		}
+	}
+}
+
 // Range calls the provided function for each nested key-value pair.
 func (m *MapOfMaps[K1, K2, V]) Range(f func(K1, K2, V) bool) bool {
 	if m.items == nil {
@@ -1405,6 +2964,25 @@ func (m *MapOfMaps[K1, K2, V]) Range(f func(K1, K2, V) bool) bool {
 	return true
 }
 
+// Filter returns a new nested map with the entries for which keep returns true.
+// Inner maps that have no matching entries are not included in the result.
+func (m *MapOfMaps[K1, K2, V]) Filter(keep func(K1, K2, V) bool) map[K1]map[K2]V {
+	return filterMapOfMaps(m.items, keep)
+}
+
+// Count returns the number of nested entries for which pred returns true.
+func (m *MapOfMaps[K1, K2, V]) Count(pred func(K1, K2, V) bool) int {
+	return countMapOfMaps(m.items, pred)
+}
+
+// FilterInto returns a new [MapOfMaps] with the entries for which keep returns true.
+// Inner maps that have no matching entries are not included in the result.
+func (m *MapOfMaps[K1, K2, V]) FilterInto(keep func(K1, K2, V) bool) *MapOfMaps[K1, K2, V] {
+	return &MapOfMaps[K1, K2, V]{
+		items: filterMapOfMaps(m.items, keep),
+	}
+}
+
 // Copy returns a deep copy of the nested map structure.
 func (m *MapOfMaps[K1, K2, V]) Copy() map[K1]map[K2]V {
 	if m.items == nil {
@@ -1417,6 +2995,35 @@ func (m *MapOfMaps[K1, K2, V]) Copy() map[K1]map[K2]V {
 	return result
 }
 
+// Equal returns true if other has the same outer keys, inner keys and values as the map.
+// An outer key with an empty inner map is not equal to a missing outer key.
+func (m *MapOfMaps[K1, K2, V]) Equal(other *MapOfMaps[K1, K2, V]) bool {
+	return m.EqualFunc(other, func(a, b V) bool { return a == b })
+}
+
+// EqualFunc returns true if other has the same outer and inner keys as the map
+// and their values are equal according to eq.
+// An outer key with an empty inner map is not equal to a missing outer key.
+func (m *MapOfMaps[K1, K2, V]) EqualFunc(other *MapOfMaps[K1, K2, V], eq func(V, V) bool) bool {
+	if other == nil {
+		return false
+	}
+	return equalMapOfMaps(m.items, other.items, eq)
+}
+
+func equalMapOfMaps[K1, K2 comparable, V any](a, b map[K1]map[K2]V, eq func(V, V) bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for outerKey, innerA := range a {
+		innerB, ok := b[outerKey]
+		if !ok || !maps.EqualFunc(innerA, innerB, eq) {
+			return false
+		}
+	}
+	return true
+}
+
 // Raw returns the underlying nested map structure.
 func (m *MapOfMaps[K1, K2, V]) Raw() map[K1]map[K2]V {
 	if m.items == nil {
@@ -1443,6 +3050,38 @@ func (m *MapOfMaps[K1, K2, V]) Refill(raw map[K1]map[K2]V) {
 	m.items = result
 }
 
+func countMapOfMaps[K1 comparable, K2 comparable, V any](items map[K1]map[K2]V, pred func(K1, K2, V) bool) int {
+	var n int
+	for outerKey, innerMap := range items {
+		for innerKey, value := range innerMap {
+			if pred(outerKey, innerKey, value) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func filterMapOfMaps[K1 comparable, K2 comparable, V comparable](items map[K1]map[K2]V, keep func(K1, K2, V) bool) map[K1]map[K2]V {
+	result := make(map[K1]map[K2]V)
+	for outerKey, innerMap := range items {
+		var filtered map[K2]V
+		for innerKey, value := range innerMap {
+			if !keep(outerKey, innerKey, value) {
+				continue
+			}
+			if filtered == nil {
+				filtered = make(map[K2]V)
+			}
+			filtered[innerKey] = value
+		}
+		if filtered != nil {
+			result[outerKey] = filtered
+		}
+	}
+	return result
+}
+
 func getMapsOfMapsLength[K1 comparable, K2 comparable, V comparable](maps ...map[K1]map[K2]V) int {
 	length := 0
 	for _, m := range maps {
@@ -1540,6 +3179,22 @@ func (m *SafeMapOfMaps[K1, K2, V]) Lookup(outerKey K1, innerKey K2) (V, bool) {
 	return zero, false
 }
 
+// GetOr returns the value for the provided nested keys or def if they are not present.
+// It is safe for concurrent/parallel use.
+func (m *SafeMapOfMaps[K1, K2, V]) GetOr(outerKey K1, innerKey K2, def V) V {
+	v, _ := m.LookupOr(outerKey, innerKey, def)
+	return v
+}
+
+// LookupOr returns the value for the provided nested keys and true if they are present, def and false otherwise.
+// It is safe for concurrent/parallel use.
+func (m *SafeMapOfMaps[K1, K2, V]) LookupOr(outerKey K1, innerKey K2, def V) (V, bool) {
+	if v, ok := m.Lookup(outerKey, innerKey); ok {
+		return v, true
+	}
+	return def, false
+}
+
 // LookupMap returns the inner map for the provided outer key and true if present, nil and false otherwise.
 // It is safe for concurrent/parallel use.
 func (m *SafeMapOfMaps[K1, K2, V]) LookupMap(outerKey K1) (map[K2]V, bool) {
@@ -1901,6 +3556,20 @@ func (m *SafeMapOfMaps[K1, K2, V]) Transform(f func(K1, K2, V) V) {
 	}
 }
 
+// TransformFilter transforms all values across all inner maps using the provided function
+// and deletes the nested keys for which the function returns false. Empty inner maps are removed.
+// It is safe for concurrent/parallel use.
+func (m *SafeMapOfMaps[K1, K2, V]) TransformFilter(f func(K1, K2, V) (V, bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.items == nil {
+		m.items = make(map[K1]map[K2]V)
+	}
+
+	transformFilterNested(m.items, f)
+}
+
 // Range calls the provided function for each nested key-value pair.
 // It is safe for concurrent/parallel use.
 func (m *SafeMapOfMaps[K1, K2, V]) Range(f func(K1, K2, V) bool) bool {
@@ -1925,6 +3594,39 @@ func (m *SafeMapOfMaps[K1, K2, V]) Range(f func(K1, K2, V) bool) bool {
 	return true
 }
 
+// Filter returns a new nested map with the entries for which keep returns true.
+// Inner maps that have no matching entries are not included in the result.
+// The returned maps are independent copies of the underlying data.
+// It is safe for concurrent/parallel use.
+func (m *SafeMapOfMaps[K1, K2, V]) Filter(keep func(K1, K2, V) bool) map[K1]map[K2]V {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return filterMapOfMaps(m.items, keep)
+}
+
+// Count returns the number of nested entries for which pred returns true.
+// It is safe for concurrent/parallel use.
+func (m *SafeMapOfMaps[K1, K2, V]) Count(pred func(K1, K2, V) bool) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return countMapOfMaps(m.items, pred)
+}
+
+// FilterInto returns a new [MapOfMaps] with the entries for which keep returns true.
+// Inner maps that have no matching entries are not included in the result.
+// The returned map is an independent copy of the underlying data.
+// It is safe for concurrent/parallel use.
+func (m *SafeMapOfMaps[K1, K2, V]) FilterInto(keep func(K1, K2, V) bool) *MapOfMaps[K1, K2, V] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return &MapOfMaps[K1, K2, V]{
+		items: filterMapOfMaps(m.items, keep),
+	}
+}
+
 // Copy returns a deep copy of the nested map structure.
 // It is safe for concurrent/parallel use.
 func (m *SafeMapOfMaps[K1, K2, V]) Copy() map[K1]map[K2]V {
@@ -1946,6 +3648,33 @@ func (m *SafeMapOfMaps[K1, K2, V]) Copy() map[K1]map[K2]V {
 	return result
 }
 
+// Equal returns true if other has the same outer keys, inner keys and values as the map.
+// An outer key with an empty inner map is not equal to a missing outer key.
+// It is safe for concurrent/parallel use.
+func (m *SafeMapOfMaps[K1, K2, V]) Equal(other *SafeMapOfMaps[K1, K2, V]) bool {
+	return m.EqualFunc(other, func(a, b V) bool { return a == b })
+}
+
+// EqualFunc returns true if other has the same outer and inner keys as the map
+// and their values are equal according to eq.
+// An outer key with an empty inner map is not equal to a missing outer key.
+// A snapshot of other is taken before locking the map, so two maps can be compared with each other
+// concurrently without deadlocks. It is safe for concurrent/parallel use.
+func (m *SafeMapOfMaps[K1, K2, V]) EqualFunc(other *SafeMapOfMaps[K1, K2, V], eq func(V, V) bool) bool {
+	if other == nil {
+		return false
+	}
+	if other == m {
+		return true
+	}
+	snapshot := other.Copy()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return equalMapOfMaps(m.items, snapshot, eq)
+}
+
 // Raw returns the underlying nested map structure.
 // It is safe for concurrent/parallel use.
 func (m *SafeMapOfMaps[K1, K2, V]) Raw() map[K1]map[K2]V {
@@ -1988,3 +3717,848 @@ func (m *SafeMapOfMaps[K1, K2, V]) Refill(raw map[K1]map[K2]V) {
 	}
 	m.items = result
 }
+
+// ReplaceAll replaces the underlying nested maps with a deep copy of the provided ones under the write lock
+// and returns the previous nested maps. The copy is made before acquiring the lock,
+// so readers never see a partial state.
+// It is safe for concurrent/parallel use.
+func (m *SafeMapOfMaps[K1, K2, V]) ReplaceAll(newItems map[K1]map[K2]V) map[K1]map[K2]V {
+	items := make(map[K1]map[K2]V, len(newItems))
+	for outerKey, innerMap := range newItems {
+		items[outerKey] = lang.CopyMap(innerMap)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old := m.items
+	m.items = items
+	return old
+}
+
+// Adopt replaces the underlying nested maps with the provided ones without copying them,
+// the map takes ownership of them. The provided maps must not be used by the caller after this call.
+// It is safe for concurrent/parallel use.
+func (m *SafeMapOfMaps[K1, K2, V]) Adopt(items map[K1]map[K2]V) {
+	if items == nil {
+		items = make(map[K1]map[K2]V)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.items = items
+}
+
+// SortedMap is a map that keeps its keys in ascending order.
+// It allows range queries and lookups of the nearest keys, unlike [Map] that has no order
+// and [OrderedPairs] that keeps the insertion order.
+// It is backed by a sorted slice, so lookups take O(log n) and insertions and deletions take O(n).
+// It is NOT safe for concurrent/parallel use.
+//
+// The type parameter K must implement the Ordered interface.
+type SortedMap[K Ordered, V any] struct {
+	keys   []K
+	values []V
+}
+
+// NewSortedMap returns a new SortedMap with the entries of the provided maps.
+// If several maps have the same key, the value from the last of them is used.
+func NewSortedMap[K Ordered, V any](raw ...map[K]V) *SortedMap[K, V] {
+	items := make(map[K]V, getMapsLength(raw...))
+	for _, r := range raw {
+		maps.Copy(items, r)
+	}
+
+	m := &SortedMap[K, V]{}
+	if len(items) == 0 {
+		return m
+	}
+
+	// Sort keys once instead of inserting them one by one
+	m.keys = make([]K, 0, len(items))
+	for k := range items {
+		m.keys = append(m.keys, k)
+	}
+	sort.Slice(m.keys, func(i, j int) bool { return m.keys[i] < m.keys[j] })
+
+	m.values = make([]V, len(m.keys))
+	for i, k := range m.keys {
+		m.values[i] = items[k]
+	}
+	return m
+}
+
+// Set sets the value for the key, keeping the keys sorted.
+func (m *SortedMap[K, V]) Set(key K, value V) {
+	i, found := m.search(key)
+	if found {
+		m.values[i] = value
+		return
+	}
+	m.keys = append(m.keys, key)
+	m.values = append(m.values, value)
+	copy(m.keys[i+1:], m.keys[i:])
+	copy(m.values[i+1:], m.values[i:])
+	m.keys[i] = key
+	m.values[i] = value
+}
+
+// Get returns the value for the provided key or the default type value if the key is not present in the map.
+func (m *SortedMap[K, V]) Get(key K) V {
+	v, _ := m.Lookup(key)
+	return v
+}
+
+// Lookup returns the value for the provided key and true if the key is present in the map,
+// the default type value and false otherwise.
+func (m *SortedMap[K, V]) Lookup(key K) (V, bool) {
+	i, found := m.search(key)
+	if !found {
+		return *new(V), false
+	}
+	return m.values[i], true
+}
+
+// GetOr returns the value for the provided key or def if the key is not present in the map.
+func (m *SortedMap[K, V]) GetOr(key K, def V) V {
+	v, _ := m.LookupOr(key, def)
+	return v
+}
+
+// LookupOr returns the value for the provided key and true if the key is present in the map, def and false otherwise.
+func (m *SortedMap[K, V]) LookupOr(key K, def V) (V, bool) {
+	if v, ok := m.Lookup(key); ok {
+		return v, true
+	}
+	return def, false
+}
+
+// Has returns true if the key exists in the map.
+func (m *SortedMap[K, V]) Has(key K) bool {
+	_, found := m.search(key)
+	return found
+}
+
+// Delete removes the keys from the map and returns true if at least one key existed.
+func (m *SortedMap[K, V]) Delete(keys ...K) (deleted bool) {
+	for _, key := range keys {
+		i, found := m.search(key)
+		if !found {
+			continue
+		}
+		m.keys = append(m.keys[:i], m.keys[i+1:]...)
+		m.values[i] = *new(V)
+		m.values = append(m.values[:i], m.values[i+1:]...)
+		deleted = true
+	}
+	return deleted
+}
+
+// Len returns the number of keys in the map.
+func (m *SortedMap[K, V]) Len() int {
+	return len(m.keys)
+}
+
+// Keys returns a slice of all keys in ascending order.
+func (m *SortedMap[K, V]) Keys() []K {
+	return append([]K(nil), m.keys...)
+}
+
+// Values returns a slice of all values in the order of their keys.
+func (m *SortedMap[K, V]) Values() []V {
+	return append([]V(nil), m.values...)
+}
+
+// Min returns the smallest key with its value and false if the map is empty.
+func (m *SortedMap[K, V]) Min() (K, V, bool) {
+	if len(m.keys) == 0 {
+		return *new(K), *new(V), false
+	}
+	return m.keys[0], m.values[0], true
+}
+
+// Max returns the largest key with its value and false if the map is empty.
+func (m *SortedMap[K, V]) Max() (K, V, bool) {
+	if len(m.keys) == 0 {
+		return *new(K), *new(V), false
+	}
+	last := len(m.keys) - 1
+	return m.keys[last], m.values[last], true
+}
+
+// Floor returns the largest key that is less than or equal to the provided key with its value.
+// It returns false if there is no such key.
+func (m *SortedMap[K, V]) Floor(key K) (K, V, bool) {
+	i, found := m.search(key)
+	if !found {
+		i--
+	}
+	if i < 0 {
+		return *new(K), *new(V), false
+	}
+	return m.keys[i], m.values[i], true
+}
+
+// Ceil returns the smallest key that is greater than or equal to the provided key with its value.
+// It returns false if there is no such key.
+func (m *SortedMap[K, V]) Ceil(key K) (K, V, bool) {
+	i, _ := m.search(key)
+	if i >= len(m.keys) {
+		return *new(K), *new(V), false
+	}
+	return m.keys[i], m.values[i], true
+}
+
+// Range calls f for every key in the range [from, to] in ascending order.
+// If f returns false, the iteration stops.
+func (m *SortedMap[K, V]) Range(from, to K, f func(K, V) bool) {
+	start, end := m.bounds(from, to)
+	for i := start; i < end; i++ {
+		if !f(m.keys[i], m.values[i]) {
+			return
+		}
+	}
+}
+
+// Iter returns an iterator over the map in ascending order of keys.
+func (m *SortedMap[K, V]) Iter() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for i := range m.keys {
+			if !yield(m.keys[i], m.values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Clear removes all keys from the map.
+func (m *SortedMap[K, V]) Clear() {
+	m.keys = nil
+	m.values = nil
+}
+
+// search returns the index of the first key that is greater than or equal to the provided key
+// and true if the key at that index is equal to the provided key.
+func (m *SortedMap[K, V]) search(key K) (int, bool) {
+	i := sort.Search(len(m.keys), func(i int) bool { return m.keys[i] >= key })
+	return i, i < len(m.keys) && m.keys[i] == key
+}
+
+// bounds returns the half-open interval of indexes of keys in the range [from, to].
+func (m *SortedMap[K, V]) bounds(from, to K) (int, int) {
+	if from > to {
+		return 0, 0
+	}
+	start, _ := m.search(from)
+	end := sort.Search(len(m.keys), func(i int) bool { return m.keys[i] > to })
+	return start, end
+}
+
+// SafeSortedMap is a thread-safe variant of the [SortedMap] type.
+// It uses a RW mutex to protect the underlying structure.
+//
+// The type parameter K must implement the Ordered interface.
+type SafeSortedMap[K Ordered, V any] struct {
+	m  SortedMap[K, V]
+	mu sync.RWMutex
+}
+
+// NewSafeSortedMap returns a new SafeSortedMap with the entries of the provided maps.
+// It is a thread-safe variant of the NewSortedMap function.
+func NewSafeSortedMap[K Ordered, V any](raw ...map[K]V) *SafeSortedMap[K, V] {
+	return &SafeSortedMap[K, V]{
+		m: *NewSortedMap(raw...),
+	}
+}
+
+// Set sets the value for the key, keeping the keys sorted.
+// It is safe for concurrent/parallel use.
+func (s *SafeSortedMap[K, V]) Set(key K, value V) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.m.Set(key, value)
+}
+
+// Get returns the value for the provided key or the default type value if the key is not present in the map.
+// It is safe for concurrent/parallel use.
+func (s *SafeSortedMap[K, V]) Get(key K) V {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.m.Get(key)
+}
+
+// Lookup returns the value for the provided key and true if the key is present in the map,
+// the default type value and false otherwise.
+// It is safe for concurrent/parallel use.
+func (s *SafeSortedMap[K, V]) Lookup(key K) (V, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.m.Lookup(key)
+}
+
+// GetOr returns the value for the provided key or def if the key is not present in the map.
+// It is safe for concurrent/parallel use.
+func (s *SafeSortedMap[K, V]) GetOr(key K, def V) V {
+	v, _ := s.LookupOr(key, def)
+	return v
+}
+
+// LookupOr returns the value for the provided key and true if the key is present in the map, def and false otherwise.
+// It is safe for concurrent/parallel use.
+func (s *SafeSortedMap[K, V]) LookupOr(key K, def V) (V, bool) {
+	if v, ok := s.Lookup(key); ok {
+		return v, true
+	}
+	return def, false
+}
+
+// Has returns true if the key exists in the map.
+// It is safe for concurrent/parallel use.
+func (s *SafeSortedMap[K, V]) Has(key K) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.m.Has(key)
+}
+
+// Delete removes the keys from the map and returns true if at least one key existed.
+// It is safe for concurrent/parallel use.
+func (s *SafeSortedMap[K, V]) Delete(keys ...K) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.m.Delete(keys...)
+}
+
+// Len returns the number of keys in the map.
+// It is safe for concurrent/parallel use.
+func (s *SafeSortedMap[K, V]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.m.Len()
+}
+
+// Keys returns a slice of all keys in ascending order.
+// It is safe for concurrent/parallel use.
+func (s *SafeSortedMap[K, V]) Keys() []K {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.m.Keys()
+}
+
+// Values returns a slice of all values in the order of their keys.
+// It is safe for concurrent/parallel use.
+func (s *SafeSortedMap[K, V]) Values() []V {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.m.Values()
+}
+
+// Min returns the smallest key with its value and false if the map is empty.
+// It is safe for concurrent/parallel use.
+func (s *SafeSortedMap[K, V]) Min() (K, V, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.m.Min()
+}
+
+// Max returns the largest key with its value and false if the map is empty.
+// It is safe for concurrent/parallel use.
+func (s *SafeSortedMap[K, V]) Max() (K, V, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.m.Max()
+}
+
+// Floor returns the largest key that is less than or equal to the provided key with its value.
+// It returns false if there is no such key.
+// It is safe for concurrent/parallel use.
+func (s *SafeSortedMap[K, V]) Floor(key K) (K, V, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.m.Floor(key)
+}
+
+// Ceil returns the smallest key that is greater than or equal to the provided key with its value.
+// It returns false if there is no such key.
+// It is safe for concurrent/parallel use.
+func (s *SafeSortedMap[K, V]) Ceil(key K) (K, V, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.m.Ceil(key)
+}
+
+// Range calls f for every key in the range [from, to] in ascending order.
+// If f returns false, the iteration stops.
+// It iterates over a snapshot of the range, so it is safe to modify the map inside f.
+// It is safe for concurrent/parallel use.
+func (s *SafeSortedMap[K, V]) Range(from, to K, f func(K, V) bool) {
+	s.mu.RLock()
+	start, end := s.m.bounds(from, to)
+	keys := append([]K(nil), s.m.keys[start:end]...)
+	values := append([]V(nil), s.m.values[start:end]...)
+	s.mu.RUnlock()
+
+	for i := range keys {
+		if !f(keys[i], values[i]) {
+			return
+		}
+	}
+}
+
+// Iter returns an iterator over a snapshot of the map in ascending order of keys.
+// The snapshot is taken when the iteration starts, so it is safe to modify the map inside the loop.
+// It is safe for concurrent/parallel use.
+func (s *SafeSortedMap[K, V]) Iter() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		s.mu.RLock()
+		keys := s.m.Keys()
+		values := s.m.Values()
+		s.mu.RUnlock()
+
+		for i := range keys {
+			if !yield(keys[i], values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Clear removes all keys from the map.
+// It is safe for concurrent/parallel use.
+func (s *SafeSortedMap[K, V]) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.m.Clear()
+}
+
+// Hasher returns a hash of the key that is used to choose a shard of [ShardedMap].
+// Equal keys must have equal hashes.
+type Hasher[K comparable] func(key K) uint64
+
+// ShardedMap is a map that splits keys between several [SafeMap] shards to reduce lock contention.
+// A shard of a key is chosen using the [Hasher]. The default hasher uses [maphash] for strings, numbers and booleans
+// and walks the fields of other keys (e.g. structs) using reflection, it is slower and it is better
+// to provide a custom hasher for such keys.
+// A zero value is ready to use with 16 shards and the default hasher.
+// It is safe for concurrent/parallel use.
+type ShardedMap[K comparable, V any] struct {
+	shards []*SafeMap[K, V]
+	hasher Hasher[K]
+	// initOnce creates shards and the hasher of a zero value map on the first use
+	initOnce sync.Once
+}
+
+// NewShardedMap returns a new [ShardedMap] with the provided number of shards and an optional custom hasher.
+// If shardCount is not positive, 16 shards are used.
+func NewShardedMap[K comparable, V any](shardCount int, hasher ...Hasher[K]) *ShardedMap[K, V] {
+	m := &ShardedMap[K, V]{}
+	if len(hasher) > 0 {
+		m.hasher = hasher[0]
+	}
+	m.init(shardCount)
+	return m
+}
+
+// init creates the shards and the default hasher if they are not set, it is done only once.
+func (m *ShardedMap[K, V]) init(shardCount int) {
+	m.initOnce.Do(func() {
+		if shardCount <= 0 {
+			shardCount = 16
+		}
+		m.shards = make([]*SafeMap[K, V], shardCount)
+		for i := range m.shards {
+			m.shards[i] = NewSafeMap[K, V]()
+		}
+		if m.hasher == nil {
+			m.hasher = defaultHasher[K](maphash.MakeSeed())
+		}
+	})
+}
+
+// Get returns the value for the provided key or the default type value if the key is not present in the map.
+// It is safe for concurrent/parallel use.
+func (m *ShardedMap[K, V]) Get(key K) V {
+	return m.shard(key).Get(key)
+}
+
+// Lookup returns the value for the provided key and true if the key is present in the map.
+// It is safe for concurrent/parallel use.
+func (m *ShardedMap[K, V]) Lookup(key K) (V, bool) {
+	return m.shard(key).Lookup(key)
+}
+
+// GetOr returns the value for the provided key or def if the key is not present in the map.
+// It is safe for concurrent/parallel use.
+func (m *ShardedMap[K, V]) GetOr(key K, def V) V {
+	v, _ := m.LookupOr(key, def)
+	return v
+}
+
+// LookupOr returns the value for the provided key and true if the key is present in the map, def and false otherwise.
+// It is safe for concurrent/parallel use.
+func (m *ShardedMap[K, V]) LookupOr(key K, def V) (V, bool) {
+	if v, ok := m.Lookup(key); ok {
+		return v, true
+	}
+	return def, false
+}
+
+// Has returns true if the key is present in the map. It is safe for concurrent/parallel use.
+func (m *ShardedMap[K, V]) Has(key K) bool {
+	return m.shard(key).Has(key)
+}
+
+// Set sets the value for the provided key. It is safe for concurrent/parallel use.
+func (m *ShardedMap[K, V]) Set(key K, value V) {
+	m.shard(key).Set(key, value)
+}
+
+// Delete removes keys and associated values from the map, returns true if any key was deleted.
+// It is safe for concurrent/parallel use.
+func (m *ShardedMap[K, V]) Delete(keys ...K) (deleted bool) {
+	for _, key := range keys {
+		if m.shard(key).Delete(key) {
+			deleted = true
+		}
+	}
+	return deleted
+}
+
+// Len returns the number of keys in all shards. It is safe for concurrent/parallel use.
+func (m *ShardedMap[K, V]) Len() int {
+	m.init(0)
+
+	var n int
+	for _, s := range m.shards {
+		n += s.Len()
+	}
+	return n
+}
+
+// Range calls the provided function for each key-value pair in the map shard by shard.
+// It stops and returns false if the function returns false.
+// It is safe for concurrent/parallel use, but it is not an atomic snapshot of the whole map.
+// DON'T USE SHARDED MAP METHOD INSIDE LOOP TO PREVENT FROM DEADLOCK!
+func (m *ShardedMap[K, V]) Range(f func(K, V) bool) bool {
+	m.init(0)
+
+	for _, s := range m.shards {
+		if !s.Range(f) {
+			return false
+		}
+	}
+	return true
+}
+
+// ShardStats returns the number of keys in every shard, it can be used to check the distribution of keys.
+// It is safe for concurrent/parallel use.
+func (m *ShardedMap[K, V]) ShardStats() []int {
+	m.init(0)
+
+	stats := make([]int, len(m.shards))
+	for i, s := range m.shards {
+		stats[i] = s.Len()
+	}
+	return stats
+}
+
+func (m *ShardedMap[K, V]) shard(key K) *SafeMap[K, V] {
+	m.init(0)
+
+	return m.shards[m.hasher(key)%uint64(len(m.shards))]
+}
+
+// defaultHasher returns a hasher that hashes strings, numbers and booleans directly
+// and other keys by their fields using reflection, so keys that are == (e.g. 0.0 and -0.0) always have equal hashes.
+func defaultHasher[K comparable](seed maphash.Seed) Hasher[K] {
+	return func(key K) uint64 {
+		switch k := any(key).(type) {
+		case string:
+			return maphash.String(seed, k)
+		case int:
+			return hashUint64(seed, uint64(k))
+		case int8:
+			return hashUint64(seed, uint64(k))
+		case int16:
+			return hashUint64(seed, uint64(k))
+		case int32:
+			return hashUint64(seed, uint64(k))
+		case int64:
+			return hashUint64(seed, uint64(k))
+		case uint:
+			return hashUint64(seed, uint64(k))
+		case uint8:
+			return hashUint64(seed, uint64(k))
+		case uint16:
+			return hashUint64(seed, uint64(k))
+		case uint32:
+			return hashUint64(seed, uint64(k))
+		case uint64:
+			return hashUint64(seed, k)
+		case uintptr:
+			return hashUint64(seed, uint64(k))
+		case float32:
+			return hashUint64(seed, floatBits(float64(k)))
+		case float64:
+			return hashUint64(seed, floatBits(k))
+		case bool:
+			if k {
+				return hashUint64(seed, 1)
+			}
+			return hashUint64(seed, 0)
+		}
+
+		var h maphash.Hash
+		h.SetSeed(seed)
+		writeHashValue(&h, reflect.ValueOf(key))
+		return h.Sum64()
+	}
+}
+
+func hashUint64(seed maphash.Seed, v uint64) uint64 {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return maphash.Bytes(seed, buf[:])
+}
+
+// floatBits returns the bits of f with -0 replaced by +0, because they are equal.
+func floatBits(f float64) uint64 {
+	if f == 0 {
+		return 0
+	}
+	return math.Float64bits(f)
+}
+
+// writeHashValue writes v to h so that values that are == produce the same bytes.
+// Blank struct fields are skipped because they are ignored by ==.
+func writeHashValue(h *maphash.Hash, v reflect.Value) {
+	var buf [8]byte
+	switch v.Kind() {
+	case reflect.String:
+		h.WriteString(v.String())
+		return
+	case reflect.Bool:
+		if v.Bool() {
+			buf[0] = 1
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		binary.LittleEndian.PutUint64(buf[:], uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		binary.LittleEndian.PutUint64(buf[:], v.Uint())
+	case reflect.Float32, reflect.Float64:
+		binary.LittleEndian.PutUint64(buf[:], floatBits(v.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		binary.LittleEndian.PutUint64(buf[:], floatBits(real(c)))
+		h.Write(buf[:])
+		binary.LittleEndian.PutUint64(buf[:], floatBits(imag(c)))
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		binary.LittleEndian.PutUint64(buf[:], uint64(v.Pointer()))
+	case reflect.Interface:
+		writeHashValue(h, v.Elem())
+		return
+	case reflect.Array:
+		for i := range v.Len() {
+			writeHashValue(h, v.Index(i))
+		}
+		return
+	case reflect.Struct:
+		for i := range v.NumField() {
+			if v.Type().Field(i).Name != "_" {
+				writeHashValue(h, v.Field(i))
+			}
+		}
+		return
+	}
+	// Scalars are written as 8 bytes, invalid values (nil interfaces) as zeros
+	h.Write(buf[:])
+}
+
+// LazyExpiringMap is a map where every entry expires after its TTL.
+// Expiry is lazy: expired entries are skipped by reads and removed when they are found,
+// [LazyExpiringMap.Purge] removes all expired entries at once. It does not start any goroutines,
+// so it does not need to be closed.
+// It is safe for concurrent/parallel use.
+type LazyExpiringMap[K comparable, V any] struct {
+	items map[K]expiringEntry[V]
+	ttl   time.Duration
+	mu    sync.RWMutex
+}
+
+// expiringEntry is a value of [LazyExpiringMap] with its expiry time, zero time means it never expires.
+type expiringEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// expired returns true if the entry has expired at now.
+func (e expiringEntry[V]) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// NewLazyExpiringMap returns a new [LazyExpiringMap] with the default TTL used by [LazyExpiringMap.Set].
+// If ttl is not positive, entries set without TTL never expire.
+func NewLazyExpiringMap[K comparable, V any](ttl time.Duration) *LazyExpiringMap[K, V] {
+	return &LazyExpiringMap[K, V]{
+		items: make(map[K]expiringEntry[V]),
+		ttl:   ttl,
+	}
+}
+
+// Set sets the value for the key with the default TTL of the map.
+// It is safe for concurrent/parallel use.
+func (m *LazyExpiringMap[K, V]) Set(key K, value V) {
+	m.SetWithTTL(key, value, m.ttl)
+}
+
+// SetWithTTL sets the value for the key that expires after ttl, the entry never expires if ttl is not positive.
+// It is safe for concurrent/parallel use.
+func (m *LazyExpiringMap[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	entry := expiringEntry[V]{value: value}
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.items == nil {
+		m.items = make(map[K]expiringEntry[V])
+	}
+	m.items[key] = entry
+}
+
+// Get returns the value for the key or the default type value if the key is not present or has expired.
+// It is safe for concurrent/parallel use.
+func (m *LazyExpiringMap[K, V]) Get(key K) V {
+	v, _ := m.Lookup(key)
+	return v
+}
+
+// Lookup returns the value for the key and true if the key is present and has not expired.
+// An expired entry is removed from the map. It is safe for concurrent/parallel use.
+func (m *LazyExpiringMap[K, V]) Lookup(key K) (V, bool) {
+	now := time.Now()
+
+	m.mu.RLock()
+	entry, ok := m.items[key]
+	m.mu.RUnlock()
+
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	if !entry.expired(now) {
+		return entry.value, true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The entry may have been replaced while the lock was released
+	if entry, ok := m.items[key]; ok && entry.expired(now) {
+		delete(m.items, key)
+	}
+	var zero V
+	return zero, false
+}
+
+// Has returns true if the key is present and has not expired. It is safe for concurrent/parallel use.
+func (m *LazyExpiringMap[K, V]) Has(key K) bool {
+	_, ok := m.Lookup(key)
+	return ok
+}
+
+// ExpiresAt returns the expiry time of the entry with the key and true if it is present and has not expired.
+// The returned time is zero if the entry never expires. It is safe for concurrent/parallel use.
+func (m *LazyExpiringMap[K, V]) ExpiresAt(key K) (time.Time, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entry, ok := m.items[key]
+	if !ok || entry.expired(time.Now()) {
+		return time.Time{}, false
+	}
+	return entry.expiresAt, true
+}
+
+// Delete removes the key from the map, returns true if the key was present and had not expired.
+// It is safe for concurrent/parallel use.
+func (m *LazyExpiringMap[K, V]) Delete(key K) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.items[key]
+	if !ok {
+		return false
+	}
+	delete(m.items, key)
+	return !entry.expired(time.Now())
+}
+
+// Len returns the number of entries that have not expired.
+// It iterates over all entries, so it takes O(n). It is safe for concurrent/parallel use.
+func (m *LazyExpiringMap[K, V]) Len() int {
+	now := time.Now()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var n int
+	for _, entry := range m.items {
+		if !entry.expired(now) {
+			n++
+		}
+	}
+	return n
+}
+
+// Copy returns a new map with the entries that have not expired. It is safe for concurrent/parallel use.
+func (m *LazyExpiringMap[K, V]) Copy() map[K]V {
+	now := time.Now()
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := make(map[K]V, len(m.items))
+	for k, entry := range m.items {
+		if !entry.expired(now) {
+			out[k] = entry.value
+		}
+	}
+	return out
+}
+
+// Purge removes all expired entries from the map and returns the number of removed entries.
+// It is safe for concurrent/parallel use.
+func (m *LazyExpiringMap[K, V]) Purge() int {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	before := len(m.items)
+	maps.DeleteFunc(m.items, func(_ K, entry expiringEntry[V]) bool { return entry.expired(now) })
+	return before - len(m.items)
+}
+
+// Clear removes all entries from the map. It is safe for concurrent/parallel use.
+func (m *LazyExpiringMap[K, V]) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.items = make(map[K]expiringEntry[V])
+}
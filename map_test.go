@@ -1,9 +1,15 @@
 package abstract_test
 
 import (
+	"math"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/maxbolgarin/abstract"
 )
@@ -3528,3 +3534,2259 @@ func TestSafeMapOfMaps_UninitializedMethods(t *testing.T) {
 		t.Errorf("Expected 1.1 after Refill on uninitialized map, got %f", m27.Get("group", 1))
 	}
 }
+
+func TestMapOfMaps_Filter(t *testing.T) {
+	m := abstract.NewMapOfMaps[string, int, float64]()
+	m.Set("users", 1, 10.5)
+	m.Set("users", 2, 20.7)
+	m.Set("products", 100, 99.99)
+	m.Set("orders", 5, 1.5)
+
+	filtered := m.Filter(func(outerKey string, innerKey int, value float64) bool {
+		return value > 15
+	})
+	if len(filtered) != 2 {
+		t.Fatalf("Expected 2 outer keys, got %d", len(filtered))
+	}
+	if len(filtered["users"]) != 1 || filtered["users"][2] != 20.7 {
+		t.Errorf("Expected users to contain only 2 -> 20.7, got %v", filtered["users"])
+	}
+	if _, ok := filtered["orders"]; ok {
+		t.Error("Expected empty inner map to be dropped")
+	}
+
+	// Result must be independent from the original
+	filtered["users"][2] = 0
+	if m.Get("users", 2) != 20.7 {
+		t.Error("Expected original to be unchanged after modifying filtered map")
+	}
+
+	into := m.FilterInto(func(outerKey string, innerKey int, value float64) bool {
+		return outerKey == "users"
+	})
+	if into.OuterLen() != 1 || into.Len() != 2 {
+		t.Errorf("Expected 1 outer key and 2 items, got %d and %d", into.OuterLen(), into.Len())
+	}
+	into.Set("users", 3, 1)
+	if m.Has("users", 3) {
+		t.Error("Expected original to be unchanged after modifying filtered map")
+	}
+
+	empty := m.Filter(func(string, int, float64) bool { return false })
+	if len(empty) != 0 {
+		t.Errorf("Expected empty result, got %v", empty)
+	}
+}
+
+func TestSafeMapOfMaps_Filter(t *testing.T) {
+	m := abstract.NewSafeMapOfMaps[string, int, float64]()
+	m.Set("users", 1, 10.5)
+	m.Set("users", 2, 20.7)
+	m.Set("products", 100, 99.99)
+
+	filtered := m.Filter(func(outerKey string, innerKey int, value float64) bool {
+		return innerKey < 100
+	})
+	if len(filtered) != 1 || len(filtered["users"]) != 2 {
+		t.Errorf("Expected only users with 2 items, got %v", filtered)
+	}
+	filtered["users"][1] = 0
+	if m.Get("users", 1) != 10.5 {
+		t.Error("Expected original to be unchanged after modifying filtered map")
+	}
+
+	into := m.FilterInto(func(outerKey string, innerKey int, value float64) bool {
+		return value > 50
+	})
+	if into.Len() != 1 || into.Get("products", 100) != 99.99 {
+		t.Errorf("Expected only products, got %v", into.Raw())
+	}
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			m.Set("group", i, float64(i))
+		}()
+		go func() {
+			defer wg.Done()
+			m.Filter(func(string, int, float64) bool { return true })
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSafeMap_Version(t *testing.T) {
+	m := abstract.NewSafeMap[string, int]()
+	if m.Version() != 0 {
+		t.Errorf("Expected initial version 0, got %d", m.Version())
+	}
+
+	m.Set("a", 1)
+	v1 := m.Version()
+	if v1 == 0 {
+		t.Error("Expected version to be incremented after Set")
+	}
+
+	value, ok, version := m.GetWithVersion("a")
+	if !ok || value != 1 || version != v1 {
+		t.Errorf("Expected (1, true, %d), got (%d, %v, %d)", v1, value, ok, version)
+	}
+
+	// Read-only operations and no-op mutations must not change the version
+	m.Get("a")
+	m.Keys()
+	m.Delete("missing")
+	m.SetIfNotPresent("a", 2)
+	m.Pop("missing")
+	if m.Version() != v1 {
+		t.Errorf("Expected version %d after no-op operations, got %d", v1, m.Version())
+	}
+
+	mutations := []func(){
+		func() { m.SetIfNotPresent("b", 2) },
+		func() { m.Swap("a", 3) },
+		func() { m.Change("a", func(string, int) int { return 4 }) },
+		func() { m.Transform(func(_ string, v int) int { return v + 1 }) },
+		func() { m.Pop("b") },
+		func() { m.Delete("a") },
+		func() { m.Refill(map[string]int{"c": 1}) },
+		func() { m.Clear() },
+	}
+	last := m.Version()
+	for i, mutate := range mutations {
+		mutate()
+		if m.Version() <= last {
+			t.Errorf("Expected version to be incremented by mutation %d", i)
+		}
+		last = m.Version()
+	}
+
+	_, ok, version = m.GetWithVersion("a")
+	if ok || version != last {
+		t.Errorf("Expected (false, %d), got (%v, %d)", last, ok, version)
+	}
+}
+
+func TestSafeEntityMap_Version(t *testing.T) {
+	m := abstract.NewSafeEntityMap[int, *testEntity]()
+	m.Set(&testEntity{id: 1, name: "one"})
+	v1 := m.Version()
+	if v1 == 0 {
+		t.Error("Expected version to be incremented after Set")
+	}
+	m.Delete(1)
+	if m.Version() <= v1 {
+		t.Error("Expected version to be incremented after Delete")
+	}
+}
+
+func TestEntityMap_DeleteMany(t *testing.T) {
+	m := abstract.NewEntityMap[int, *testEntity]()
+	for i := range 10 {
+		m.Set(&testEntity{id: i, name: "Entity" + strconv.Itoa(i)})
+	}
+
+	if n := m.DeleteMany(1, 3, 3, 8, 42); n != 3 {
+		t.Errorf("Expected 3 deleted entities, got %d", n)
+	}
+	if m.Len() != 7 {
+		t.Fatalf("Expected 7 entities left, got %d", m.Len())
+	}
+
+	expected := []int{0, 2, 4, 5, 6, 7, 9}
+	for i, e := range m.AllOrdered() {
+		if e.GetID() != expected[i] {
+			t.Errorf("Expected entity %d at position %d, got %d", expected[i], i, e.GetID())
+		}
+		if e.GetOrder() != i {
+			t.Errorf("Expected entity %d to have order %d, got %d", e.GetID(), i, e.GetOrder())
+		}
+	}
+
+	if n := m.DeleteMany(100); n != 0 {
+		t.Errorf("Expected 0 deleted entities, got %d", n)
+	}
+	if m.NextOrder() != 7 {
+		t.Errorf("Expected next order 7, got %d", m.NextOrder())
+	}
+}
+
+func TestSafeEntityMap_DeleteMany(t *testing.T) {
+	m := abstract.NewSafeEntityMap[int, *testEntity]()
+	for i := range 5 {
+		m.Set(&testEntity{id: i, name: "Entity" + strconv.Itoa(i)})
+	}
+
+	if n := m.DeleteMany(0, 4); n != 2 {
+		t.Errorf("Expected 2 deleted entities, got %d", n)
+	}
+
+	for i, e := range m.AllOrdered() {
+		if e.GetID() != i+1 || e.GetOrder() != i {
+			t.Errorf("Expected entity %d with order %d, got %d with order %d", i+1, i, e.GetID(), e.GetOrder())
+		}
+	}
+}
+
+func TestMapAddAll(t *testing.T) {
+	m := abstract.NewMap(map[string]int{"alice": 10, "bob": 5})
+
+	totals := abstract.MapAddAll(m, map[string]int{"alice": 3, "carol": 7, "bob": -5})
+	expected := map[string]int{"alice": 13, "bob": 0, "carol": 7}
+	if !reflect.DeepEqual(totals, expected) {
+		t.Errorf("Expected totals %v, got %v", expected, totals)
+	}
+	if !reflect.DeepEqual(m.Raw(), expected) {
+		t.Errorf("Expected map %v, got %v", expected, m.Raw())
+	}
+
+	var empty abstract.Map[string, float64]
+	abstract.MapAddAll(&empty, map[string]float64{"x": 1.5})
+	if empty.Get("x") != 1.5 {
+		t.Errorf("Expected 1.5, got %v", empty.Get("x"))
+	}
+}
+
+func TestSafeMapAddAll(t *testing.T) {
+	m := abstract.NewSafeMap[string, int64]()
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			abstract.SafeMapAddAll(m, map[string]int64{"a": 1, "b": 2})
+		}()
+	}
+	wg.Wait()
+
+	if m.Get("a") != 50 || m.Get("b") != 100 {
+		t.Errorf("Expected a=50 b=100, got a=%d b=%d", m.Get("a"), m.Get("b"))
+	}
+
+	version := m.Version()
+	totals := abstract.SafeMapAddAll(m, map[string]int64{"a": -50})
+	if totals["a"] != 0 || len(totals) != 1 {
+		t.Errorf("Expected totals {a: 0}, got %v", totals)
+	}
+	if m.Version() == version {
+		t.Error("Expected version to be incremented")
+	}
+}
+
+func TestOrderedPairs_Filter(t *testing.T) {
+	pairs := abstract.NewOrderedPairs[string, int]("a", 1, "b", 2, "c", 3, "d", 4)
+
+	filtered := pairs.Filter(func(_ string, v int) bool { return v%2 == 0 })
+	if !reflect.DeepEqual(filtered.Keys(), []string{"b", "d"}) {
+		t.Errorf("Expected keys [b d], got %v", filtered.Keys())
+	}
+	if filtered.Get("d") != 4 || filtered.Get("a") != 0 {
+		t.Errorf("Unexpected values: d=%d a=%d", filtered.Get("d"), filtered.Get("a"))
+	}
+
+	filtered.Add("e", 5)
+	if len(pairs.Keys()) != 4 {
+		t.Error("Expected original to be unchanged after modifying filtered pairs")
+	}
+
+	empty := pairs.Filter(func(string, int) bool { return false })
+	if len(empty.Keys()) != 0 {
+		t.Errorf("Expected no keys, got %v", empty.Keys())
+	}
+}
+
+func TestMapOrderedPairs(t *testing.T) {
+	pairs := abstract.NewOrderedPairs[int, int](3, 30, 1, 10, 2, 20)
+
+	mapped := abstract.MapOrderedPairs(pairs, func(k, v int) string {
+		return strconv.Itoa(k) + ":" + strconv.Itoa(v)
+	})
+	if !reflect.DeepEqual(mapped.Keys(), []int{3, 1, 2}) {
+		t.Errorf("Expected keys [3 1 2], got %v", mapped.Keys())
+	}
+	if mapped.Get(1) != "1:10" {
+		t.Errorf("Expected 1:10, got %q", mapped.Get(1))
+	}
+}
+
+func TestSafeOrderedPairs_FilterAndMap(t *testing.T) {
+	pairs := abstract.NewSafeOrderedPairs[string, int]("a", 1, "b", 2, "c", 3)
+
+	filtered := pairs.Filter(func(k string, _ int) bool { return k != "b" })
+	if !reflect.DeepEqual(filtered.Keys(), []string{"a", "c"}) {
+		t.Errorf("Expected keys [a c], got %v", filtered.Keys())
+	}
+
+	mapped := abstract.MapSafeOrderedPairs(pairs, func(_ string, v int) float64 { return float64(v) / 2 })
+	if !reflect.DeepEqual(mapped.Keys(), []string{"a", "b", "c"}) {
+		t.Errorf("Expected keys [a b c], got %v", mapped.Keys())
+	}
+	if mapped.Get("c") != 1.5 {
+		t.Errorf("Expected 1.5, got %v", mapped.Get("c"))
+	}
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			pairs.Add(strconv.Itoa(i), i)
+		}()
+		go func() {
+			defer wg.Done()
+			pairs.Filter(func(string, int) bool { return true })
+		}()
+	}
+	wg.Wait()
+}
+
+func TestValueCounts(t *testing.T) {
+	m := abstract.NewMap(map[string]string{
+		"alice": "active",
+		"bob":   "banned",
+		"carol": "active",
+		"dave":  "active",
+		"eve":   "banned",
+		"frank": "pending",
+	})
+
+	expected := map[string]int{"active": 3, "banned": 2, "pending": 1}
+	if counts := abstract.ValueCounts(m); !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Expected %v, got %v", expected, counts)
+	}
+
+	value, count, ok := abstract.MostCommonValue(m)
+	if !ok || value != "active" || count != 3 {
+		t.Errorf("Expected (active, 3, true), got (%s, %d, %v)", value, count, ok)
+	}
+
+	_, _, ok = abstract.MostCommonValue(abstract.NewMap[string, int]())
+	if ok {
+		t.Error("Expected false for empty map")
+	}
+}
+
+func TestSafeMapValueCounts(t *testing.T) {
+	m := abstract.NewSafeMap(map[int]bool{1: true, 2: false, 3: true})
+
+	expected := map[bool]int{true: 2, false: 1}
+	if counts := abstract.SafeMapValueCounts(m); !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Expected %v, got %v", expected, counts)
+	}
+
+	value, count, ok := abstract.SafeMapMostCommonValue(m)
+	if !ok || !value || count != 2 {
+		t.Errorf("Expected (true, 2, true), got (%v, %d, %v)", value, count, ok)
+	}
+
+	_, _, ok = abstract.SafeMapMostCommonValue(abstract.NewSafeMap[int, bool]())
+	if ok {
+		t.Error("Expected false for empty map")
+	}
+}
+
+func TestSafeMap_RangeMutable(t *testing.T) {
+	m := abstract.NewSafeMap(map[string]int{"a": 1, "b": 2, "c": 3, "d": 4})
+
+	version := m.Version()
+	m.RangeMutable(func(_ string, ttl int) (int, bool) {
+		ttl--
+		return ttl, ttl > 1
+	})
+
+	expected := map[string]int{"c": 2, "d": 3}
+	if !reflect.DeepEqual(m.Copy(), expected) {
+		t.Errorf("Expected %v, got %v", expected, m.Copy())
+	}
+	if m.Version() == version {
+		t.Error("Expected version to be incremented")
+	}
+
+	var calls int
+	empty := abstract.NewSafeMap[string, int]()
+	empty.RangeMutable(func(string, int) (int, bool) {
+		calls++
+		return 0, true
+	})
+	if calls != 0 {
+		t.Errorf("Expected no calls for empty map, got %d", calls)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			m.Set(strconv.Itoa(i), i)
+		}()
+		go func() {
+			defer wg.Done()
+			m.RangeMutable(func(_ string, v int) (int, bool) { return v + 1, true })
+		}()
+	}
+	wg.Wait()
+}
+
+func TestMap_ReplaceAllAndAdopt(t *testing.T) {
+	m := abstract.NewMap(map[string]int{"a": 1})
+
+	newItems := map[string]int{"b": 2, "c": 3}
+	old := m.ReplaceAll(newItems)
+	if !reflect.DeepEqual(old, map[string]int{"a": 1}) {
+		t.Errorf("Expected previous map {a: 1}, got %v", old)
+	}
+	newItems["d"] = 4
+	if m.Has("d") || m.Len() != 2 {
+		t.Error("Expected ReplaceAll to copy the provided map")
+	}
+
+	adopted := map[string]int{"x": 10}
+	m.Adopt(adopted)
+	adopted["y"] = 20
+	if !m.Has("y") {
+		t.Error("Expected Adopt to take the provided map without copying")
+	}
+
+	m.Adopt(nil)
+	m.Set("z", 1)
+	if m.Len() != 1 {
+		t.Errorf("Expected 1 item after adopting nil map, got %d", m.Len())
+	}
+}
+
+func TestSafeMap_ReplaceAllAndAdopt(t *testing.T) {
+	m := abstract.NewSafeMap(map[string]int{"a": 1})
+
+	version := m.Version()
+	old := m.ReplaceAll(map[string]int{"b": 2})
+	if !reflect.DeepEqual(old, map[string]int{"a": 1}) {
+		t.Errorf("Expected previous map {a: 1}, got %v", old)
+	}
+	if m.Version() == version || m.Get("b") != 2 || m.Has("a") {
+		t.Errorf("Unexpected state after ReplaceAll: %v", m.Copy())
+	}
+
+	adopted := map[string]int{"x": 10}
+	m.Adopt(adopted)
+	if m.Get("x") != 10 {
+		t.Errorf("Expected adopted value, got %d", m.Get("x"))
+	}
+
+	m.Adopt(nil)
+	m.Set("z", 1)
+	if m.Len() != 1 {
+		t.Errorf("Expected 1 item after adopting nil map, got %d", m.Len())
+	}
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			m.ReplaceAll(map[string]int{strconv.Itoa(i): i})
+		}()
+		go func() {
+			defer wg.Done()
+			m.Get("1")
+		}()
+	}
+	wg.Wait()
+}
+
+func TestSortedMap(t *testing.T) {
+	m := abstract.NewSortedMap(map[int]string{5: "five", 1: "one", 3: "three"})
+	m.Set(7, "seven")
+	m.Set(3, "THREE")
+
+	if m.Len() != 4 {
+		t.Fatalf("expected len 4, got %d", m.Len())
+	}
+	if !reflect.DeepEqual(m.Keys(), []int{1, 3, 5, 7}) {
+		t.Errorf("unexpected keys: %v", m.Keys())
+	}
+	if !reflect.DeepEqual(m.Values(), []string{"one", "THREE", "five", "seven"}) {
+		t.Errorf("unexpected values: %v", m.Values())
+	}
+	if v, ok := m.Lookup(3); !ok || v != "THREE" {
+		t.Errorf("expected THREE, got %q %v", v, ok)
+	}
+	if _, ok := m.Lookup(4); ok {
+		t.Error("expected missing key 4")
+	}
+	if m.Get(5) != "five" || m.Get(4) != "" {
+		t.Errorf("unexpected Get results: %q %q", m.Get(5), m.Get(4))
+	}
+
+	if k, v, ok := m.Min(); !ok || k != 1 || v != "one" {
+		t.Errorf("unexpected min: %v %v %v", k, v, ok)
+	}
+	if k, v, ok := m.Max(); !ok || k != 7 || v != "seven" {
+		t.Errorf("unexpected max: %v %v %v", k, v, ok)
+	}
+
+	floorCases := []struct {
+		key  int
+		want int
+		ok   bool
+	}{{0, 0, false}, {1, 1, true}, {4, 3, true}, {5, 5, true}, {100, 7, true}}
+	for _, tc := range floorCases {
+		if k, _, ok := m.Floor(tc.key); ok != tc.ok || k != tc.want {
+			t.Errorf("Floor(%d) = %d %v, want %d %v", tc.key, k, ok, tc.want, tc.ok)
+		}
+	}
+	ceilCases := []struct {
+		key  int
+		want int
+		ok   bool
+	}{{0, 1, true}, {2, 3, true}, {5, 5, true}, {6, 7, true}, {8, 0, false}}
+	for _, tc := range ceilCases {
+		if k, _, ok := m.Ceil(tc.key); ok != tc.ok || k != tc.want {
+			t.Errorf("Ceil(%d) = %d %v, want %d %v", tc.key, k, ok, tc.want, tc.ok)
+		}
+	}
+
+	var ranged []int
+	m.Range(2, 7, func(k int, _ string) bool {
+		ranged = append(ranged, k)
+		return true
+	})
+	if !reflect.DeepEqual(ranged, []int{3, 5, 7}) {
+		t.Errorf("unexpected range: %v", ranged)
+	}
+	ranged = nil
+	m.Range(0, 10, func(k int, _ string) bool {
+		ranged = append(ranged, k)
+		return len(ranged) < 2
+	})
+	if !reflect.DeepEqual(ranged, []int{1, 3}) {
+		t.Errorf("expected range to stop early, got %v", ranged)
+	}
+	m.Range(7, 1, func(k int, _ string) bool {
+		t.Errorf("unexpected key %d in empty range", k)
+		return true
+	})
+
+	var iterated []int
+	for k := range m.Iter() {
+		iterated = append(iterated, k)
+	}
+	if !reflect.DeepEqual(iterated, []int{1, 3, 5, 7}) {
+		t.Errorf("unexpected iteration order: %v", iterated)
+	}
+
+	if !m.Delete(3) || m.Delete(3) {
+		t.Error("expected Delete to return true once")
+	}
+	if !reflect.DeepEqual(m.Keys(), []int{1, 5, 7}) {
+		t.Errorf("unexpected keys after delete: %v", m.Keys())
+	}
+	if !m.Delete(1, 4, 7) || m.Delete(4) || m.Delete() {
+		t.Error("expected Delete of several keys to return true only if some key existed")
+	}
+	if !reflect.DeepEqual(m.Keys(), []int{5}) || m.Get(5) != "five" {
+		t.Errorf("unexpected keys after deleting several: %v", m.Keys())
+	}
+
+	m.Clear()
+	if _, _, ok := m.Min(); ok {
+		t.Error("expected empty map after Clear")
+	}
+
+	merged := abstract.NewSortedMap(map[int]string{3: "c", 1: "a"}, nil, map[int]string{2: "b", 3: "C"})
+	if !reflect.DeepEqual(merged.Keys(), []int{1, 2, 3}) || !reflect.DeepEqual(merged.Values(), []string{"a", "b", "C"}) {
+		t.Errorf("expected all maps to be merged, got %v %v", merged.Keys(), merged.Values())
+	}
+
+	var zero abstract.SortedMap[string, int]
+	zero.Set("b", 2)
+	zero.Set("a", 1)
+	if !reflect.DeepEqual(zero.Keys(), []string{"a", "b"}) {
+		t.Errorf("unexpected keys of zero value map: %v", zero.Keys())
+	}
+}
+
+func TestSafeSortedMap(t *testing.T) {
+	m := abstract.NewSafeSortedMap[int, int]()
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 50 {
+				m.Set(i*50+j, j)
+				m.Get(j)
+				m.Floor(j)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if m.Len() != 500 {
+		t.Fatalf("expected len 500, got %d", m.Len())
+	}
+	prev := -1
+	for k := range m.Iter() {
+		if k <= prev {
+			t.Fatalf("keys are not sorted: %d after %d", k, prev)
+		}
+		prev = k
+		if k%2 == 1 {
+			m.Delete(k)
+		}
+	}
+	if m.Len() != 250 {
+		t.Errorf("expected len 250 after deleting in loop, got %d", m.Len())
+	}
+
+	var ranged []int
+	m.Range(10, 16, func(k, _ int) bool {
+		ranged = append(ranged, k)
+		m.Set(k+1, 0)
+		return true
+	})
+	if !reflect.DeepEqual(ranged, []int{10, 12, 14, 16}) {
+		t.Errorf("unexpected range: %v", ranged)
+	}
+	if k, _, ok := m.Ceil(11); !ok || k != 11 {
+		t.Errorf("expected key 11 set in Range, got %d %v", k, ok)
+	}
+	if k, _, ok := m.Max(); !ok || k != 498 {
+		t.Errorf("unexpected max: %d %v", k, ok)
+	}
+
+	if v, ok := m.Lookup(11); !ok || v != 0 || m.Get(12) != 12 {
+		t.Errorf("unexpected Lookup or Get results: %d %v %d", v, ok, m.Get(12))
+	}
+	if !m.Delete(10, 11, 1001) || m.Has(10) || m.Has(11) {
+		t.Error("expected Delete to remove several keys")
+	}
+
+	merged := abstract.NewSafeSortedMap(map[int]int{2: 2}, map[int]int{1: 1})
+	if !reflect.DeepEqual(merged.Keys(), []int{1, 2}) {
+		t.Errorf("expected all maps to be merged, got %v", merged.Keys())
+	}
+}
+
+func TestMapEntries(t *testing.T) {
+	m := abstract.NewMap(map[string]int{"c": 3, "a": 1, "b": 2})
+
+	entries := m.Entries()
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	for _, e := range entries {
+		if m.Get(e.Key) != e.Value {
+			t.Errorf("unexpected entry %+v", e)
+		}
+	}
+
+	sorted := abstract.SortedEntries(m)
+	expected := []abstract.Entry[string, int]{{Key: "a", Value: 1}, {Key: "b", Value: 2}, {Key: "c", Value: 3}}
+	if !reflect.DeepEqual(sorted, expected) {
+		t.Errorf("expected %v, got %v", expected, sorted)
+	}
+
+	restored := abstract.FromEntries(sorted)
+	if !reflect.DeepEqual(restored.Raw(), m.Raw()) {
+		t.Errorf("expected %v, got %v", m.Raw(), restored.Raw())
+	}
+
+	dup := abstract.FromEntries([]abstract.Entry[string, int]{{Key: "a", Value: 1}, {Key: "a", Value: 2}})
+	if dup.Len() != 1 || dup.Get("a") != 2 {
+		t.Errorf("expected last value to win, got %v", dup.Raw())
+	}
+
+	var empty abstract.Map[string, int]
+	if len(empty.Entries()) != 0 || abstract.FromEntries[string, int](nil).Len() != 0 {
+		t.Error("expected no entries for empty map")
+	}
+}
+
+func TestSafeMapEntries(t *testing.T) {
+	m := abstract.SafeMapFromEntries([]abstract.Entry[int, string]{{Key: 2, Value: "b"}, {Key: 1, Value: "a"}})
+	m.Set(3, "c")
+
+	if len(m.Entries()) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(m.Entries()))
+	}
+	expected := []abstract.Entry[int, string]{{Key: 1, Value: "a"}, {Key: 2, Value: "b"}, {Key: 3, Value: "c"}}
+	if sorted := abstract.SafeMapSortedEntries(m); !reflect.DeepEqual(sorted, expected) {
+		t.Errorf("expected %v, got %v", expected, sorted)
+	}
+}
+
+func TestMapKeySetValueSet(t *testing.T) {
+	a := abstract.NewMap(map[string]int{"x": 1, "y": 2, "z": 1})
+	b := abstract.NewSafeMap(map[string]int{"y": 5, "w": 5})
+
+	keys := a.KeySet()
+	if keys.Len() != 3 || !keys.Has("x") || !keys.Has("y") || !keys.Has("z") {
+		t.Errorf("unexpected key set: %v", keys.Raw())
+	}
+
+	onlyA := keys.Difference(b.KeySet().Raw())
+	if onlyA.Len() != 2 || !onlyA.Has("x") || !onlyA.Has("z") {
+		t.Errorf("unexpected difference: %v", onlyA.Raw())
+	}
+
+	keys.Add("new")
+	if a.Has("new") {
+		t.Error("expected key set to be independent from the map")
+	}
+
+	values := abstract.ValueSet(a)
+	if values.Len() != 2 || !values.Has(1) || !values.Has(2) {
+		t.Errorf("unexpected value set: %v", values.Raw())
+	}
+	safeValues := abstract.SafeMapValueSet(b)
+	if safeValues.Len() != 1 || !safeValues.Has(5) {
+		t.Errorf("unexpected value set: %v", safeValues.Raw())
+	}
+
+	var empty abstract.Map[string, int]
+	if empty.KeySet().Len() != 0 || abstract.ValueSet(&empty).Len() != 0 {
+		t.Error("expected empty sets for empty map")
+	}
+}
+
+func TestShardedMap(t *testing.T) {
+	m := abstract.NewShardedMap[string, int](8)
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 100 {
+				m.Set(strconv.Itoa(i*100+j), j)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if m.Len() != 800 {
+		t.Fatalf("expected len 800, got %d", m.Len())
+	}
+	if v, ok := m.Lookup("105"); !ok || v != 5 {
+		t.Errorf("expected 5, got %d %v", v, ok)
+	}
+	if !m.Has("0") || m.Has("missing") || m.Get("799") != 99 {
+		t.Error("unexpected lookup results")
+	}
+	if !m.Delete("0", "missing") || m.Delete("0") {
+		t.Error("unexpected Delete result")
+	}
+
+	stats := m.ShardStats()
+	if len(stats) != 8 {
+		t.Fatalf("expected 8 shards, got %d", len(stats))
+	}
+	total := 0
+	for _, n := range stats {
+		total += n
+		if n == 0 {
+			t.Errorf("expected keys in every shard, got %v", stats)
+		}
+	}
+	if total != 799 {
+		t.Errorf("expected 799 keys in shards, got %d", total)
+	}
+
+	count := 0
+	m.Range(func(string, int) bool {
+		count++
+		return true
+	})
+	if count != 799 {
+		t.Errorf("expected to range over 799 keys, got %d", count)
+	}
+}
+
+func TestShardedMapZeroValue(t *testing.T) {
+	var m abstract.ShardedMap[string, int]
+	if m.Len() != 0 || m.Has("a") {
+		t.Error("expected empty zero value map")
+	}
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Set(strconv.Itoa(i), i)
+		}()
+	}
+	wg.Wait()
+
+	if m.Len() != 10 || m.Get("7") != 7 {
+		t.Errorf("expected 10 keys in zero value map, got %d", m.Len())
+	}
+	if stats := m.ShardStats(); len(stats) != 16 {
+		t.Errorf("expected 16 shards, got %d", len(stats))
+	}
+}
+
+func TestShardedMapHasher(t *testing.T) {
+	type compositeKey struct {
+		tenant string
+		id     int
+	}
+
+	byID := abstract.NewShardedMap[compositeKey, string](4, func(k compositeKey) uint64 {
+		return uint64(k.id)
+	})
+	for i := range 8 {
+		byID.Set(compositeKey{tenant: "t", id: i}, "v")
+	}
+	if stats := byID.ShardStats(); !reflect.DeepEqual(stats, []int{2, 2, 2, 2}) {
+		t.Errorf("expected keys distributed by custom hasher, got %v", stats)
+	}
+	if byID.Get(compositeKey{tenant: "t", id: 3}) != "v" {
+		t.Error("expected to find key by custom hasher")
+	}
+
+	def := abstract.NewShardedMap[compositeKey, int](4)
+	for i := range 100 {
+		def.Set(compositeKey{tenant: "t" + strconv.Itoa(i%3), id: i}, i)
+	}
+	if def.Len() != 100 || def.Get(compositeKey{tenant: "t1", id: 4}) != 4 {
+		t.Error("unexpected values with default hasher")
+	}
+	for _, n := range def.ShardStats() {
+		if n == 0 {
+			t.Errorf("expected keys in every shard, got %v", def.ShardStats())
+		}
+	}
+
+	// Keys that are == must land in the same shard
+	floats := abstract.NewShardedMap[float64, string](64)
+	floats.Set(0.0, "zero")
+	if v, ok := floats.Lookup(math.Copysign(0, -1)); !ok || v != "zero" {
+		t.Errorf("expected -0 to find +0 entry, got %q, %v", v, ok)
+	}
+	type point struct {
+		x, y float64
+	}
+	points := abstract.NewShardedMap[point, int](64)
+	points.Set(point{x: 0, y: 1}, 1)
+	points.Set(point{x: math.Copysign(0, -1), y: 1}, 2)
+	if points.Len() != 1 || points.Get(point{y: 1}) != 2 {
+		t.Errorf("expected equal struct keys to be stored once, got len %d", points.Len())
+	}
+	type nested struct {
+		name  string
+		pair  [2]float64
+		value any
+	}
+	nestedKeys := abstract.NewShardedMap[nested, int](64)
+	nestedKeys.Set(nested{name: "a", pair: [2]float64{0, 1}, value: 0.0}, 1)
+	nestedKeys.Set(nested{name: "a", pair: [2]float64{math.Copysign(0, -1), 1}, value: math.Copysign(0, -1)}, 2)
+	nestedKeys.Set(nested{name: "b"}, 3)
+	if nestedKeys.Len() != 2 || nestedKeys.Get(nested{name: "a", pair: [2]float64{0, 1}, value: 0.0}) != 2 {
+		t.Errorf("expected equal nested keys to be stored once, got len %d", nestedKeys.Len())
+	}
+
+	ints := abstract.NewShardedMap[int, int](0)
+	if len(ints.ShardStats()) != 16 {
+		t.Errorf("expected 16 default shards, got %d", len(ints.ShardStats()))
+	}
+	for i := range 1000 {
+		ints.Set(i, i)
+	}
+	for _, n := range ints.ShardStats() {
+		if n == 0 {
+			t.Errorf("expected keys in every shard, got %v", ints.ShardStats())
+		}
+	}
+}
+
+func TestMapPartition(t *testing.T) {
+	m := abstract.NewMap(map[string]int{"a": 1, "b": 2, "c": 3, "d": 4})
+	isEven := func(_ string, v int) bool { return v%2 == 0 }
+
+	matched, rest := m.Partition(isEven)
+	if !reflect.DeepEqual(matched, map[string]int{"b": 2, "d": 4}) {
+		t.Errorf("unexpected matched: %v", matched)
+	}
+	if !reflect.DeepEqual(rest, map[string]int{"a": 1, "c": 3}) {
+		t.Errorf("unexpected rest: %v", rest)
+	}
+
+	matchedMap, restMap := m.PartitionInto(isEven)
+	if matchedMap.Len() != 2 || restMap.Len() != 2 || !matchedMap.Has("b") || !restMap.Has("a") {
+		t.Errorf("unexpected partition into maps: %v %v", matchedMap.Raw(), restMap.Raw())
+	}
+	matchedMap.Set("e", 6)
+	if m.Has("e") || m.Len() != 4 {
+		t.Error("expected partitions to be independent from the source map")
+	}
+
+	var empty abstract.Map[string, int]
+	matched, rest = empty.Partition(isEven)
+	if matched == nil || rest == nil || len(matched) != 0 || len(rest) != 0 {
+		t.Errorf("expected empty non-nil maps, got %v %v", matched, rest)
+	}
+}
+
+func TestSafeMapPartition(t *testing.T) {
+	m := abstract.NewSafeMap(map[int]string{1: "x", 2: "yy", 3: "zzz"})
+	isLong := func(_ int, v string) bool { return len(v) > 1 }
+
+	matched, rest := m.Partition(isLong)
+	if !reflect.DeepEqual(matched, map[int]string{2: "yy", 3: "zzz"}) || !reflect.DeepEqual(rest, map[int]string{1: "x"}) {
+		t.Errorf("unexpected partition: %v %v", matched, rest)
+	}
+
+	matchedMap, restMap := m.PartitionInto(isLong)
+	if matchedMap.Len() != 2 || restMap.Len() != 1 {
+		t.Errorf("unexpected partition into maps: %v %v", matchedMap.Raw(), restMap.Raw())
+	}
+}
+
+func TestSafeMapReplaceIf(t *testing.T) {
+	m := abstract.NewSafeMap(map[string]string{"order": "created"})
+	transition := func(from, to string) func(string, bool) (string, bool) {
+		return func(old string, exists bool) (string, bool) {
+			return to, exists && old == from
+		}
+	}
+
+	version := m.Version()
+	if v, ok := m.ReplaceIf("order", transition("created", "paid")); !ok || v != "paid" {
+		t.Errorf("expected transition to paid, got %q %v", v, ok)
+	}
+	if m.Version() == version {
+		t.Error("expected version to change after replace")
+	}
+
+	version = m.Version()
+	if v, ok := m.ReplaceIf("order", transition("created", "canceled")); ok || v != "paid" {
+		t.Errorf("expected no transition from paid, got %q %v", v, ok)
+	}
+	if m.Version() != version {
+		t.Error("expected version to stay the same without replace")
+	}
+
+	if v, ok := m.ReplaceIf("missing", transition("created", "paid")); ok || v != "" || m.Has("missing") {
+		t.Errorf("expected no value for missing key, got %q %v", v, ok)
+	}
+	if v, ok := m.ReplaceIf("new", func(_ string, exists bool) (string, bool) { return "created", !exists }); !ok || v != "created" {
+		t.Errorf("expected to insert new key, got %q %v", v, ok)
+	}
+
+	counter := abstract.NewSafeMap[string, int]()
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			counter.ReplaceIf("n", func(old int, _ bool) (int, bool) { return old + 1, old < 10 })
+		}()
+	}
+	wg.Wait()
+	if counter.Get("n") != 10 {
+		t.Errorf("expected counter to stop at 10, got %d", counter.Get("n"))
+	}
+}
+
+func TestMapSample(t *testing.T) {
+	items := make(map[int]string, 100)
+	for i := range 100 {
+		items[i] = strconv.Itoa(i)
+	}
+	m := abstract.NewMap(items)
+
+	sample := m.Sample(10)
+	if len(sample) != 10 {
+		t.Fatalf("expected 10 pairs, got %d", len(sample))
+	}
+	for k, v := range sample {
+		if items[k] != v {
+			t.Errorf("unexpected pair %d: %s", k, v)
+		}
+	}
+
+	keys := m.SampleKeys(30)
+	if len(keys) != 30 {
+		t.Fatalf("expected 30 keys, got %d", len(keys))
+	}
+	seen := make(map[int]bool)
+	for _, k := range keys {
+		if seen[k] || !m.Has(k) {
+			t.Errorf("unexpected or repeated key %d", k)
+		}
+		seen[k] = true
+	}
+
+	if len(m.Sample(1000)) != 100 || len(m.SampleKeys(1000)) != 100 {
+		t.Error("expected sample to be limited by map size")
+	}
+	if len(m.Sample(0)) != 0 || len(m.SampleKeys(-1)) != 0 {
+		t.Error("expected empty sample for non-positive n")
+	}
+
+	// Every key should be chosen at least once in many samples
+	hits := make(map[int]bool)
+	for range 200 {
+		for _, k := range m.SampleKeys(5) {
+			hits[k] = true
+		}
+	}
+	if len(hits) < 90 {
+		t.Errorf("expected sampling to cover most keys, got %d", len(hits))
+	}
+
+	var empty abstract.Map[int, string]
+	if len(empty.Sample(3)) != 0 {
+		t.Error("expected empty sample for empty map")
+	}
+}
+
+func TestSafeMapSample(t *testing.T) {
+	m := abstract.NewSafeMap(map[string]int{"a": 1, "b": 2, "c": 3})
+
+	sample := m.Sample(2)
+	if len(sample) != 2 {
+		t.Fatalf("expected 2 pairs, got %d", len(sample))
+	}
+	for k, v := range sample {
+		if m.Get(k) != v {
+			t.Errorf("unexpected pair %s: %d", k, v)
+		}
+	}
+	if len(m.SampleKeys(5)) != 3 {
+		t.Error("expected sample to be limited by map size")
+	}
+}
+
+func TestSafeMapCacheStats(t *testing.T) {
+	m := abstract.NewSafeMap(map[string]int{"a": 1})
+
+	m.Get("a")
+	m.Get("b")
+	if hits, misses := m.CacheStats(); hits != 0 || misses != 0 {
+		t.Errorf("expected no stats when disabled, got %d %d", hits, misses)
+	}
+
+	m.TrackStats(true)
+	m.Get("a")
+	m.Lookup("a")
+	m.Lookup("missing")
+	m.Has("a")
+	if hits, misses := m.CacheStats(); hits != 2 || misses != 1 {
+		t.Errorf("expected 2 hits and 1 miss, got %d %d", hits, misses)
+	}
+
+	m.TrackStats(true)
+	if hits, _ := m.CacheStats(); hits != 2 {
+		t.Errorf("expected enabling twice to keep counters, got %d hits", hits)
+	}
+
+	m.ResetStats()
+	if hits, misses := m.CacheStats(); hits != 0 || misses != 0 {
+		t.Errorf("expected zero stats after reset, got %d %d", hits, misses)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				m.Get(strconv.Itoa(i % 2))
+				m.Get("a")
+			}
+		}()
+	}
+	wg.Wait()
+	if hits, misses := m.CacheStats(); hits != 1000 || misses != 1000 {
+		t.Errorf("expected 1000 hits and misses, got %d %d", hits, misses)
+	}
+
+	m.TrackStats(false)
+	m.Get("a")
+	if hits, misses := m.CacheStats(); hits != 0 || misses != 0 {
+		t.Errorf("expected no stats after disabling, got %d %d", hits, misses)
+	}
+}
+
+func TestMapCopyInto(t *testing.T) {
+	m := abstract.NewMap(map[string]int{"a": 1, "b": 2})
+	dst := map[string]int{"stale": 0, "a": 100}
+
+	got := m.CopyInto(dst)
+	if !reflect.DeepEqual(got, map[string]int{"a": 1, "b": 2}) {
+		t.Errorf("unexpected copy: %v", got)
+	}
+	if !reflect.DeepEqual(dst, got) {
+		t.Error("expected CopyInto to fill the provided map")
+	}
+	dst["c"] = 3
+	if m.Has("c") {
+		t.Error("expected copy to be independent from the map")
+	}
+
+	if got := m.CopyInto(nil); !reflect.DeepEqual(got, map[string]int{"a": 1, "b": 2}) {
+		t.Errorf("unexpected copy into nil: %v", got)
+	}
+
+	var empty abstract.Map[string, int]
+	if got := empty.CopyInto(dst); len(got) != 0 {
+		t.Errorf("expected empty map, got %v", got)
+	}
+}
+
+func TestSafeMapCopyInto(t *testing.T) {
+	m := abstract.NewSafeMap(map[int]string{1: "a"})
+	dst := make(map[int]string, 10)
+
+	for i := range 3 {
+		m.Set(i+2, "x")
+		dst = m.CopyInto(dst)
+		if len(dst) != i+2 {
+			t.Errorf("expected %d pairs, got %d", i+2, len(dst))
+		}
+	}
+	if dst[1] != "a" {
+		t.Errorf("unexpected copy: %v", dst)
+	}
+}
+
+func TestEntityMap_OrderErrors(t *testing.T) {
+	m := abstract.NewEntityMap[int, *testEntity]()
+	m.Set(&testEntity{id: 1, name: "Entity1"})
+	m.Set(&testEntity{id: 2, name: "Entity2"})
+	if !m.IsOrderValid() {
+		t.Error("Expected order to be valid")
+	}
+	if errs := m.OrderErrors(); len(errs) != 0 {
+		t.Errorf("Expected no order errors, got %v", errs)
+	}
+
+	m.SetManualOrder(&testEntity{id: 3, name: "Entity3", order: 1})
+	m.SetManualOrder(&testEntity{id: 4, name: "Entity4", order: 10})
+	if m.IsOrderValid() {
+		t.Error("Expected order to be invalid")
+	}
+	errs := m.OrderErrors()
+	sort.Ints(errs[:2])
+	if !reflect.DeepEqual(errs, []int{2, 3, 4}) {
+		t.Errorf("Expected order errors [2 3 4], got %v", errs)
+	}
+}
+
+func TestSafeEntityMap_OrderErrors(t *testing.T) {
+	m := abstract.NewSafeEntityMap[int, *testEntity]()
+	m.Set(&testEntity{id: 1, name: "Entity1"})
+	if !m.IsOrderValid() {
+		t.Error("Expected order to be valid")
+	}
+	m.SetManualOrder(&testEntity{id: 2, name: "Entity2", order: -1})
+	if m.IsOrderValid() {
+		t.Error("Expected order to be invalid")
+	}
+	if errs := m.OrderErrors(); !reflect.DeepEqual(errs, []int{2}) {
+		t.Errorf("Expected order errors [2], got %v", errs)
+	}
+}
+
+func TestZipMap(t *testing.T) {
+	m, err := abstract.ZipMap([]string{"a", "b", "a"}, []int{1, 2, 3})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(m.Raw(), map[string]int{"a": 3, "b": 2}) {
+		t.Errorf("Unexpected map: %v", m.Raw())
+	}
+	if _, err := abstract.ZipMap([]string{"a"}, []int{1, 2}); err == nil {
+		t.Error("Expected error for length mismatch")
+	}
+
+	sm, err := abstract.SafeZipMap([]int{1, 2}, []string{"x", "y"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if sm.Len() != 2 || sm.Get(2) != "y" {
+		t.Errorf("Unexpected safe map: %v", sm.Raw())
+	}
+	if _, err := abstract.SafeZipMap([]int{1, 2}, []string{"x"}); err == nil {
+		t.Error("Expected error for length mismatch")
+	}
+
+	tm := abstract.ZipMapTrunc([]string{"a", "b", "c"}, []int{1, 2})
+	if !reflect.DeepEqual(tm.Raw(), map[string]int{"a": 1, "b": 2}) {
+		t.Errorf("Unexpected truncated map: %v", tm.Raw())
+	}
+	if abstract.ZipMapTrunc[string, int](nil, []int{1}).Len() != 0 {
+		t.Error("Expected empty map")
+	}
+}
+
+func TestMap_TransformFilter(t *testing.T) {
+	normalize := func(_ string, v int) (int, bool) {
+		return v * 2, v > 0
+	}
+
+	m := abstract.NewMap(map[string]int{"a": 1, "b": -1, "c": 3})
+	m.TransformFilter(normalize)
+	if !reflect.DeepEqual(m.Raw(), map[string]int{"a": 2, "c": 6}) {
+		t.Errorf("Unexpected map: %v", m.Raw())
+	}
+
+	sm := abstract.NewSafeMap(map[string]int{"a": 1, "b": 0})
+	sm.TransformFilter(normalize)
+	if !reflect.DeepEqual(sm.Raw(), map[string]int{"a": 2}) {
+		t.Errorf("Unexpected safe map: %v", sm.Raw())
+	}
+}
+
+func TestMapOfMaps_TransformFilter(t *testing.T) {
+	keepPositive := func(_ string, _ string, v int) (int, bool) {
+		return v + 1, v > 0
+	}
+
+	m := abstract.NewMapOfMaps(map[string]map[string]int{
+		"x": {"a": 1, "b": -1},
+		"y": {"c": 0},
+	})
+	m.TransformFilter(keepPositive)
+	if !reflect.DeepEqual(m.Raw(), map[string]map[string]int{"x": {"a": 2}}) {
+		t.Errorf("Unexpected map: %v", m.Raw())
+	}
+
+	sm := abstract.NewSafeMapOfMaps(map[string]map[string]int{
+		"x": {"a": -1},
+		"y": {"b": 5},
+	})
+	sm.TransformFilter(keepPositive)
+	if !reflect.DeepEqual(sm.Raw(), map[string]map[string]int{"y": {"b": 6}}) {
+		t.Errorf("Unexpected safe map: %v", sm.Raw())
+	}
+}
+
+func TestMap_KeysValuesWhere(t *testing.T) {
+	even := func(_ string, v int) bool { return v%2 == 0 }
+
+	m := abstract.NewMap(map[string]int{"a": 1, "b": 2, "c": 4})
+	keys := m.KeysWhere(even)
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, []string{"b", "c"}) {
+		t.Errorf("Expected keys [b c], got %v", keys)
+	}
+	values := m.ValuesWhere(even)
+	sort.Ints(values)
+	if !reflect.DeepEqual(values, []int{2, 4}) {
+		t.Errorf("Expected values [2 4], got %v", values)
+	}
+	if got := m.KeysWhere(func(string, int) bool { return false }); len(got) != 0 {
+		t.Errorf("Expected no keys, got %v", got)
+	}
+
+	sm := abstract.NewSafeMap(map[string]int{"a": 1, "b": 2})
+	if got := sm.KeysWhere(even); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("Expected keys [b], got %v", got)
+	}
+	if got := sm.ValuesWhere(even); !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("Expected values [2], got %v", got)
+	}
+}
+
+func TestMap_IntersectUnionKeys(t *testing.T) {
+	base := map[string]int{"a": 1, "b": 2, "c": 3}
+	override := map[string]int{"b": 20, "c": 30, "d": 40}
+	sum := func(_ string, a, b int) int { return a + b }
+
+	m := abstract.NewMap(base)
+	if got := m.IntersectKeys(override); !reflect.DeepEqual(got, map[string]int{"b": 2, "c": 3}) {
+		t.Errorf("Unexpected intersection: %v", got)
+	}
+	if got := m.UnionKeys(override, sum); !reflect.DeepEqual(got, map[string]int{"a": 1, "b": 22, "c": 33, "d": 40}) {
+		t.Errorf("Unexpected union: %v", got)
+	}
+	if got := m.UnionKeys(override, nil); !reflect.DeepEqual(got, map[string]int{"a": 1, "b": 2, "c": 3, "d": 40}) {
+		t.Errorf("Unexpected union without resolve: %v", got)
+	}
+	if m.Len() != 3 {
+		t.Error("Expected the map to be unchanged")
+	}
+
+	sm := abstract.NewSafeMap(base)
+	if got := sm.IntersectKeys(map[string]int{"a": 0, "x": 0}); !reflect.DeepEqual(got, map[string]int{"a": 1}) {
+		t.Errorf("Unexpected safe intersection: %v", got)
+	}
+	if got := sm.UnionKeys(map[string]int{"x": 9}, sum); len(got) != 4 || got["x"] != 9 {
+		t.Errorf("Unexpected safe union: %v", got)
+	}
+}
+
+func TestSafeMapDo(t *testing.T) {
+	m := abstract.NewSafeMap(map[string]int{"a": 1})
+	version := m.Version()
+
+	var wg sync.WaitGroup
+	for range 100 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			abstract.SafeMapDo(m, func(items map[string]int) int {
+				items["a"]++
+				return items["a"]
+			})
+		}()
+	}
+	wg.Wait()
+
+	total := abstract.SafeMapDo(m, func(items map[string]int) string {
+		items["b"] = items["a"] * 2
+		return strconv.Itoa(items["a"] + items["b"])
+	})
+	if total != "303" {
+		t.Errorf("Expected 303, got %s", total)
+	}
+	if m.Get("b") != 202 {
+		t.Errorf("Expected b to be 202, got %d", m.Get("b"))
+	}
+	if m.Version() <= version {
+		t.Error("Expected version to be increased")
+	}
+}
+
+func TestEntityMap_OrderMap(t *testing.T) {
+	m := abstract.NewEntityMap[int, *testEntity]()
+	for i := 1; i <= 3; i++ {
+		m.Set(&testEntity{id: i, name: "Entity" + strconv.Itoa(i)})
+	}
+	saved := m.OrderMap()
+	if !reflect.DeepEqual(saved, map[int]int{1: 0, 2: 1, 3: 2}) {
+		t.Errorf("Unexpected order map: %v", saved)
+	}
+
+	m.ChangeOrder(map[int]int{3: 0, 1: 1, 2: 2})
+	if err := m.RestoreOrder(saved); err != nil {
+		t.Fatalf("RestoreOrder failed: %v", err)
+	}
+	if !reflect.DeepEqual(m.OrderMap(), saved) {
+		t.Errorf("Expected restored order %v, got %v", saved, m.OrderMap())
+	}
+	if err := m.RestoreOrder(map[int]int{1: 2, 4: 0}); err == nil {
+		t.Error("Expected error for unknown entity")
+	}
+	if !reflect.DeepEqual(m.OrderMap(), saved) {
+		t.Error("Expected order to be unchanged after error")
+	}
+
+	sm := abstract.NewSafeEntityMap[int, *testEntity]()
+	sm.Set(&testEntity{id: 1, name: "Entity1"})
+	sm.Set(&testEntity{id: 2, name: "Entity2"})
+	if err := sm.RestoreOrder(map[int]int{2: 0, 1: 1}); err != nil {
+		t.Fatalf("RestoreOrder failed: %v", err)
+	}
+	if !reflect.DeepEqual(sm.OrderMap(), map[int]int{1: 1, 2: 0}) {
+		t.Errorf("Unexpected safe order map: %v", sm.OrderMap())
+	}
+	if err := sm.RestoreOrder(map[int]int{5: 0}); err == nil {
+		t.Error("Expected error for unknown entity")
+	}
+}
+
+func TestMap_EqualTo(t *testing.T) {
+	m := abstract.NewMap(map[string][]int{"a": {1, 2}, "b": nil})
+	eq := func(a, b []int) bool { return reflect.DeepEqual(a, b) }
+	if !m.EqualTo(map[string][]int{"a": {1, 2}, "b": nil}, eq) {
+		t.Error("Expected maps to be equal")
+	}
+	if m.EqualTo(map[string][]int{"a": {1, 2}}, eq) || m.EqualTo(map[string][]int{"a": {1}, "b": nil}, eq) {
+		t.Error("Expected maps to be not equal")
+	}
+
+	cm := abstract.NewMap(map[string]int{"a": 1})
+	if !abstract.EqualToComparable(cm, map[string]int{"a": 1}) || abstract.EqualToComparable(cm, map[string]int{"a": 2}) {
+		t.Error("Unexpected EqualToComparable result")
+	}
+
+	sm := abstract.NewSafeMap(map[string]int{"a": 1, "b": 2})
+	if !sm.EqualTo(map[string]int{"a": 1, "b": 2}, func(a, b int) bool { return a == b }) {
+		t.Error("Expected safe maps to be equal")
+	}
+	if !abstract.SafeMapEqualToComparable(sm, map[string]int{"b": 2, "a": 1}) || abstract.SafeMapEqualToComparable(sm, nil) {
+		t.Error("Unexpected SafeMapEqualToComparable result")
+	}
+	if !abstract.EqualToComparable(abstract.NewMap[string, int](), nil) {
+		t.Error("Expected empty map to be equal to nil map")
+	}
+}
+
+func TestSafeMapOfMaps_ReplaceAll(t *testing.T) {
+	m := abstract.NewSafeMapOfMaps(map[string]map[string]int{"old": {"a": 1}})
+
+	config := map[string]map[string]int{"db": {"port": 5432}, "cache": {"ttl": 60}}
+	old := m.ReplaceAll(config)
+	if !reflect.DeepEqual(old, map[string]map[string]int{"old": {"a": 1}}) {
+		t.Errorf("Expected previous items, got %v", old)
+	}
+	config["db"]["port"] = 1
+	if m.Get("db", "port") != 5432 || m.HasMap("old") {
+		t.Error("Expected replaced items to be a deep copy")
+	}
+
+	adopted := map[string]map[string]int{"x": {"y": 1}}
+	m.Adopt(adopted)
+	if m.Get("x", "y") != 1 || m.HasMap("db") {
+		t.Error("Expected adopted items")
+	}
+	m.Adopt(nil)
+	if m.Len() != 0 {
+		t.Error("Expected empty map after adopting nil")
+	}
+	m.Set("a", "b", 1)
+	if m.Get("a", "b") != 1 {
+		t.Error("Expected map to be usable after adopting nil")
+	}
+}
+
+func TestMaps_GetOr(t *testing.T) {
+	type getter interface {
+		GetOr(key string, def int) int
+		LookupOr(key string, def int) (int, bool)
+	}
+
+	sharded := abstract.NewShardedMap[string, int](4)
+	sharded.Set("a", 1)
+	maps := map[string]getter{
+		"Map":              abstract.NewMap(map[string]int{"a": 1}),
+		"SafeMap":          abstract.NewSafeMap(map[string]int{"a": 1}),
+		"OrderedPairs":     abstract.NewOrderedPairs[string, int]("a", 1),
+		"SafeOrderedPairs": abstract.NewSafeOrderedPairs[string, int]("a", 1),
+		"SortedMap":        abstract.NewSortedMap(map[string]int{"a": 1}),
+		"SafeSortedMap":    abstract.NewSafeSortedMap(map[string]int{"a": 1}),
+		"ShardedMap":       sharded,
+	}
+	for name, m := range maps {
+		if v := m.GetOr("a", 10); v != 1 {
+			t.Errorf("%s: expected 1, got %d", name, v)
+		}
+		if v := m.GetOr("b", 10); v != 10 {
+			t.Errorf("%s: expected default 10, got %d", name, v)
+		}
+		if v, ok := m.LookupOr("a", 10); !ok || v != 1 {
+			t.Errorf("%s: expected 1 and true, got %d and %v", name, v, ok)
+		}
+		if v, ok := m.LookupOr("b", 10); ok || v != 10 {
+			t.Errorf("%s: expected 10 and false, got %d and %v", name, v, ok)
+		}
+	}
+
+	mm := abstract.NewMapOfMaps(map[string]map[string]int{"x": {"a": 1}})
+	smm := abstract.NewSafeMapOfMaps(map[string]map[string]int{"x": {"a": 1}})
+	if mm.GetOr("x", "a", 5) != 1 || mm.GetOr("x", "b", 5) != 5 || smm.GetOr("y", "a", 5) != 5 {
+		t.Error("Unexpected GetOr result for map of maps")
+	}
+	if v, ok := smm.LookupOr("x", "a", 5); !ok || v != 1 {
+		t.Errorf("Expected 1 and true, got %d and %v", v, ok)
+	}
+	if v, ok := mm.LookupOr("y", "a", 5); ok || v != 5 {
+		t.Errorf("Expected 5 and false, got %d and %v", v, ok)
+	}
+}
+
+func TestOrderedPairs_RandWeighted(t *testing.T) {
+	pairs := abstract.NewOrderedPairs[string, int]("a", 1, "b", 2, "c", 3, "d", 4)
+	weight := func(k string, _ int) float64 {
+		switch k {
+		case "a":
+			return 1
+		case "b":
+			return 3
+		case "c":
+			return -5
+		}
+		return 0
+	}
+
+	counts := make(map[int]int)
+	const n = 4000
+	for range n {
+		counts[pairs.RandWeighted(weight)]++
+	}
+	if counts[3] != 0 || counts[4] != 0 {
+		t.Errorf("Expected pairs with non-positive weights to be skipped, got %v", counts)
+	}
+	if ratio := float64(counts[2]) / float64(counts[1]); ratio < 2 || ratio > 4.5 {
+		t.Errorf("Expected ratio of about 3, got %.2f (%v)", ratio, counts)
+	}
+
+	uniform := make(map[int]int)
+	for range 400 {
+		uniform[pairs.RandWeighted(func(string, int) float64 { return 0 })]++
+	}
+	if len(uniform) != 4 {
+		t.Errorf("Expected uniform fallback over all values, got %v", uniform)
+	}
+
+	safe := abstract.NewSafeOrderedPairs[string, int]("x", 10, "y", 20)
+	if v := safe.RandWeighted(func(k string, _ int) float64 {
+		if k == "y" {
+			return 1
+		}
+		return 0
+	}); v != 20 {
+		t.Errorf("Expected 20, got %d", v)
+	}
+	if v := abstract.NewOrderedPairs[string, int]().RandWeighted(weight); v != 0 {
+		t.Errorf("Expected zero value for empty pairs, got %d", v)
+	}
+}
+
+func TestSafeMapCompareAndSwapMany(t *testing.T) {
+	m := abstract.NewSafeMap(map[string]int{"a": 1, "b": 2, "c": 3})
+	version := m.Version()
+
+	if abstract.SafeMapCompareAndSwapMany(m, map[string]int{"a": 1, "b": 5}, map[string]int{"a": 10, "b": 20}) {
+		t.Error("Expected swap to fail on mismatched value")
+	}
+	if abstract.SafeMapCompareAndSwapMany(m, map[string]int{"a": 1, "x": 0}, map[string]int{"a": 10}) {
+		t.Error("Expected swap to fail on missing key")
+	}
+	if m.Get("a") != 1 || m.Get("b") != 2 || m.Version() != version {
+		t.Errorf("Expected map to be unchanged, got %v", m.Copy())
+	}
+
+	if !abstract.SafeMapCompareAndSwapMany(m, map[string]int{"a": 1, "b": 2}, map[string]int{"a": 10, "b": 20, "d": 4}) {
+		t.Error("Expected swap to succeed")
+	}
+	if m.Get("a") != 10 || m.Get("b") != 20 || m.Get("c") != 3 || m.Get("d") != 4 {
+		t.Errorf("Unexpected map after swap: %v", m.Copy())
+	}
+	if m.Version() == version {
+		t.Error("Expected version to change after swap")
+	}
+
+	s := abstract.NewSafeMap(map[string][]int{"a": {1, 2}})
+	eq := func(a, b []int) bool { return reflect.DeepEqual(a, b) }
+	if !s.CompareAndSwapMany(map[string][]int{"a": {1, 2}}, map[string][]int{"a": {3}}, eq) {
+		t.Error("Expected swap with eq func to succeed")
+	}
+	if got := s.Get("a"); len(got) != 1 || got[0] != 3 {
+		t.Errorf("Expected [3], got %v", got)
+	}
+}
+
+func TestMapCount(t *testing.T) {
+	even := func(_ string, v int) bool { return v%2 == 0 }
+
+	m := abstract.NewMap(map[string]int{"a": 1, "b": 2, "c": 4})
+	if n := m.Count(even); n != 2 {
+		t.Errorf("Expected 2, got %d", n)
+	}
+	if n := abstract.NewMap[string, int]().Count(even); n != 0 {
+		t.Errorf("Expected 0 for empty map, got %d", n)
+	}
+	var zero abstract.Map[string, int]
+	if n := zero.Count(even); n != 0 {
+		t.Errorf("Expected 0 for nil map, got %d", n)
+	}
+
+	calls := 0
+	m.Count(func(string, int) bool { calls++; return true })
+	if calls != m.Len() {
+		t.Errorf("Expected predicate to be called %d times, got %d", m.Len(), calls)
+	}
+
+	sm := abstract.NewSafeMap(map[string]int{"a": 1, "b": 2, "c": 4})
+	if n := sm.Count(even); n != 2 {
+		t.Errorf("Expected 2, got %d", n)
+	}
+	var zeroSafe abstract.SafeMap[string, int]
+	if n := zeroSafe.Count(even); n != 0 {
+		t.Errorf("Expected 0 for nil safe map, got %d", n)
+	}
+	calls = 0
+	sm.Count(func(string, int) bool { calls++; return false })
+	if calls != sm.Len() {
+		t.Errorf("Expected predicate to be called %d times, got %d", sm.Len(), calls)
+	}
+
+	mm := abstract.NewMapOfMaps[string, string, int]()
+	mm.Set("x", "a", 1)
+	mm.Set("x", "b", 2)
+	mm.Set("y", "a", 3)
+	odd := func(_, _ string, v int) bool { return v%2 == 1 }
+	if n := mm.Count(odd); n != 2 {
+		t.Errorf("Expected 2, got %d", n)
+	}
+
+	smm := abstract.NewSafeMapOfMaps[string, string, int]()
+	smm.Set("x", "a", 1)
+	smm.Set("y", "b", 3)
+	if n := smm.Count(func(k1, _ string, v int) bool { return k1 == "y" && v == 3 }); n != 1 {
+		t.Errorf("Expected 1, got %d", n)
+	}
+}
+
+func TestMapMerge(t *testing.T) {
+	m := abstract.NewMap[string, int]()
+	m.Merge()
+	m.Merge(nil, map[string]int{})
+	if m.Len() != 0 {
+		t.Errorf("Expected empty map, got %v", m.Raw())
+	}
+
+	m.Merge(map[string]int{"a": 1, "b": 2}, map[string]int{"b": 20, "c": 3})
+	expected := map[string]int{"a": 1, "b": 20, "c": 3}
+	if !reflect.DeepEqual(m.Raw(), expected) {
+		t.Errorf("Expected %v, got %v", expected, m.Raw())
+	}
+
+	m.MergeFrom(abstract.NewMap(map[string]int{"a": 10, "d": 4}))
+	m.MergeFrom(nil)
+	expected = map[string]int{"a": 10, "b": 20, "c": 3, "d": 4}
+	if !reflect.DeepEqual(m.Raw(), expected) {
+		t.Errorf("Expected %v, got %v", expected, m.Raw())
+	}
+}
+
+func TestSafeMapMerge(t *testing.T) {
+	m := abstract.NewSafeMap[string, int]()
+	m.Merge()
+	if m.Len() != 0 {
+		t.Errorf("Expected empty map, got %v", m.Copy())
+	}
+
+	m.Merge(map[string]int{"a": 1}, map[string]int{"a": 2, "b": 3})
+	if m.Get("a") != 2 || m.Get("b") != 3 {
+		t.Errorf("Unexpected map: %v", m.Copy())
+	}
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Merge(map[string]int{"k" + strconv.Itoa(i): i, "shared": i})
+		}()
+	}
+	wg.Wait()
+	if m.Len() != 53 {
+		t.Errorf("Expected 53 keys, got %d", m.Len())
+	}
+
+	a := abstract.NewSafeMap(map[string]int{"a": 1})
+	b := abstract.NewSafeMap(map[string]int{"b": 2})
+	wg.Add(2)
+	go func() { defer wg.Done(); a.MergeFrom(b) }()
+	go func() { defer wg.Done(); b.MergeFrom(a) }()
+	wg.Wait()
+	if !a.Has("b") || !b.Has("a") {
+		t.Errorf("Expected keys to be merged, got %v and %v", a.Copy(), b.Copy())
+	}
+}
+
+func TestMapMergeWith(t *testing.T) {
+	keepExisting := func(_ string, existing, _ int) int { return existing }
+	sum := func(_ string, existing, incoming int) int { return existing + incoming }
+
+	var m abstract.Map[string, int]
+	m.MergeWith(map[string]int{"a": 1}, sum)
+	if m.Get("a") != 1 {
+		t.Errorf("Expected 1 in map with nil underlying map, got %v", m.Raw())
+	}
+
+	m.MergeWith(map[string]int{"a": 10, "b": 2}, keepExisting)
+	if m.Get("a") != 1 || m.Get("b") != 2 {
+		t.Errorf("Expected existing value to be kept, got %v", m.Raw())
+	}
+	m.MergeWith(map[string]int{"a": 10, "c": 3}, sum)
+	if m.Get("a") != 11 || m.Get("c") != 3 {
+		t.Errorf("Expected values to be summed, got %v", m.Raw())
+	}
+	m.MergeWith(map[string]int{"a": 100}, nil)
+	if m.Get("a") != 100 {
+		t.Errorf("Expected nil onConflict to overwrite, got %v", m.Raw())
+	}
+	m.MergeWith(nil, sum)
+	if m.Len() != 3 {
+		t.Errorf("Expected 3 keys, got %v", m.Raw())
+	}
+
+	var sm abstract.SafeMap[string, int]
+	sm.MergeWith(map[string]int{"a": 1}, sum)
+	sm.MergeWith(map[string]int{"a": 2, "b": 5}, sum)
+	if sm.Get("a") != 3 || sm.Get("b") != 5 {
+		t.Errorf("Unexpected safe map: %v", sm.Copy())
+	}
+	sm.MergeWith(map[string]int{"b": 7}, nil)
+	if sm.Get("b") != 7 {
+		t.Errorf("Expected nil onConflict to overwrite, got %v", sm.Copy())
+	}
+}
+
+func TestMapFilter(t *testing.T) {
+	even := func(_ string, v int) bool { return v%2 == 0 }
+
+	m := abstract.NewMap(map[string]int{"a": 1, "b": 2, "c": 4})
+	filtered := m.Filter(even)
+	expected := map[string]int{"b": 2, "c": 4}
+	if !reflect.DeepEqual(filtered, expected) {
+		t.Errorf("Expected %v, got %v", expected, filtered)
+	}
+	filtered["b"] = 100
+	filtered["z"] = 0
+	if m.Len() != 3 || m.Get("b") != 2 || m.Has("z") {
+		t.Errorf("Expected original map to be untouched, got %v", m.Raw())
+	}
+
+	var empty abstract.Map[string, int]
+	if got := empty.Filter(even); got == nil || len(got) != 0 {
+		t.Errorf("Expected empty non-nil map, got %v", got)
+	}
+
+	sm := abstract.NewSafeMap(map[string]int{"a": 1, "b": 2})
+	if got := sm.Filter(even); !reflect.DeepEqual(got, map[string]int{"b": 2}) {
+		t.Errorf("Unexpected filtered safe map: %v", got)
+	}
+	var emptySafe abstract.SafeMap[string, int]
+	if got := emptySafe.Filter(even); got == nil {
+		t.Error("Expected non-nil map")
+	}
+}
+
+func TestMapFilterInto(t *testing.T) {
+	m := abstract.NewMap(map[string]int{"a": 1, "b": 2, "c": 3})
+
+	all := m.FilterInto(func(string, int) bool { return true })
+	if !reflect.DeepEqual(all.Raw(), m.Raw()) {
+		t.Errorf("Expected all entries, got %v", all.Raw())
+	}
+	all.Set("a", 100)
+	if m.Get("a") != 1 {
+		t.Error("Expected filtered map not to share storage with the original")
+	}
+
+	if none := m.FilterInto(func(string, int) bool { return false }); none.Len() != 0 {
+		t.Errorf("Expected no entries, got %v", none.Raw())
+	}
+
+	var empty abstract.Map[string, int]
+	if got := empty.FilterInto(func(string, int) bool { return true }); got.Len() != 0 {
+		t.Errorf("Expected empty map, got %v", got.Raw())
+	}
+
+	sm := abstract.NewSafeMap(map[string]int{"a": 1, "b": 2})
+	odd := sm.FilterInto(func(_ string, v int) bool { return v%2 == 1 })
+	if !reflect.DeepEqual(odd.Raw(), map[string]int{"a": 1}) {
+		t.Errorf("Unexpected filtered safe map: %v", odd.Raw())
+	}
+	odd.Set("a", 10)
+	if sm.Get("a") != 1 {
+		t.Error("Expected filtered map not to share storage with the safe map")
+	}
+}
+
+func TestMapFilterInPlace(t *testing.T) {
+	keepEven := func(_ int, v string) bool { return len(v)%2 == 0 }
+
+	m := abstract.NewMap[int, string]()
+	for i := range 100 {
+		m.Set(i, strings.Repeat("x", i))
+	}
+	m.FilterInPlace(keepEven)
+	if m.Len() != 50 {
+		t.Errorf("Expected 50 entries, got %d", m.Len())
+	}
+	for i := 0; i < 100; i += 2 {
+		if m.Get(i) != strings.Repeat("x", i) {
+			t.Errorf("Expected entry %d to be unchanged", i)
+		}
+	}
+
+	var empty abstract.Map[int, string]
+	empty.FilterInPlace(keepEven)
+	if empty.Len() != 0 {
+		t.Error("Expected empty map")
+	}
+
+	sm := abstract.NewSafeMap(map[int]string{1: "a", 2: "bb", 3: "ccc"})
+	version := sm.Version()
+	sm.FilterInPlace(func(int, string) bool { return true })
+	if sm.Version() != version {
+		t.Error("Expected version to be unchanged when nothing is deleted")
+	}
+	sm.FilterInPlace(keepEven)
+	if sm.Len() != 1 || sm.Get(2) != "bb" {
+		t.Errorf("Unexpected safe map: %v", sm.Copy())
+	}
+	if sm.Version() == version {
+		t.Error("Expected version to change")
+	}
+}
+
+func TestSafeEntityMap_AllOrderedCopy(t *testing.T) {
+	m := abstract.NewSafeEntityMap[int, *testEntity]()
+	m.Set(&testEntity{id: 1, name: "a"})
+	m.Set(&testEntity{id: 2, name: "b"})
+	m.Set(&testEntity{id: 3, name: "c"})
+
+	clone := func(e *testEntity) *testEntity {
+		c := *e
+		return &c
+	}
+	snapshot := m.AllOrderedCopy(clone)
+	aliased := m.AllOrdered()
+
+	m.ChangeOrder(map[int]int{1: 2, 2: 1, 3: 0})
+
+	for i, e := range snapshot {
+		if e.GetOrder() != i || e.GetID() != i+1 {
+			t.Errorf("Expected snapshot to be stable, got id %d with order %d at %d", e.GetID(), e.GetOrder(), i)
+		}
+	}
+	if aliased[0].GetOrder() == 0 {
+		t.Error("Expected AllOrdered values to alias the entities of the map")
+	}
+
+	plain := m.AllOrderedCopy()
+	if len(plain) != 3 || plain[0].GetID() != 3 || plain[2].GetID() != 1 {
+		t.Errorf("Unexpected order without clone: %v", plain)
+	}
+}
+
+func TestMapGetOrCompute(t *testing.T) {
+	var calls int
+	compute := func() int {
+		calls++
+		return 42
+	}
+
+	var m abstract.Map[string, int]
+	if v := m.GetOrCompute("a", compute); v != 42 || calls != 1 {
+		t.Errorf("Expected computed 42 with 1 call, got %d with %d calls", v, calls)
+	}
+	if m.Get("a") != 42 {
+		t.Error("Expected computed value to be stored")
+	}
+	if v := m.GetOrCompute("a", compute); v != 42 || calls != 1 {
+		t.Errorf("Expected compute not to be called on hit, got %d calls", calls)
+	}
+	m.Set("b", 0)
+	if v := m.GetOrCompute("b", compute); v != 0 || calls != 1 {
+		t.Errorf("Expected existing zero value, got %d with %d calls", v, calls)
+	}
+
+	sm := abstract.NewSafeMap[string, int]()
+	var safeCalls atomic.Int64
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v := sm.GetOrCompute("k", func() int { safeCalls.Add(1); return 7 }); v != 7 {
+				t.Errorf("Expected 7, got %d", v)
+			}
+		}()
+	}
+	wg.Wait()
+	if safeCalls.Load() != 1 {
+		t.Errorf("Expected compute to be called once, got %d", safeCalls.Load())
+	}
+}
+
+func TestMapReduce(t *testing.T) {
+	m := abstract.NewMap(map[string]int{"a": 1, "b": 2, "c": 3})
+	sum := abstract.MapReduce(m, 0, func(acc int, _ string, v int) int { return acc + v })
+	if sum != 6 {
+		t.Errorf("Expected 6, got %d", sum)
+	}
+
+	keys := abstract.MapReduce(m, []string{}, func(acc []string, k string, _ int) []string { return append(acc, k) })
+	sort.Strings(keys)
+	if joined := strings.Join(keys, ","); joined != "a,b,c" {
+		t.Errorf("Expected a,b,c, got %s", joined)
+	}
+
+	var empty abstract.Map[string, int]
+	if got := abstract.MapReduce(&empty, "init", func(string, string, int) string { return "changed" }); got != "init" {
+		t.Errorf("Expected initial value for empty map, got %s", got)
+	}
+
+	sm := abstract.NewSafeMap(map[string]int{"x": 10, "y": 20})
+	if got := abstract.SafeMapReduce(sm, 1, func(acc int, _ string, v int) int { return acc + v }); got != 31 {
+		t.Errorf("Expected 31, got %d", got)
+	}
+}
+
+func TestMapAnyAll(t *testing.T) {
+	positive := func(_ string, v int) bool { return v > 0 }
+	even := func(_ string, v int) bool { return v%2 == 0 }
+
+	var empty abstract.Map[string, int]
+	if empty.Any(positive) {
+		t.Error("Expected Any to be false for empty map")
+	}
+	if !empty.All(positive) {
+		t.Error("Expected All to be true for empty map")
+	}
+
+	m := abstract.NewMap(map[string]int{"a": 1, "b": 2, "c": 3})
+	if !m.Any(even) || m.All(even) {
+		t.Error("Expected some but not all values to be even")
+	}
+	if !m.All(positive) {
+		t.Error("Expected all values to be positive")
+	}
+
+	var calls int
+	m.Any(func(string, int) bool { calls++; return true })
+	if calls != 1 {
+		t.Errorf("Expected Any to stop at the first match, got %d calls", calls)
+	}
+	calls = 0
+	m.All(func(string, int) bool { calls++; return false })
+	if calls != 1 {
+		t.Errorf("Expected All to stop at the first mismatch, got %d calls", calls)
+	}
+
+	sm := abstract.NewSafeMap(map[string]int{"a": 2, "b": 4})
+	if !sm.All(even) || !sm.Any(even) {
+		t.Error("Expected all values of safe map to be even")
+	}
+	if sm.Any(func(_ string, v int) bool { return v > 10 }) {
+		t.Error("Expected no values greater than 10")
+	}
+	var emptySafe abstract.SafeMap[string, int]
+	if emptySafe.Any(positive) || !emptySafe.All(positive) {
+		t.Error("Unexpected result for empty safe map")
+	}
+}
+
+func TestMapValues(t *testing.T) {
+	m := abstract.NewMap(map[string]int{"a": 1, "b": 22})
+	out := abstract.MapValues(m, func(k string, v int) string { return k + "=" + strconv.Itoa(v) })
+	expected := map[string]string{"a": "a=1", "b": "b=22"}
+	if !reflect.DeepEqual(out.Raw(), expected) {
+		t.Errorf("Expected %v, got %v", expected, out.Raw())
+	}
+	if m.Len() != 2 || m.Get("b") != 22 {
+		t.Errorf("Expected source map to be untouched, got %v", m.Raw())
+	}
+
+	lengths := abstract.MapValues(out, func(_ string, v string) int { return len(v) })
+	if lengths.Get("a") != 3 || lengths.Get("b") != 4 {
+		t.Errorf("Unexpected lengths: %v", lengths.Raw())
+	}
+
+	if got := abstract.MapValues[string, int, string](nil, func(string, int) string { return "" }); got == nil || got.Len() != 0 {
+		t.Error("Expected empty map for nil source")
+	}
+	var empty abstract.Map[string, int]
+	got := abstract.MapValues(&empty, func(string, int) bool { return true })
+	got.Set("x", true)
+	if got.Len() != 1 {
+		t.Error("Expected usable empty map for empty source")
+	}
+}
+
+func TestMapOfMaps_Equal(t *testing.T) {
+	a := abstract.NewMapOfMaps(map[string]map[string]int{"x": {"a": 1, "b": 2}, "y": {"c": 3}})
+	b := abstract.NewMapOfMaps(map[string]map[string]int{"y": {"c": 3}, "x": {"b": 2, "a": 1}})
+	if !a.Equal(b) || !b.Equal(a) {
+		t.Error("Expected maps to be equal")
+	}
+
+	b.Set("y", "c", 4)
+	if a.Equal(b) {
+		t.Error("Expected maps with different values not to be equal")
+	}
+	if !a.EqualFunc(b, func(v1, v2 int) bool { return v1%2 == v2%2 || v1+1 == v2 }) {
+		t.Error("Expected maps to be equal according to eq")
+	}
+
+	c := abstract.NewMapOfMaps(map[string]map[string]int{"x": {"a": 1, "b": 2}, "y": {"c": 3}, "z": {}})
+	if a.Equal(c) {
+		t.Error("Expected empty inner map not to be equal to a missing key")
+	}
+	if a.Equal(nil) {
+		t.Error("Expected map not to be equal to nil")
+	}
+	if !abstract.NewMapOfMaps[string, string, int]().Equal(abstract.NewMapOfMaps[string, string, int]()) {
+		t.Error("Expected empty maps to be equal")
+	}
+
+	sa := abstract.NewSafeMapOfMaps(map[string]map[string]int{"x": {"a": 1}})
+	sb := abstract.NewSafeMapOfMaps(map[string]map[string]int{"x": {"a": 1}})
+	if !sa.Equal(sb) || !sa.Equal(sa) || sa.Equal(nil) {
+		t.Error("Unexpected result for safe maps")
+	}
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(3)
+		go func() { defer wg.Done(); sa.Equal(sb) }()
+		go func() { defer wg.Done(); sb.Equal(sa) }()
+		go func() { defer wg.Done(); sb.Set("x", "a", 1) }()
+	}
+	wg.Wait()
+
+	sb.Set("x", "b", 2)
+	if sa.Equal(sb) {
+		t.Error("Expected safe maps with different keys not to be equal")
+	}
+}
+
+func TestInvertMap(t *testing.T) {
+	inverted := abstract.InvertMap(map[string]int{"a": 1, "b": 2})
+	if !reflect.DeepEqual(inverted, map[int]string{1: "a", 2: "b"}) {
+		t.Errorf("Unexpected inverted map: %v", inverted)
+	}
+	if got := abstract.InvertMap[string, int](nil); got == nil || len(got) != 0 {
+		t.Errorf("Expected empty map, got %v", got)
+	}
+
+	dup := abstract.InvertMap(map[string]int{"a": 1, "b": 1, "c": 2})
+	if len(dup) != 2 || (dup[1] != "a" && dup[1] != "b") || dup[2] != "c" {
+		t.Errorf("Unexpected inverted map with duplicate values: %v", dup)
+	}
+
+	m := abstract.NewMap(map[string]int{"x": 10, "y": 20})
+	index := abstract.MapInvert(m)
+	if index.Get(10) != "x" || index.Get(20) != "y" || index.Len() != 2 {
+		t.Errorf("Unexpected inverted Map: %v", index.Raw())
+	}
+
+	sm := abstract.NewSafeMap(map[int]string{1: "one"})
+	if got := abstract.SafeMapInvert(sm); got.Get("one") != 1 {
+		t.Errorf("Unexpected inverted SafeMap: %v", got.Raw())
+	}
+}
+
+func TestMapCompact(t *testing.T) {
+	ints := abstract.NewMap(map[string]int{"a": 0, "b": 1, "c": 0})
+	if n := ints.Compact(); n != 2 || ints.Len() != 1 || ints.Get("b") != 1 {
+		t.Errorf("Expected 2 zeros to be removed, got %d, %v", n, ints.Raw())
+	}
+
+	strs := abstract.NewMap(map[int]string{1: "", 2: "x"})
+	if n := strs.Compact(); n != 1 || !strs.Has(2) {
+		t.Errorf("Expected empty string to be removed, got %d, %v", n, strs.Raw())
+	}
+
+	v := 0
+	ptrs := abstract.NewMap(map[string]*int{"nil": nil, "zero": &v})
+	if n := ptrs.Compact(); n != 1 || !ptrs.Has("zero") {
+		t.Errorf("Expected only nil pointer to be removed, got %d, %v", n, ptrs.Raw())
+	}
+
+	type point struct {
+		X, Y int
+		Tags []string
+	}
+	structs := abstract.NewMap(map[string]point{"zero": {}, "x": {X: 1}, "tags": {Tags: []string{}}})
+	if n := structs.Compact(); n != 1 || structs.Has("zero") || structs.Len() != 2 {
+		t.Errorf("Expected zero struct to be removed, got %d, %v", n, structs.Raw())
+	}
+
+	ifaces := abstract.NewMap(map[string]any{"nil": nil, "zero": 0})
+	if n := ifaces.Compact(); n != 1 || !ifaces.Has("zero") {
+		t.Errorf("Expected only nil interface to be removed, got %d, %v", n, ifaces.Raw())
+	}
+
+	var empty abstract.Map[string, int]
+	if n := empty.Compact(); n != 0 {
+		t.Errorf("Expected nothing to be removed, got %d", n)
+	}
+
+	sm := abstract.NewSafeMap(map[string]int{"a": 0, "b": 2})
+	version := sm.Version()
+	if n := sm.Compact(); n != 1 || sm.Len() != 1 || sm.Version() == version {
+		t.Errorf("Expected zero to be removed from safe map, got %d, %v", n, sm.Copy())
+	}
+	version = sm.Version()
+	if n := sm.Compact(); n != 0 || sm.Version() != version {
+		t.Error("Expected nothing to change")
+	}
+}
+
+func TestMapDiff(t *testing.T) {
+	onlyA, onlyB, both := abstract.MapDiff(map[string]int{"a": 1, "b": 2, "c": 3}, map[string]int{"b": 20, "c": 3, "d": 4})
+	if !reflect.DeepEqual(onlyA, map[string]int{"a": 1}) {
+		t.Errorf("Unexpected onlyInA: %v", onlyA)
+	}
+	if !reflect.DeepEqual(onlyB, map[string]int{"d": 4}) {
+		t.Errorf("Unexpected onlyInB: %v", onlyB)
+	}
+	if !reflect.DeepEqual(both, map[string][2]int{"b": {2, 20}, "c": {3, 3}}) {
+		t.Errorf("Unexpected both: %v", both)
+	}
+
+	onlyA, onlyB, both = abstract.MapDiff[string, int](nil, nil)
+	if onlyA == nil || onlyB == nil || both == nil || len(onlyA)+len(onlyB)+len(both) != 0 {
+		t.Error("Expected empty non-nil maps for nil inputs")
+	}
+
+	m := abstract.NewMap(map[string]int{"x": 1, "y": 2})
+	self, other, common := m.Diff(map[string]int{"y": 5, "z": 6})
+	if self.Len() != 1 || self.Get("x") != 1 || other.Len() != 1 || other.Get("z") != 6 {
+		t.Errorf("Unexpected diff: %v, %v", self.Raw(), other.Raw())
+	}
+	if common["y"] != [2]int{2, 5} || len(common) != 1 {
+		t.Errorf("Unexpected common values: %v", common)
+	}
+	self.Set("x", 100)
+	if m.Get("x") != 1 {
+		t.Error("Expected diff maps to be independent of the map")
+	}
+
+	sm := abstract.NewSafeMap(map[string]int{"x": 1})
+	self, other, common = sm.Diff(map[string]int{"x": 1})
+	if self.Len() != 0 || other.Len() != 0 || common["x"] != [2]int{1, 1} {
+		t.Errorf("Unexpected safe diff: %v, %v, %v", self.Raw(), other.Raw(), common)
+	}
+}
+
+func TestMapSortedKeys(t *testing.T) {
+	ints := abstract.NewMap(map[int]string{3: "c", 1: "a", 10: "j", -2: "z"})
+	if got := abstract.SortedKeysOrdered(ints); !reflect.DeepEqual(got, []int{-2, 1, 3, 10}) {
+		t.Errorf("Unexpected ordered keys: %v", got)
+	}
+	if got := ints.SortedKeys(func(a, b int) bool { return a > b }); !reflect.DeepEqual(got, []int{10, 3, 1, -2}) {
+		t.Errorf("Unexpected descending keys: %v", got)
+	}
+	if ints.Len() != 4 {
+		t.Error("Expected map to be unchanged")
+	}
+
+	strs := abstract.NewSafeMap(map[string]int{"bb": 1, "a": 2, "ccc": 3, "dd": 4})
+	if got := abstract.SafeMapSortedKeysOrdered(strs); !reflect.DeepEqual(got, []string{"a", "bb", "ccc", "dd"}) {
+		t.Errorf("Unexpected ordered keys: %v", got)
+	}
+
+	byLen := strs.SortedKeys(func(a, b string) bool { return len(a) < len(b) })
+	isSorted := sort.SliceIsSorted(byLen, func(i, j int) bool { return len(byLen[i]) < len(byLen[j]) })
+	if len(byLen) != 4 || byLen[0] != "a" || byLen[3] != "ccc" || !isSorted {
+		t.Errorf("Unexpected keys sorted by length: %v", byLen)
+	}
+
+	var empty abstract.Map[string, int]
+	if got := empty.SortedKeys(func(a, b string) bool { return a < b }); len(got) != 0 {
+		t.Errorf("Expected no keys, got %v", got)
+	}
+}
+
+func TestLazyExpiringMap(t *testing.T) {
+	m := abstract.NewLazyExpiringMap[string, int](30 * time.Millisecond)
+	m.Set("short", 1)
+	m.SetWithTTL("long", 2, time.Hour)
+	m.SetWithTTL("forever", 3, 0)
+
+	if m.Get("short") != 1 || !m.Has("long") || m.Len() != 3 {
+		t.Errorf("Expected all entries to be present, got %v", m.Copy())
+	}
+	if at, ok := m.ExpiresAt("forever"); !ok || !at.IsZero() {
+		t.Errorf("Expected entry without expiry, got %v, %v", at, ok)
+	}
+	if at, ok := m.ExpiresAt("long"); !ok || time.Until(at) < 59*time.Minute {
+		t.Errorf("Unexpected expiry time %v", at)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if v, ok := m.Lookup("short"); ok || v != 0 {
+		t.Errorf("Expected expired entry to be skipped, got %d", v)
+	}
+	if _, ok := m.ExpiresAt("short"); ok {
+		t.Error("Expected no expiry time for expired entry")
+	}
+	if m.Len() != 2 || !reflect.DeepEqual(m.Copy(), map[string]int{"long": 2, "forever": 3}) {
+		t.Errorf("Unexpected entries after expiry: %v", m.Copy())
+	}
+
+	m.SetWithTTL("a", 1, time.Millisecond)
+	m.SetWithTTL("b", 2, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	if n := m.Purge(); n != 2 {
+		t.Errorf("Expected 2 purged entries, got %d", n)
+	}
+	if n := m.Purge(); n != 0 {
+		t.Errorf("Expected nothing to purge, got %d", n)
+	}
+
+	m.SetWithTTL("expired", 1, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	if m.Delete("expired") {
+		t.Error("Expected delete of expired entry to return false")
+	}
+	if !m.Delete("long") || m.Has("long") {
+		t.Error("Expected entry to be deleted")
+	}
+
+	m.Set("short", 10)
+	if m.Get("short") != 10 {
+		t.Error("Expected expired key to be set again")
+	}
+	m.Clear()
+	if m.Len() != 0 {
+		t.Error("Expected empty map after clear")
+	}
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := strconv.Itoa(i % 5)
+			m.SetWithTTL(key, i, time.Millisecond)
+			m.Get(key)
+			m.Purge()
+		}()
+	}
+	wg.Wait()
+}
+
+func TestMapEqual(t *testing.T) {
+	m := abstract.NewMap(map[string][]int{"a": {1, 2}, "b": nil})
+	if !m.Equal(map[string][]int{"a": {1, 2}, "b": nil}) {
+		t.Error("Expected maps to be deeply equal")
+	}
+	if m.Equal(map[string][]int{"a": {1, 2}}) {
+		t.Error("Expected maps with different keys not to be equal")
+	}
+	if m.Equal(map[string][]int{"a": {2, 1}, "b": nil}) {
+		t.Error("Expected maps with different values not to be equal")
+	}
+	if m.Equal(map[string][]int{"a": {1, 2}, "c": nil}) {
+		t.Error("Expected maps with different key sets not to be equal")
+	}
+
+	var empty abstract.Map[string, int]
+	if !empty.Equal(nil) || !empty.Equal(map[string]int{}) {
+		t.Error("Expected empty map to be equal to nil and empty maps")
+	}
+
+	type point struct{ X, Y int }
+	sm := abstract.NewSafeMap(map[int]point{1: {1, 2}})
+	if !sm.Equal(map[int]point{1: {1, 2}}) || sm.Equal(map[int]point{1: {2, 1}}) {
+		t.Error("Unexpected result for safe map")
+	}
+}
+
+func TestMapEqualFunc(t *testing.T) {
+	sameLen := func(a, b []int) bool { return len(a) == len(b) }
+	m := abstract.NewMap(map[string][]int{"a": {1, 2}, "b": nil})
+	if !m.EqualFunc(map[string][]int{"a": {3, 4}, "b": {}}, sameLen) {
+		t.Error("Expected maps to be equal according to eq")
+	}
+	if m.EqualFunc(map[string][]int{"a": {1}, "b": nil}, sameLen) || m.EqualFunc(map[string][]int{"a": {1, 2}}, sameLen) {
+		t.Error("Expected maps with different values or keys not to be equal")
+	}
+
+	sm := abstract.NewSafeMap(map[string]int{"a": 1, "b": 2})
+	if !sm.EqualFunc(map[string]int{"a": 1, "b": 2}, func(a, b int) bool { return a == b }) {
+		t.Error("Expected safe map to be equal")
+	}
+	if sm.EqualFunc(map[string]int{"a": 1, "b": 3}, func(a, b int) bool { return a == b }) {
+		t.Error("Expected safe maps with different values not to be equal")
+	}
+}
@@ -1,6 +1,7 @@
 package abstract
 
 import (
+	"encoding/json"
 	"iter"
 	"maps"
 	"sync"
@@ -220,6 +221,33 @@ func (m *Set[K]) SymmetricDifference(set map[K]struct{}) *Set[K] {
 	return out
 }
 
+// MarshalJSON encodes the set as a JSON array of its values in arbitrary order.
+func (m *Set[K]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(lang.Keys(m.items))
+}
+
+// UnmarshalJSON decodes a JSON array into the set replacing its values.
+func (m *Set[K]) UnmarshalJSON(data []byte) error {
+	items, err := unmarshalSetJSON[K](data)
+	if err != nil {
+		return err
+	}
+	m.items = items
+	return nil
+}
+
+func unmarshalSetJSON[K comparable](data []byte) (map[K]struct{}, error) {
+	var values []K
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	items := make(map[K]struct{}, len(values))
+	for _, v := range values {
+		items[v] = struct{}{}
+	}
+	return items, nil
+}
+
 // SafeSet is used like a set, but it is protected with RW mutex, so it can be used in many goroutines.
 type SafeSet[K comparable] struct {
 	items map[K]struct{}
@@ -549,3 +577,27 @@ func (m *SafeSet[K]) SymmetricDifference(set map[K]struct{}) *Set[K] {
 	}
 	return out
 }
+
+// MarshalJSON encodes the set as a JSON array of its values in arbitrary order.
+// It is safe for concurrent/parallel use.
+func (m *SafeSet[K]) MarshalJSON() ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return json.Marshal(lang.Keys(m.items))
+}
+
+// UnmarshalJSON decodes a JSON array into the set replacing its values.
+// It is safe for concurrent/parallel use.
+func (m *SafeSet[K]) UnmarshalJSON(data []byte) error {
+	items, err := unmarshalSetJSON[K](data)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.items = items
+	return nil
+}
@@ -1,6 +1,9 @@
 package abstract_test
 
 import (
+	"encoding/json"
+	"reflect"
+	"sort"
 	"sync"
 	"testing"
 
@@ -789,3 +792,47 @@ func TestSafeSet_NilMapBehavior(t *testing.T) {
 		t.Error("Expected Iter to yield no items for uninitialized safe set")
 	}
 }
+
+func TestSet_JSON(t *testing.T) {
+	s := abstract.NewSet([]string{"a", "b", "a"})
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded abstract.Set[string]
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded.Len() != 2 || !decoded.Has("a") || !decoded.Has("b") {
+		t.Errorf("Expected decoded set to have a and b, got %v", decoded.Values())
+	}
+
+	values := s.Values()
+	sort.Strings(values)
+	if roundTrip := abstract.NewSet(values); !reflect.DeepEqual(roundTrip.Raw(), s.Raw()) {
+		t.Errorf("Expected slice round-trip to keep the set, got %v", roundTrip.Values())
+	}
+
+	if data, _ := json.Marshal(abstract.NewSet[int]()); string(data) != "[]" {
+		t.Errorf("Expected empty JSON array, got %s", data)
+	}
+	if err := json.Unmarshal([]byte(`{"a":1}`), &decoded); err == nil {
+		t.Error("Expected error for non-array JSON")
+	}
+
+	cfg := struct {
+		Tags *abstract.SafeSet[int] `json:"tags"`
+	}{Tags: abstract.NewSafeSetFromItems(1, 2, 2)}
+	data, err = json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	cfg.Tags = nil
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if cfg.Tags.Len() != 2 || !cfg.Tags.Has(1) || !cfg.Tags.Has(2) {
+		t.Errorf("Expected decoded safe set to have 1 and 2, got %v", cfg.Tags.Values())
+	}
+}
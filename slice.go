@@ -325,6 +325,49 @@ func (s *SafeSlice[T]) Iter2() iter.Seq2[int, T] {
 	return slices.All(s.items)
 }
 
+// Filter returns a new slice with the elements of the slice for which keep returns true, preserving their order.
+func Filter[T any](slice []T, keep func(T) bool) []T {
+	var out []T
+	for _, v := range slice {
+		if keep(v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// MapSlice returns a new slice with the results of calling f for every element of the slice.
+// It is not called Map because of the [Map] type.
+func MapSlice[T, R any](slice []T, f func(T) R) []R {
+	out := make([]R, len(slice))
+	for i, v := range slice {
+		out[i] = f(v)
+	}
+	return out
+}
+
+// Reduce folds the elements of the slice into a single value, starting with initial
+// and calling f with the accumulator and every element in order.
+func Reduce[T, R any](slice []T, initial R, f func(R, T) R) R {
+	acc := initial
+	for _, v := range slice {
+		acc = f(acc, v)
+	}
+	return acc
+}
+
+// Find returns the first element of the slice for which match returns true.
+// It returns the zero value and false if there is no such element.
+func Find[T any](slice []T, match func(T) bool) (T, bool) {
+	for _, v := range slice {
+		if match(v) {
+			return v, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
 func getSlicesLen[T any](slices ...[]T) int {
 	var length int
 	for _, slice := range slices {
@@ -1,6 +1,8 @@
 package abstract_test
 
 import (
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 
@@ -350,3 +352,41 @@ func TestSafeSlice_Raw(t *testing.T) {
 		t.Errorf("expected length 3, got %d", len(raw))
 	}
 }
+
+func TestSliceFunctions(t *testing.T) {
+	nums := []int{1, 2, 3, 4, 5}
+
+	evens := abstract.Filter(nums, func(v int) bool { return v%2 == 0 })
+	if len(evens) != 2 || evens[0] != 2 || evens[1] != 4 {
+		t.Errorf("Expected [2 4], got %v", evens)
+	}
+	if none := abstract.Filter(nums, func(int) bool { return false }); len(none) != 0 {
+		t.Errorf("Expected empty slice, got %v", none)
+	}
+
+	strs := abstract.MapSlice(nums, strconv.Itoa)
+	if strings.Join(strs, "") != "12345" {
+		t.Errorf("Expected 12345, got %v", strs)
+	}
+	if empty := abstract.MapSlice([]int{}, strconv.Itoa); len(empty) != 0 {
+		t.Errorf("Expected empty slice, got %v", empty)
+	}
+
+	if sum := abstract.Reduce(nums, 0, func(acc, v int) int { return acc + v }); sum != 15 {
+		t.Errorf("Expected 15, got %d", sum)
+	}
+	joined := abstract.Reduce(nums, "", func(acc string, v int) string { return acc + strconv.Itoa(v) })
+	if joined != "12345" {
+		t.Errorf("Expected 12345, got %s", joined)
+	}
+	if got := abstract.Reduce(nil, 7, func(acc, v int) int { return acc + v }); got != 7 {
+		t.Errorf("Expected initial value for empty slice, got %d", got)
+	}
+
+	if v, ok := abstract.Find(nums, func(v int) bool { return v > 3 }); !ok || v != 4 {
+		t.Errorf("Expected 4, got %d, %v", v, ok)
+	}
+	if v, ok := abstract.Find(nums, func(v int) bool { return v > 10 }); ok || v != 0 {
+		t.Errorf("Expected no match, got %d, %v", v, ok)
+	}
+}
@@ -1,7 +1,11 @@
 package abstract
 
 import (
+	"cmp"
 	"context"
+	"hash/fnv"
+	"math"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -9,57 +13,265 @@ import (
 	"github.com/maxbolgarin/lang"
 )
 
-// Result represents the outcome of a task execution.
+// OverflowPolicy defines what [WorkerPoolV2.Submit] does when the task queue is full.
+type OverflowPolicy int32
+
+const (
+	// OverflowBlock blocks the caller until there is space in the queue or the timeout is reached.
+	// It is the default policy.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest rejects the submitted task immediately if the queue is full.
+	OverflowDropNewest
+	// OverflowDropOldest evicts the oldest queued task to make space for the submitted one.
+	OverflowDropOldest
+	// OverflowCallerRuns executes the submitted task in the caller goroutine if the queue is full.
+	// Tasks of [WorkerPoolWithState] are rejected instead, because the caller goroutine has no worker state.
+	OverflowCallerRuns
+)
+
+// TaskResult represents the outcome of a task execution.
+type TaskResult[T any] struct {
+	Value T
+	Err   error
+}
+
+// taskV2 is a task submitted to the pool with an optional caller's id and its submission sequence number.
+// Weight is the estimated cost of a task in the shared queue, it is zero for keyed tasks.
+// A task of the pool with worker-local state has fnWorker instead of fn, it gets the index of the worker
+// that executes it to find the state of this worker.
+// A task of [TypedWorkerPool] has neither, its input is kept in the inputs of the pool under the slot.
+type taskV2[T any] struct {
+	id       string
+	seq      uint64
+	weight   int
+	fn       func() (T, error)
+	fnWorker func(worker int) (T, error)
+	inputs   taskInputs[T]
+	slot     int
+}
+
+// taskInputs keeps typed inputs of queued tasks, so they pass through the queues
+// as slot numbers without a closure per input.
+type taskInputs[T any] interface {
+	// run takes the input from the slot and processes it.
+	run(slot int) (T, error)
+	// release frees the slot of an input that will not be processed.
+	release(slot int)
+}
+
+// callerWorker is the index of the worker passed to a task that is executed in the caller goroutine.
+const callerWorker = -1
+
+// isValid returns true if the task has a function to execute.
+func (t taskV2[T]) isValid() bool {
+	return t.fn != nil || t.fnWorker != nil || t.inputs != nil
+}
+
+// call executes the task in the worker with the provided index.
+func (t taskV2[T]) call(worker int) (T, error) {
+	switch {
+	case t.inputs != nil:
+		return t.inputs.run(t.slot)
+	case t.fnWorker != nil:
+		return t.fnWorker(worker)
+	}
+	return t.fn()
+}
+
+// discard frees the resources of a task that will not be executed.
+func (t taskV2[T]) discard() {
+	if t.inputs != nil {
+		t.inputs.release(t.slot)
+	}
+}
+
+// resultV2 represents the outcome of a task execution with the id and the sequence number of the task.
 type resultV2[T any] struct {
+	ID    string
+	Seq   uint64
 	Value T
 	Err   error
 }
 
 // WorkerPool manages a pool of workers that process tasks concurrently.
 type WorkerPoolV2[T any] struct {
-	workers    int
-	tasks      chan func() (T, error)
+	// mu protects workers and queues from being replaced by Restart while they are used
+	mu      sync.RWMutex
+	workers int
+	tasks   chan taskV2[T]
+	keyed   []chan taskV2[T]
+	quit    chan struct{}
+	// restarting is closed when a restart begins to reject submissions until the queues are replaced;
+	// restartMu serializes restarts
+	restarting chan struct{}
+	restartMu  sync.Mutex
 	results    chan resultV2[T]
 	wg         sync.WaitGroup
 	ctx        context.Context
 	cancelFunc context.CancelFunc
+	// initWorkers is called with the number of workers every time before they are started,
+	// it is used to create worker-local state and is nil for a pool without state;
+	// the returned function is called by every worker of this start when it exits
+	initWorkers func(workers int) (exit func(worker int))
 
 	started   atomic.Bool
 	submitted atomic.Int64
 	running   atomic.Int64
 	finished  atomic.Int64
+	dropped   atomic.Int64
+	deduped   atomic.Int64
+
+	seq          atomic.Uint64
+	gapTimeout   atomic.Int64
+	lastActivity atomic.Int64
+
+	// idsMu protects pendingIDs and cancelledIDs that are used to cancel queued tasks with ids
+	// and activeIDs that are used to deduplicate queued and running tasks with ids
+	idsMu        sync.Mutex
+	pendingIDs   map[string]int
+	cancelledIDs map[string]int
+	activeIDs    map[string]int
+	deduplicate  atomic.Bool
+
+	// queuedWeight is the total weight of tasks in the shared queue, it is limited by the queue capacity;
+	// freed is closed to wake up submitters waiting for weight when weightWaiters is positive
+	queuedWeight  atomic.Int64
+	weightWaiters atomic.Int64
+	weightMu      sync.Mutex
+	freed         chan struct{}
+
+	sampler        atomic.Pointer[queueSampler]
+	hooks          atomic.Pointer[taskHooksV2]
+	transform      atomic.Pointer[func(T) T]
+	overflowPolicy atomic.Int32
+}
+
+// taskHooksV2 holds callbacks that are invoked around every task execution.
+type taskHooksV2 struct {
+	onStart func()
+	onEnd   func(dur time.Duration, err error)
 }
 
 // NewWorkerPool creates a new worker pool with the specified number of workers and task queue capacity.
-func NewWorkerPoolV2[T any](workers, queueCapacity int) *WorkerPoolV2[T] {
+// The optional maxResults limits the number of results buffered until they are fetched,
+// it equals to queueCapacity by default. When the result buffer is full, workers block
+// until results are fetched, so the task queue fills up and applies backpressure to the submitters.
+func NewWorkerPoolV2[T any](workers, queueCapacity int, maxResultsRaw ...int) *WorkerPoolV2[T] {
 	if workers <= 0 {
 		workers = 1
 	}
 	if queueCapacity <= 0 {
 		queueCapacity = workers * 100
 	}
+	maxResults := queueCapacity
+	if len(maxResultsRaw) > 0 && maxResultsRaw[0] > 0 {
+		maxResults = maxResultsRaw[0]
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
-	return &WorkerPoolV2[T]{
-		workers:    workers,
-		tasks:      make(chan func() (T, error), queueCapacity),
-		results:    make(chan resultV2[T], queueCapacity),
+	p := &WorkerPoolV2[T]{
+		results:    make(chan resultV2[T], maxResults),
 		ctx:        ctx,
 		cancelFunc: cancel,
 	}
+	p.resize(workers, queueCapacity)
+
+	return p
 }
 
 // Start launches the worker goroutines.
 func (p *WorkerPoolV2[T]) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	if p.started.Load() {
 		return
 	}
 
+	p.startWorkers()
+	p.started.Store(true)
+	p.touch()
+}
+
+// Restart waits for all queued and running tasks to complete, then replaces the workers and the task queue
+// with new ones of the provided sizes (non-positive values are handled like in [NewWorkerPoolV2]).
+// The pool stops accepting tasks when the restart begins: submissions made during the restart,
+// including the ones that are waiting for space in the queue, return false.
+// Results, hooks and overflow policy are kept, so results of tasks completed before the restart
+// can be fetched after it. If the result buffer is full, Restart waits until results are fetched.
+// Restart does not interrupt running tasks, so it never returns if a task never returns.
+// Other methods, e.g. [WorkerPoolV2.HealthCheck], are not blocked while it waits for tasks.
+// If the pool is not started, it only changes sizes that will be used by [WorkerPoolV2.Start].
+func (p *WorkerPoolV2[T]) Restart(newWorkers, newQueue int) {
+	if newWorkers <= 0 {
+		newWorkers = 1
+	}
+	if newQueue <= 0 {
+		newQueue = newWorkers * 100
+	}
+
+	p.restartMu.Lock()
+	defer p.restartMu.Unlock()
+
+	// Reject new submissions and wake up waiting ones, so they release mu
+	close(p.restarting)
+
+	p.mu.Lock()
+	started := p.started.Load()
+	if started {
+		// Workers process the rest of the queues before exiting, no task can be added after that
+		close(p.quit)
+	}
+	p.mu.Unlock()
+
+	if started {
+		p.wg.Wait()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.resize(newWorkers, newQueue)
+	if started && p.started.Load() {
+		p.startWorkers()
+	}
+}
+
+// keyedQueueCapacity is the capacity of the keyed queue of every worker (see [WorkerPoolV2.SubmitKeyed]).
+// It is small and does not depend on the queue capacity, so pools that do not use keyed tasks
+// do not pay for them, a full keyed queue blocks the submitter.
+const keyedQueueCapacity = 16
+
+// resize creates new queues for the provided number of workers.
+func (p *WorkerPoolV2[T]) resize(workers, queueCapacity int) {
+	keyed := make([]chan taskV2[T], workers)
+	for i := range keyed {
+		keyed[i] = make(chan taskV2[T], min(queueCapacity, keyedQueueCapacity))
+	}
+
+	p.workers = workers
+	p.tasks = make(chan taskV2[T], queueCapacity)
+	p.keyed = keyed
+	p.quit = make(chan struct{})
+	p.restarting = make(chan struct{})
+}
+
+// startWorkers launches the worker goroutines, p.mu must be held.
+func (p *WorkerPoolV2[T]) startWorkers() {
+	var exit func(worker int)
+	if p.initWorkers != nil {
+		exit = p.initWorkers(p.workers)
+	}
 	p.wg.Add(p.workers)
-	for range p.workers {
-		lang.Go(nil, p.worker)
+	for i := range p.workers {
+		tasks, lane, quit := p.tasks, p.keyed[i], p.quit
+		lang.Go(nil, func() {
+			if exit != nil {
+				defer exit(i)
+			}
+			p.worker(i, tasks, lane, quit)
+		})
 	}
-	p.started.Store(true)
 }
 
 // Stop signals all workers to stop after completing their current tasks.
@@ -72,61 +284,529 @@ func (p *WorkerPoolV2[T]) Stop() {
 	p.started.Store(false)
 }
 
-// worker is the goroutine that processes tasks.
-func (p *WorkerPoolV2[T]) worker() {
+// worker is the goroutine that processes tasks from the shared queue and from its own keyed queue.
+// When quit is closed, it processes the rest of the queues and exits.
+func (p *WorkerPoolV2[T]) worker(index int, tasks, lane chan taskV2[T], quit chan struct{}) {
 	defer p.wg.Done()
 
 	for {
 		select {
 		case <-p.ctx.Done():
 			return
-		case task, ok := <-p.tasks:
-			if !ok {
+		case task, ok := <-tasks:
+			if !ok || !p.process(task, index) {
 				return
 			}
-			p.running.Add(1)
-			value, err := task()
-			select {
-			case p.results <- resultV2[T]{Value: value, Err: err}:
-				p.running.Add(-1)
-				p.finished.Add(1)
+		case task := <-lane:
+			if !p.process(task, index) {
+				return
+			}
+		case <-quit:
+			p.drain(tasks, lane, index)
+			return
+		}
+	}
+}
 
-			case <-p.ctx.Done():
+// drain processes tasks from the queues until they are empty.
+func (p *WorkerPoolV2[T]) drain(tasks, lane chan taskV2[T], worker int) {
+	for {
+		select {
+		case task := <-lane:
+			if !p.process(task, worker) {
+				return
+			}
+		case task := <-tasks:
+			if !p.process(task, worker) {
 				return
 			}
+		default:
+			return
 		}
 	}
 }
 
+// process executes the task and sends its result, returns false if the pool was stopped.
+func (p *WorkerPoolV2[T]) process(task taskV2[T], worker int) bool {
+	defer p.releaseID(task.id)
+	p.releaseWeight(task.weight)
+	p.running.Add(1)
+
+	var (
+		value T
+		err   error
+	)
+	if p.takePending(task.id) {
+		value, err = p.runTask(task, worker)
+	} else {
+		task.discard()
+		err = context.Canceled
+	}
+
+	select {
+	case p.results <- resultV2[T]{ID: task.id, Seq: task.seq, Value: value, Err: err}:
+		p.running.Add(-1)
+		p.finished.Add(1)
+		return true
+
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// runTask executes the task in the worker and applies the result transform to its value.
+func (p *WorkerPoolV2[T]) runTask(task taskV2[T], worker int) (T, error) {
+	p.touch()
+	defer p.touch()
+
+	value, err := p.runWithHooks(task, worker)
+	if transform := p.transform.Load(); transform != nil && err == nil {
+		value = (*transform)(value)
+	}
+	return value, err
+}
+
+// runWithHooks executes the task in the worker, calling the configured hooks around it.
+func (p *WorkerPoolV2[T]) runWithHooks(task taskV2[T], worker int) (T, error) {
+	hooks := p.hooks.Load()
+	if hooks == nil {
+		return task.call(worker)
+	}
+
+	if hooks.onStart != nil {
+		hooks.onStart()
+	}
+	start := time.Now()
+	value, err := task.call(worker)
+	if hooks.onEnd != nil {
+		hooks.onEnd(time.Since(start), err)
+	}
+	return value, err
+}
+
+// SetHooks sets callbacks that are invoked by a worker around every task.
+// onStart is called right before the task is executed, onEnd is called right after it
+// with the task duration and the returned error. Any of the hooks can be nil.
+// Hooks are called in the worker goroutine, so they should be fast and safe for concurrent use.
+// It is safe to call SetHooks while the pool is running, new hooks apply to the next executed tasks.
+func (p *WorkerPoolV2[T]) SetHooks(onStart func(), onEnd func(dur time.Duration, err error)) {
+	if onStart == nil && onEnd == nil {
+		p.hooks.Store(nil)
+		return
+	}
+	p.hooks.Store(&taskHooksV2{onStart: onStart, onEnd: onEnd})
+}
+
+// SetResultTransform sets a function that is applied to the value of every successful task
+// before the result is stored, so all fetch methods return transformed values.
+// Values of tasks that returned an error are not transformed. Passing nil removes the transform.
+// The transform is called in the worker goroutine, so it should be safe for concurrent use.
+// To get results of another type, create the pool with that type and convert values inside the submitted tasks.
+// It is safe to call SetResultTransform while the pool is running, it applies to the next completed tasks.
+func (p *WorkerPoolV2[T]) SetResultTransform(transform func(T) T) {
+	if transform == nil {
+		p.transform.Store(nil)
+		return
+	}
+	p.transform.Store(&transform)
+}
+
+// SetDeduplicate enables or disables deduplication of tasks submitted with [WorkerPoolV2.SubmitWithID].
+// When it is enabled, a submission with the id of a task that is queued or running is coalesced with that task:
+// the new task is not added to the queue, the submission returns true and the only result is the result
+// of the existing task, that can be fetched by the id using [WorkerPoolV2.FetchResultsMap].
+// Tasks without ids are never deduplicated. It is disabled by default.
+// It is safe to call SetDeduplicate while the pool is running, it applies to the next submissions.
+func (p *WorkerPoolV2[T]) SetDeduplicate(enabled bool) {
+	p.deduplicate.Store(enabled)
+}
+
+// SetOverflowPolicy sets the behavior of [WorkerPoolV2.Submit] when the task queue is full.
+// The default policy is [OverflowBlock]. It is safe to call SetOverflowPolicy while the pool is running.
+func (p *WorkerPoolV2[T]) SetOverflowPolicy(policy OverflowPolicy) {
+	p.overflowPolicy.Store(int32(policy))
+}
+
 // Submit adds a task to the pool and returns true if the task was accepted.
 // Returns false if the pool is stopped or the task queue is full and the timeout is reached.
+// If the queue is full, the behavior depends on the overflow policy (see [WorkerPoolV2.SetOverflowPolicy]),
+// the timeout is used only with [OverflowBlock] policy.
 func (p *WorkerPoolV2[T]) Submit(task func() (T, error), timeoutRaw ...time.Duration) bool {
-	if task == nil {
+	return p.submit(taskV2[T]{fn: task}, timeoutRaw...)
+}
+
+// SubmitWithID adds a task with the caller's id to the pool and returns true if the task was accepted.
+// The id is used to correlate the result with the task in [WorkerPoolV2.FetchResultsMap], so it should be unique.
+// It behaves like [WorkerPoolV2.Submit] otherwise.
+func (p *WorkerPoolV2[T]) SubmitWithID(id string, task func() (T, error), timeoutRaw ...time.Duration) bool {
+	return p.submit(taskV2[T]{id: id, fn: task}, timeoutRaw...)
+}
+
+// SubmitWithWeight adds a task with the estimated cost to the pool and returns true if the task was accepted.
+// The capacity of the task queue limits the total weight of queued tasks rather than their number,
+// tasks submitted with other methods have weight 1. Weight less than 1 is treated as 1.
+// Returns false if the weight is greater than the queue capacity, so the task can never be queued.
+// It behaves like [WorkerPoolV2.Submit] otherwise, the queue is full when there is not enough free weight for the task.
+func (p *WorkerPoolV2[T]) SubmitWithWeight(task func() (T, error), weight int, timeoutRaw ...time.Duration) bool {
+	return p.submit(taskV2[T]{fn: task, weight: weight}, timeoutRaw...)
+}
+
+func (p *WorkerPoolV2[T]) submit(task taskV2[T], timeoutRaw ...time.Duration) bool {
+	if !task.isValid() {
 		return false
 	}
-	if p.IsStopped() {
-		return false
+	task.seq = p.seq.Add(1)
+	task.weight = max(task.weight, 1)
+
+	accepted, callerRuns := p.enqueue(task, timeoutRaw...)
+	if callerRuns {
+		// The task is executed after p.mu is released, so it can submit to the pool and does not block Restart
+		return p.runInCaller(task)
 	}
+	return accepted
+}
+
+// enqueue adds a task to the queue according to the overflow policy and returns true if the task was accepted.
+// It returns true as the second value if the task should be executed in the caller goroutine instead.
+func (p *WorkerPoolV2[T]) enqueue(task taskV2[T], timeoutRaw ...time.Duration) (bool, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 
+	if p.IsStopped() || p.isRestarting() || task.weight > cap(p.tasks) {
+		task.discard()
+		return false, false
+	}
+	if !p.acquireID(task.id) {
+		task.discard()
+		p.deduped.Add(1)
+		return true, false
+	}
+
+	switch OverflowPolicy(p.overflowPolicy.Load()) {
+	case OverflowDropNewest:
+		if p.trySubmit(task) {
+			return true, false
+		}
+		p.reject(task)
+		p.dropped.Add(1)
+		return false, false
+
+	case OverflowDropOldest:
+		if p.submitDropOldest(task) {
+			return true, false
+		}
+		p.reject(task)
+		return false, false
+
+	case OverflowCallerRuns:
+		if p.trySubmit(task) {
+			return true, false
+		}
+		if task.fnWorker != nil {
+			// The caller goroutine has no worker-local state to run the task with
+			p.reject(task)
+			p.dropped.Add(1)
+			return false, false
+		}
+		return false, true
+	}
+
+	var timeout <-chan time.Time
 	if len(timeoutRaw) > 0 {
 		timer := time.NewTimer(timeoutRaw[0])
 		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	p.weightWaiters.Add(1)
+	defer p.weightWaiters.Add(-1)
 
+	for {
+		freed := p.weightFreed()
+		if p.trySubmit(task) {
+			return true, false
+		}
 		select {
-		case p.tasks <- task:
-			p.submitted.Add(1)
-			return true
-		case <-timer.C:
-			return false
+		case <-freed:
+		case <-timeout:
+			p.reject(task)
+			return false, false
+		case <-p.restarting:
+			p.reject(task)
+			return false, false
 		case <-p.ctx.Done():
+			p.reject(task)
+			return false, false
+		}
+	}
+}
+
+// reject releases the id and the resources of a task that was not queued.
+func (p *WorkerPoolV2[T]) reject(task taskV2[T]) {
+	p.releaseID(task.id)
+	task.discard()
+}
+
+// reserveWeight adds the weight of a task to the queued weight and returns false
+// if it would exceed the queue capacity, p.mu must be held.
+func (p *WorkerPoolV2[T]) reserveWeight(weight int) bool {
+	capacity := int64(cap(p.tasks))
+	for {
+		current := p.queuedWeight.Load()
+		if current+int64(weight) > capacity {
 			return false
 		}
+		if p.queuedWeight.CompareAndSwap(current, current+int64(weight)) {
+			return true
+		}
+	}
+}
+
+// releaseWeight subtracts the weight of a task that left the queue and wakes up waiting submitters.
+func (p *WorkerPoolV2[T]) releaseWeight(weight int) {
+	if weight == 0 {
+		return
+	}
+	p.queuedWeight.Add(-int64(weight))
+	if p.weightWaiters.Load() == 0 {
+		return
+	}
+
+	p.weightMu.Lock()
+	defer p.weightMu.Unlock()
+
+	if p.freed != nil {
+		close(p.freed)
+		p.freed = nil
 	}
+}
+
+// weightFreed returns a channel that is closed when the weight of a task is released.
+func (p *WorkerPoolV2[T]) weightFreed() <-chan struct{} {
+	p.weightMu.Lock()
+	defer p.weightMu.Unlock()
+
+	if p.freed == nil {
+		p.freed = make(chan struct{})
+	}
+	return p.freed
+}
+
+// Cancel cancels a task submitted with [WorkerPoolV2.SubmitWithID] that is waiting in the queue.
+// The cancelled task is not executed, its result has [context.Canceled] error and zero value.
+// Returns false if there is no queued task with the id, e.g. it has already started or completed.
+// If several queued tasks have the same id, only one of them is cancelled.
+func (p *WorkerPoolV2[T]) Cancel(id string) bool {
+	if id == "" {
+		return false
+	}
+
+	p.idsMu.Lock()
+	defer p.idsMu.Unlock()
+
+	if p.pendingIDs[id] <= p.cancelledIDs[id] {
+		return false
+	}
+	if p.cancelledIDs == nil {
+		p.cancelledIDs = make(map[string]int)
+	}
+	p.cancelledIDs[id]++
+	return true
+}
+
+// addPending registers a task with the id that is being added to the queue.
+func (p *WorkerPoolV2[T]) addPending(id string) {
+	if id == "" {
+		return
+	}
+
+	p.idsMu.Lock()
+	defer p.idsMu.Unlock()
+
+	if p.pendingIDs == nil {
+		p.pendingIDs = make(map[string]int)
+	}
+	p.pendingIDs[id]++
+}
+
+// takePending unregisters a task with the id that leaves the queue and returns false if it was cancelled.
+func (p *WorkerPoolV2[T]) takePending(id string) bool {
+	if id == "" {
+		return true
+	}
+
+	p.idsMu.Lock()
+	defer p.idsMu.Unlock()
+
+	decrementCount(p.pendingIDs, id)
+	return !decrementCount(p.cancelledIDs, id)
+}
+
+// acquireID registers a queued or running task with the id and returns false
+// if the deduplication is enabled and there is already such task.
+func (p *WorkerPoolV2[T]) acquireID(id string) bool {
+	if id == "" {
+		return true
+	}
+
+	p.idsMu.Lock()
+	defer p.idsMu.Unlock()
+
+	if p.deduplicate.Load() && p.activeIDs[id] > 0 {
+		return false
+	}
+	if p.activeIDs == nil {
+		p.activeIDs = make(map[string]int)
+	}
+	p.activeIDs[id]++
+	return true
+}
+
+// releaseID unregisters a task with the id that has completed or has been rejected.
+func (p *WorkerPoolV2[T]) releaseID(id string) {
+	if id == "" {
+		return
+	}
+
+	p.idsMu.Lock()
+	defer p.idsMu.Unlock()
+
+	decrementCount(p.activeIDs, id)
+}
+
+// decrementCount decrements the counter of the key and returns true if it was positive.
+func decrementCount(counters map[string]int, key string) bool {
+	n := counters[key]
+	switch {
+	case n <= 0:
+		return false
+	case n == 1:
+		delete(counters, key)
+	default:
+		counters[key] = n - 1
+	}
+	return true
+}
+
+// SubmitKeyed adds a task to the pool and returns true if the task was accepted.
+// Tasks with the same key are executed one by one in submission order by the same worker,
+// while tasks with different keys can be executed in parallel.
+// Every worker has a small keyed queue of its own (16 tasks) that does not depend on the queue capacity.
+// It blocks until there is space in the queue of the worker or the timeout is reached,
+// overflow policy is not applied to keyed tasks.
+// Returns false if the pool is stopped or the timeout is reached.
+func (p *WorkerPoolV2[T]) SubmitKeyed(key string, task func() (T, error), timeoutRaw ...time.Duration) bool {
+	return p.submitKeyed(key, taskV2[T]{fn: task}, timeoutRaw...)
+}
+
+func (p *WorkerPoolV2[T]) submitKeyed(key string, task taskV2[T], timeoutRaw ...time.Duration) bool {
+	if !task.isValid() {
+		return false
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.IsStopped() || p.isRestarting() {
+		task.discard()
+		return false
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	lane := p.keyed[h.Sum32()%uint32(len(p.keyed))]
+	task.seq = p.seq.Add(1)
+
+	var timeout <-chan time.Time
+	if len(timeoutRaw) > 0 {
+		timer := time.NewTimer(timeoutRaw[0])
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case lane <- task:
+		p.submitted.Add(1)
+		return true
+	case <-timeout:
+		task.discard()
+		return false
+	case <-p.restarting:
+		task.discard()
+		return false
+	case <-p.ctx.Done():
+		task.discard()
+		return false
+	}
+}
+
+// isRestarting returns true if a restart has begun and the queues are not replaced yet, p.mu must be held.
+func (p *WorkerPoolV2[T]) isRestarting() bool {
+	select {
+	case <-p.restarting:
+		return true
+	default:
+		return false
+	}
+}
+
+// trySubmit adds a task to the queue without blocking and returns false if the queue is full
+// or there is not enough free weight for the task.
+func (p *WorkerPoolV2[T]) trySubmit(task taskV2[T]) bool {
+	if !p.reserveWeight(task.weight) {
+		return false
+	}
+	p.addPending(task.id)
 	select {
 	case p.tasks <- task:
 		p.submitted.Add(1)
 		return true
+	default:
+		p.takePending(task.id)
+		p.releaseWeight(task.weight)
+		return false
+	}
+}
+
+// submitDropOldest adds a task to the queue evicting the oldest queued tasks if the queue is full.
+func (p *WorkerPoolV2[T]) submitDropOldest(task taskV2[T]) bool {
+	for {
+		if p.trySubmit(task) {
+			return true
+		}
+		select {
+		case <-p.ctx.Done():
+			return false
+		case evicted := <-p.tasks:
+			// Evicted task will never produce a result
+			p.takePending(evicted.id)
+			p.reject(evicted)
+			p.releaseWeight(evicted.weight)
+			p.submitted.Add(-1)
+			p.dropped.Add(1)
+		default:
+			// Workers have taken tasks from the queue in the meantime, try again
+		}
+	}
+}
+
+// runInCaller executes a task in the caller goroutine and stores its result like a worker does,
+// p.mu must not be held.
+func (p *WorkerPoolV2[T]) runInCaller(task taskV2[T]) bool {
+	defer p.releaseID(task.id)
+
+	p.submitted.Add(1)
+	p.running.Add(1)
+	value, err := p.runTask(task, callerWorker)
+	select {
+	case p.results <- resultV2[T]{ID: task.id, Seq: task.seq, Value: value, Err: err}:
+		p.running.Add(-1)
+		p.finished.Add(1)
+		return true
+
 	case <-p.ctx.Done():
+		p.running.Add(-1)
+		p.submitted.Add(-1)
 		return false
 	}
 }
@@ -136,6 +816,87 @@ func (p *WorkerPoolV2[T]) Submit(task func() (T, error), timeoutRaw ...time.Dura
 // If the timeout is reached before the number of results is equal to the number of submitted tasks, it returns the results and errors.
 // If some tasks are added after the call to FetchResults, they will not be fetched by this method (use FetchAllResults instead)
 func (p *WorkerPoolV2[T]) FetchResults(timeoutRaw ...time.Duration) ([]T, []error) {
+	var (
+		results []T
+		errors  []error
+	)
+	p.fetch(func(expectedCount int) {
+		results = make([]T, 0, expectedCount)
+	}, func(result resultV2[T]) {
+		results = append(results, result.Value)
+		errors = append(errors, result.Err)
+	}, 0, timeoutRaw...)
+
+	return results, errors
+}
+
+// FetchResultsOrdered fetches results from the pool like [WorkerPoolV2.FetchResults],
+// but returns them in the order the tasks were submitted.
+// If the gap timeout is set (see [WorkerPoolV2.SetOrderedGapTimeout]) and no result arrives during it,
+// it stops waiting for the missing results and returns the ones it has, so one slow task does not
+// block the rest. Results of such tasks are returned by the next fetch.
+func (p *WorkerPoolV2[T]) FetchResultsOrdered(timeoutRaw ...time.Duration) ([]T, []error) {
+	var collected []resultV2[T]
+	p.fetch(func(expectedCount int) {
+		collected = make([]resultV2[T], 0, expectedCount)
+	}, func(result resultV2[T]) {
+		collected = append(collected, result)
+	}, time.Duration(p.gapTimeout.Load()), timeoutRaw...)
+
+	slices.SortFunc(collected, func(a, b resultV2[T]) int {
+		return cmp.Compare(a.Seq, b.Seq)
+	})
+
+	results := make([]T, 0, len(collected))
+	var errors []error
+	for _, result := range collected {
+		results = append(results, result.Value)
+		errors = append(errors, result.Err)
+	}
+	return results, errors
+}
+
+// SetOrderedGapTimeout sets how long [WorkerPoolV2.FetchResultsOrdered] waits for the next result
+// when some results are still missing before returning the results it has.
+// Zero or negative duration disables the gap timeout, it is the default.
+func (p *WorkerPoolV2[T]) SetOrderedGapTimeout(d time.Duration) {
+	p.gapTimeout.Store(int64(max(d, 0)))
+}
+
+// FetchResultsMap fetches results from the pool like [WorkerPoolV2.FetchResults]
+// and returns them keyed by the ids of tasks provided in [WorkerPoolV2.SubmitWithID].
+// Results of tasks submitted without id are stored with an empty id.
+// If several tasks have the same id, only one of their results is returned.
+func (p *WorkerPoolV2[T]) FetchResultsMap(timeoutRaw ...time.Duration) map[string]TaskResult[T] {
+	var results map[string]TaskResult[T]
+	p.fetch(func(expectedCount int) {
+		results = make(map[string]TaskResult[T], expectedCount)
+	}, func(result resultV2[T]) {
+		results[result.ID] = TaskResult[T]{Value: result.Value, Err: result.Err}
+	}, 0, timeoutRaw...)
+
+	return results
+}
+
+// FetchResultsFunc fetches results from the pool like [WorkerPoolV2.FetchResults]
+// and returns only the ones for which keep returns true, e.g. successful results with non-zero values.
+// Discarded results are fetched too, so they are not returned by the next fetch.
+// If keep is nil, all results are returned.
+func (p *WorkerPoolV2[T]) FetchResultsFunc(keep func(T, error) bool, timeoutRaw ...time.Duration) []TaskResult[T] {
+	var results []TaskResult[T]
+	p.fetch(func(int) {}, func(result resultV2[T]) {
+		if keep == nil || keep(result.Value, result.Err) {
+			results = append(results, TaskResult[T]{Value: result.Value, Err: result.Err})
+		}
+	}, 0, timeoutRaw...)
+
+	return results
+}
+
+// fetch reads the results of tasks submitted at the time of call and passes them to collect.
+// init is called with the number of expected results before reading.
+// If gap is positive, it stops reading when no result arrives during gap.
+func (p *WorkerPoolV2[T]) fetch(init func(expectedCount int), collect func(resultV2[T]), gap time.Duration, timeoutRaw ...time.Duration) {
 	var timeout time.Duration = time.Hour * 24 * 365
 	if len(timeoutRaw) > 0 {
 		timeout = timeoutRaw[0]
@@ -146,23 +907,52 @@ func (p *WorkerPoolV2[T]) FetchResults(timeoutRaw ...time.Duration) ([]T, []erro
 
 	// Capture the count before the loop to avoid race condition
 	expectedCount := int(p.submitted.Load())
+	init(expectedCount)
 
-	results := make([]T, 0, expectedCount)
-	var errors []error
+	var gapTimer *time.Timer
+	var gapC <-chan time.Time
+	if gap > 0 {
+		gapTimer = time.NewTimer(gap)
+		defer gapTimer.Stop()
+		gapC = gapTimer.C
+	}
 
 	for range expectedCount {
 		select {
 		case result := <-p.results:
-			results = append(results, result.Value)
-			errors = append(errors, result.Err)
+			collect(result)
 			p.submitted.Add(-1)
 			p.finished.Add(-1)
+			if gapTimer != nil {
+				gapTimer.Reset(gap)
+			}
+		case <-gapC:
+			return
 		case <-ctx.Done():
-			return results, errors
+			return
 		}
 	}
+}
 
-	return results, errors
+// DrainResults calls f for every result that is buffered in the pool and not fetched yet, without waiting
+// for running tasks. It is intended to be called after [WorkerPoolV2.Stop] to flush completed results,
+// e.g. to persist them on shutdown. Results of tasks that are still running are not drained.
+// Returns the number of drained results.
+func (p *WorkerPoolV2[T]) DrainResults(f func(TaskResult[T])) int {
+	var n int
+	for {
+		select {
+		case result := <-p.results:
+			p.submitted.Add(-1)
+			p.finished.Add(-1)
+			n++
+			if f != nil {
+				f(TaskResult[T]{Value: result.Value, Err: result.Err})
+			}
+		default:
+			return n
+		}
+	}
 }
 
 // FetchAllResults fetches all results from the pool.
@@ -214,7 +1004,340 @@ func (p *WorkerPoolV2[T]) Finished() int {
 	return int(p.finished.Load())
 }
 
+// Dropped returns the number of tasks that were discarded because the queue was full
+// when using [OverflowDropNewest] or [OverflowDropOldest] policy.
+func (p *WorkerPoolV2[T]) Dropped() int {
+	return int(p.dropped.Load())
+}
+
+// Deduplicated returns the number of submissions that were coalesced with queued or running tasks
+// with the same id when the deduplication is enabled (see [WorkerPoolV2.SetDeduplicate]).
+func (p *WorkerPoolV2[T]) Deduplicated() int {
+	return int(p.deduped.Load())
+}
+
+// QueuedWeight returns the total weight of tasks waiting in the shared queue (see [WorkerPoolV2.SubmitWithWeight]).
+func (p *WorkerPoolV2[T]) QueuedWeight() int {
+	return int(p.queuedWeight.Load())
+}
+
+// ResultBufferLen returns the number of results that are buffered and waiting to be fetched.
+func (p *WorkerPoolV2[T]) ResultBufferLen() int {
+	return len(p.results)
+}
+
+// LastActivity returns the time when a task was last started or finished by the pool.
+// If no task has been executed yet, it returns the time when the pool was started
+// or zero time if it has never been started.
+func (p *WorkerPoolV2[T]) LastActivity() time.Time {
+	last := p.lastActivity.Load()
+	if last == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, last)
+}
+
+// HealthCheck returns false if there are queued tasks but no task was started or finished
+// during stuckThreshold, that means workers are stuck, e.g. blocked on the full result buffer
+// (fetch the results to unblock them) or deadlocked in tasks. A pool with deadlocked tasks cannot be
+// recovered with [WorkerPoolV2.Restart], because it waits for running tasks; replace the pool instead.
+// A pool without queued tasks is considered healthy.
+func (p *WorkerPoolV2[T]) HealthCheck(stuckThreshold time.Duration) bool {
+	if p.queued() == 0 {
+		return true
+	}
+	return time.Since(p.LastActivity()) <= stuckThreshold
+}
+
+// defaultQueueHistorySize is the default number of queue depth samples kept by [WorkerPoolV2.EnableQueueSampling].
+const defaultQueueHistorySize = 1024
+
+// queueSampler keeps the last queue depth samples in a ring buffer.
+type queueSampler struct {
+	mu      sync.Mutex
+	samples []int
+	next    int
+	full    bool
+	stop    chan struct{}
+}
+
+func (s *queueSampler) add(depth int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples[s.next] = depth
+	s.next = (s.next + 1) % len(s.samples)
+	if s.next == 0 {
+		s.full = true
+	}
+}
+
+// history returns the samples from the oldest to the newest.
+func (s *queueSampler) history() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.full {
+		return slices.Clone(s.samples[:s.next])
+	}
+	return append(slices.Clone(s.samples[s.next:]), s.samples[:s.next]...)
+}
+
+// EnableQueueSampling starts recording the number of queued tasks every interval until the pool is stopped.
+// The optional historySize limits the number of kept samples (1024 by default), the oldest samples are overwritten.
+// Calling it again replaces the previous sampling and discards its history,
+// zero or negative interval disables sampling.
+func (p *WorkerPoolV2[T]) EnableQueueSampling(interval time.Duration, historySizeRaw ...int) {
+	if interval <= 0 {
+		if old := p.sampler.Swap(nil); old != nil {
+			close(old.stop)
+		}
+		return
+	}
+
+	historySize := defaultQueueHistorySize
+	if len(historySizeRaw) > 0 && historySizeRaw[0] > 0 {
+		historySize = historySizeRaw[0]
+	}
+	sampler := &queueSampler{
+		samples: make([]int, historySize),
+		stop:    make(chan struct{}),
+	}
+	if old := p.sampler.Swap(sampler); old != nil {
+		close(old.stop)
+	}
+
+	lang.Go(nil, func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				sampler.add(p.queued())
+			case <-sampler.stop:
+				return
+			case <-p.ctx.Done():
+				return
+			}
+		}
+	})
+}
+
+// QueueDepthHistory returns the recorded queue depth samples from the oldest to the newest.
+// Returns nil if sampling is not enabled (see [WorkerPoolV2.EnableQueueSampling]).
+func (p *WorkerPoolV2[T]) QueueDepthHistory() []int {
+	sampler := p.sampler.Load()
+	if sampler == nil {
+		return nil
+	}
+	return sampler.history()
+}
+
+// QueueDepthPercentile returns the p-th percentile (0-100) of the recorded queue depth samples
+// using the nearest-rank method, e.g. QueueDepthPercentile(95) returns the p95 queue depth.
+// Returns 0 if there are no samples.
+func (p *WorkerPoolV2[T]) QueueDepthPercentile(pct float64) int {
+	samples := p.QueueDepthHistory()
+	if len(samples) == 0 {
+		return 0
+	}
+	slices.Sort(samples)
+
+	rank := int(math.Ceil(pct / 100 * float64(len(samples))))
+	return samples[min(max(rank-1, 0), len(samples)-1)]
+}
+
+// queued returns the number of tasks waiting in the queues.
+func (p *WorkerPoolV2[T]) queued() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	n := len(p.tasks)
+	for _, lane := range p.keyed {
+		n += len(lane)
+	}
+	return n
+}
+
+// touch updates the time of the last activity.
+func (p *WorkerPoolV2[T]) touch() {
+	p.lastActivity.Store(time.Now().UnixNano())
+}
+
 // IsStopped returns true if the worker pool has been stopped.
 func (p *WorkerPoolV2[T]) IsStopped() bool {
 	return !p.started.Load()
 }
+
+// WorkerPoolWithState is a [WorkerPoolV2] where every worker owns a state of type S (e.g. a DB connection)
+// that is created once for the worker when it starts and is passed to every task executed by this worker.
+// It has all methods of WorkerPoolV2, tasks without state can be submitted using the embedded pool.
+type WorkerPoolWithState[T, S any] struct {
+	*WorkerPoolV2[T]
+	newState func() S
+	// run calls the task of the pool with the state of the worker, it is nil if there is no task
+	run     func(worker int) (T, error)
+	release atomic.Pointer[func(S)]
+	// states holds the state of every worker, it is replaced when workers are started
+	states atomic.Pointer[[]S]
+}
+
+// NewWorkerPoolWithState creates a new worker pool with the specified number of workers and task queue capacity,
+// that executes task with the state of the worker for every submission. newState is called once for every worker
+// when the workers are started to create its state. Workers are recreated by [WorkerPoolV2.Restart],
+// so new states are created after a restart, use [WorkerPoolWithState.SetStateRelease] to close the old ones.
+// With [OverflowCallerRuns] policy a submission is rejected when the queue is full.
+// See [NewWorkerPoolV2] for details about the sizes.
+func NewWorkerPoolWithState[T, S any](workers, queueCapacity int, newState func() S, task func(S) (T, error), maxResultsRaw ...int) *WorkerPoolWithState[T, S] {
+	p := &WorkerPoolWithState[T, S]{
+		WorkerPoolV2: NewWorkerPoolV2[T](workers, queueCapacity, maxResultsRaw...),
+		newState:     newState,
+	}
+	if newState != nil {
+		p.initWorkers = p.initStates
+	}
+	if task != nil {
+		p.run = func(worker int) (T, error) { return task(p.state(worker)) }
+	}
+	return p
+}
+
+// SetStateRelease sets a function that is called with the state of every worker when the worker exits
+// after [WorkerPoolV2.Stop] or [WorkerPoolV2.Restart], e.g. to close a connection. Passing nil removes it.
+// It is called in the goroutine of the exiting worker after its last task has returned.
+// It is safe to call SetStateRelease while the pool is running.
+func (p *WorkerPoolWithState[T, S]) SetStateRelease(release func(S)) {
+	if release == nil {
+		p.release.Store(nil)
+		return
+	}
+	p.release.Store(&release)
+}
+
+// Submit adds a task execution with the state of the worker to the pool and returns true if it was accepted.
+// It behaves like [WorkerPoolV2.Submit] otherwise.
+func (p *WorkerPoolWithState[T, S]) Submit(timeoutRaw ...time.Duration) bool {
+	return p.submit(taskV2[T]{fnWorker: p.run}, timeoutRaw...)
+}
+
+// SubmitWithID adds a task execution with the state of the worker and the caller's id to the pool
+// and returns true if it was accepted. It behaves like [WorkerPoolV2.SubmitWithID] otherwise.
+func (p *WorkerPoolWithState[T, S]) SubmitWithID(id string, timeoutRaw ...time.Duration) bool {
+	return p.submit(taskV2[T]{id: id, fnWorker: p.run}, timeoutRaw...)
+}
+
+// SubmitKeyed adds a task execution with the state of the worker to the pool and returns true if it was accepted.
+// It behaves like [WorkerPoolV2.SubmitKeyed] otherwise.
+func (p *WorkerPoolWithState[T, S]) SubmitKeyed(key string, timeoutRaw ...time.Duration) bool {
+	return p.submitKeyed(key, taskV2[T]{fnWorker: p.run}, timeoutRaw...)
+}
+
+// initStates creates the states for the provided number of workers before they are started
+// and returns the function that releases the state of an exiting worker.
+func (p *WorkerPoolWithState[T, S]) initStates(workers int) func(worker int) {
+	states := make([]S, workers)
+	for i := range states {
+		states[i] = p.newState()
+	}
+	p.states.Store(&states)
+
+	return func(worker int) {
+		if release := p.release.Load(); release != nil {
+			(*release)(states[worker])
+		}
+	}
+}
+
+// state returns the state of the worker with the provided index and zero value for a pool without state.
+func (p *WorkerPoolWithState[T, S]) state(worker int) S {
+	states := p.states.Load()
+	if states == nil || worker < 0 || worker >= len(*states) {
+		return *new(S)
+	}
+	return (*states)[worker]
+}
+
+// TypedWorkerPool is a [WorkerPoolV2] that processes submitted input values with a shared handler.
+// Submitted values are kept in the pool until a worker takes them, so a submission does not allocate a closure.
+// It has all methods of WorkerPoolV2, tasks without input can be submitted using the embedded pool.
+type TypedWorkerPool[In, Out any] struct {
+	*WorkerPoolV2[Out]
+	handler func(In) (Out, error)
+
+	// inputsMu protects inputs of queued tasks and free slots of inputs that can be reused
+	inputsMu sync.Mutex
+	inputs   []In
+	free     []int
+}
+
+// NewTypedWorkerPool creates a new worker pool with the specified number of workers and task queue capacity,
+// that calls handler for every submitted input value. See [NewWorkerPoolV2] for details about the sizes.
+func NewTypedWorkerPool[In, Out any](workers, queueCapacity int, handler func(In) (Out, error), maxResultsRaw ...int) *TypedWorkerPool[In, Out] {
+	return &TypedWorkerPool[In, Out]{
+		WorkerPoolV2: NewWorkerPoolV2[Out](workers, queueCapacity, maxResultsRaw...),
+		handler:      handler,
+	}
+}
+
+// Submit adds the input value to the pool to be processed by the handler and returns true if it was accepted.
+// It behaves like [WorkerPoolV2.Submit] otherwise.
+func (p *TypedWorkerPool[In, Out]) Submit(in In, timeoutRaw ...time.Duration) bool {
+	return p.submit(p.task(in), timeoutRaw...)
+}
+
+// SubmitWithID adds the input value with the caller's id to the pool to be processed by the handler
+// and returns true if it was accepted. It behaves like [WorkerPoolV2.SubmitWithID] otherwise.
+func (p *TypedWorkerPool[In, Out]) SubmitWithID(id string, in In, timeoutRaw ...time.Duration) bool {
+	task := p.task(in)
+	task.id = id
+	return p.submit(task, timeoutRaw...)
+}
+
+// SubmitKeyed adds the input value to the pool to be processed by the handler and returns true if it was accepted.
+// It behaves like [WorkerPoolV2.SubmitKeyed] otherwise.
+func (p *TypedWorkerPool[In, Out]) SubmitKeyed(key string, in In, timeoutRaw ...time.Duration) bool {
+	return p.submitKeyed(key, p.task(in), timeoutRaw...)
+}
+
+// task stores the input in a free slot and returns a task that processes it,
+// returns an invalid task if there is no handler.
+func (p *TypedWorkerPool[In, Out]) task(in In) taskV2[Out] {
+	if p.handler == nil {
+		return taskV2[Out]{}
+	}
+
+	p.inputsMu.Lock()
+	defer p.inputsMu.Unlock()
+
+	if n := len(p.free); n > 0 {
+		slot := p.free[n-1]
+		p.free = p.free[:n-1]
+		p.inputs[slot] = in
+		return taskV2[Out]{inputs: p, slot: slot}
+	}
+	p.inputs = append(p.inputs, in)
+	return taskV2[Out]{inputs: p, slot: len(p.inputs) - 1}
+}
+
+// run takes the input from the slot and processes it with the handler.
+func (p *TypedWorkerPool[In, Out]) run(slot int) (Out, error) {
+	return p.handler(p.take(slot))
+}
+
+// release frees the slot of an input that will not be processed.
+func (p *TypedWorkerPool[In, Out]) release(slot int) {
+	p.take(slot)
+}
+
+// take returns the input from the slot and frees the slot.
+func (p *TypedWorkerPool[In, Out]) take(slot int) In {
+	p.inputsMu.Lock()
+	defer p.inputsMu.Unlock()
+
+	in := p.inputs[slot]
+	// Do not keep references to processed values
+	p.inputs[slot] = *new(In)
+	p.free = append(p.free, slot)
+	return in
+}
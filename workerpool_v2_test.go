@@ -1,7 +1,11 @@
 package abstract_test
 
 import (
+	"context"
 	"errors"
+	"reflect"
+	"sort"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -982,3 +986,1182 @@ func TestWorkerPoolV2GenericTypes(t *testing.T) {
 		}
 	})
 }
+
+func TestWorkerPoolV2Hooks(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](2, 10)
+
+	var (
+		started atomic.Int64
+		ended   atomic.Int64
+		failed  atomic.Int64
+		maxDur  atomic.Int64
+		errTask = errors.New("task failed")
+	)
+	pool.SetHooks(
+		func() { started.Add(1) },
+		func(dur time.Duration, err error) {
+			ended.Add(1)
+			if errors.Is(err, errTask) {
+				failed.Add(1)
+			}
+			if int64(dur) > maxDur.Load() {
+				maxDur.Store(int64(dur))
+			}
+		},
+	)
+	pool.Start()
+	defer pool.Stop()
+
+	for i := range 5 {
+		pool.Submit(func() (int, error) {
+			if i == 0 {
+				time.Sleep(20 * time.Millisecond)
+				return 0, errTask
+			}
+			return i, nil
+		})
+	}
+
+	_, errs := pool.FetchResults(time.Second)
+	if len(errs) != 5 {
+		t.Fatalf("Expected 5 results, got %d", len(errs))
+	}
+	if started.Load() != 5 {
+		t.Errorf("Expected onStart to be called 5 times, got %d", started.Load())
+	}
+	if ended.Load() != 5 {
+		t.Errorf("Expected onEnd to be called 5 times, got %d", ended.Load())
+	}
+	if failed.Load() != 1 {
+		t.Errorf("Expected onEnd to receive 1 error, got %d", failed.Load())
+	}
+	if time.Duration(maxDur.Load()) < 20*time.Millisecond {
+		t.Errorf("Expected max duration to be at least 20ms, got %v", time.Duration(maxDur.Load()))
+	}
+
+	// Removing hooks
+	pool.SetHooks(nil, nil)
+	pool.Submit(func() (int, error) { return 1, nil })
+	pool.FetchResults(time.Second)
+	if started.Load() != 5 || ended.Load() != 5 {
+		t.Error("Hooks should not be called after they were removed")
+	}
+
+	// Only one hook
+	pool.SetHooks(nil, func(time.Duration, error) { ended.Add(1) })
+	pool.Submit(func() (int, error) { return 1, nil })
+	pool.FetchResults(time.Second)
+	if started.Load() != 5 || ended.Load() != 6 {
+		t.Errorf("Expected only onEnd to be called, got started=%d ended=%d", started.Load(), ended.Load())
+	}
+}
+
+func TestWorkerPoolV2OverflowPolicy(t *testing.T) {
+	// newBlockedPool returns a started pool with a single busy worker and a full queue of 2 tasks
+	newBlockedPool := func(policy abstract.OverflowPolicy) (*abstract.WorkerPoolV2[int], chan struct{}) {
+		pool := abstract.NewWorkerPoolV2[int](1, 2)
+		pool.SetOverflowPolicy(policy)
+		pool.Start()
+
+		release := make(chan struct{})
+		started := make(chan struct{})
+		pool.Submit(func() (int, error) {
+			close(started)
+			<-release
+			return 0, nil
+		})
+		<-started
+		pool.Submit(func() (int, error) { return 1, nil })
+		pool.Submit(func() (int, error) { return 2, nil })
+		return pool, release
+	}
+
+	t.Run("Block", func(t *testing.T) {
+		pool, release := newBlockedPool(abstract.OverflowBlock)
+		defer pool.Stop()
+
+		if pool.Submit(func() (int, error) { return 3, nil }, 50*time.Millisecond) {
+			t.Error("Expected submit to time out on full queue")
+		}
+		close(release)
+
+		results, _ := pool.FetchResults(time.Second)
+		if len(results) != 3 {
+			t.Errorf("Expected 3 results, got %v", results)
+		}
+	})
+
+	t.Run("DropNewest", func(t *testing.T) {
+		pool, release := newBlockedPool(abstract.OverflowDropNewest)
+		defer pool.Stop()
+
+		if pool.Submit(func() (int, error) { return 3, nil }) {
+			t.Error("Expected newest task to be rejected")
+		}
+		if pool.Dropped() != 1 {
+			t.Errorf("Expected 1 dropped task, got %d", pool.Dropped())
+		}
+		close(release)
+
+		results, _ := pool.FetchResults(time.Second)
+		if len(results) != 3 || results[2] != 2 {
+			t.Errorf("Expected results [0 1 2], got %v", results)
+		}
+	})
+
+	t.Run("DropOldest", func(t *testing.T) {
+		pool, release := newBlockedPool(abstract.OverflowDropOldest)
+		defer pool.Stop()
+
+		if !pool.Submit(func() (int, error) { return 3, nil }) {
+			t.Error("Expected newest task to be accepted")
+		}
+		if !pool.Submit(func() (int, error) { return 4, nil }) {
+			t.Error("Expected newest task to be accepted")
+		}
+		if pool.Dropped() != 2 {
+			t.Errorf("Expected 2 dropped tasks, got %d", pool.Dropped())
+		}
+		close(release)
+
+		results, _ := pool.FetchResults(time.Second)
+		if len(results) != 3 || results[0] != 0 || results[1] != 3 || results[2] != 4 {
+			t.Errorf("Expected results [0 3 4], got %v", results)
+		}
+		if pool.Submitted() != 0 {
+			t.Errorf("Expected no pending tasks, got %d", pool.Submitted())
+		}
+	})
+
+	t.Run("CallerRuns", func(t *testing.T) {
+		pool, release := newBlockedPool(abstract.OverflowCallerRuns)
+		defer pool.Stop()
+
+		var ranInCaller atomic.Bool
+		if !pool.Submit(func() (int, error) {
+			ranInCaller.Store(true)
+			return 3, nil
+		}) {
+			t.Error("Expected task to be executed by caller")
+		}
+		if !ranInCaller.Load() {
+			t.Error("Expected task to be executed synchronously")
+		}
+		close(release)
+
+		results, _ := pool.FetchResults(time.Second)
+		if len(results) != 4 {
+			t.Errorf("Expected 4 results, got %v", results)
+		}
+		if pool.Dropped() != 0 {
+			t.Errorf("Expected no dropped tasks, got %d", pool.Dropped())
+		}
+	})
+
+	t.Run("CallerRunsDuringRestart", func(t *testing.T) {
+		pool, release := newBlockedPool(abstract.OverflowCallerRuns)
+		defer pool.Stop()
+
+		restarted := make(chan struct{})
+		submitted := make(chan struct{})
+		go func() {
+			defer close(submitted)
+			// The task executed in the caller submits to the same pool while Restart is waiting
+			pool.Submit(func() (int, error) {
+				go func() {
+					pool.Restart(1, 2)
+					close(restarted)
+				}()
+				time.Sleep(20 * time.Millisecond)
+				pool.Submit(func() (int, error) { return 4, nil })
+				return 3, nil
+			})
+		}()
+
+		select {
+		case <-submitted:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Expected submit from a task executed in the caller not to deadlock with Restart")
+		}
+		close(release)
+		// Restart waits for the result buffer to have space for the results of the queued tasks
+		deadline := time.After(5 * time.Second)
+		for {
+			pool.FetchResults(10 * time.Millisecond)
+			select {
+			case <-restarted:
+				return
+			case <-deadline:
+				t.Fatal("Expected restart to complete")
+			default:
+			}
+		}
+	})
+}
+
+func TestWorkerPoolV2SubmitKeyed(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](4, 100)
+	pool.Start()
+	defer pool.Stop()
+
+	const (
+		keys        = 5
+		tasksPerKey = 20
+	)
+	var (
+		mu       sync.Mutex
+		order    = make(map[string][]int)
+		inFlight = make(map[string]int)
+		overlap  atomic.Bool
+	)
+
+	for i := range tasksPerKey {
+		for k := range keys {
+			key := "key" + string(rune('a'+k))
+			ok := pool.SubmitKeyed(key, func() (int, error) {
+				mu.Lock()
+				inFlight[key]++
+				if inFlight[key] > 1 {
+					overlap.Store(true)
+				}
+				mu.Unlock()
+
+				time.Sleep(time.Millisecond)
+
+				mu.Lock()
+				inFlight[key]--
+				order[key] = append(order[key], i)
+				mu.Unlock()
+				return i, nil
+			})
+			if !ok {
+				t.Fatalf("Failed to submit keyed task %d for %s", i, key)
+			}
+		}
+	}
+
+	results, _ := pool.FetchResults(5 * time.Second)
+	if len(results) != keys*tasksPerKey {
+		t.Fatalf("Expected %d results, got %d", keys*tasksPerKey, len(results))
+	}
+	if overlap.Load() {
+		t.Error("Tasks with the same key must not run concurrently")
+	}
+	for key, got := range order {
+		for i, v := range got {
+			if v != i {
+				t.Errorf("Tasks for %s executed out of order: %v", key, got)
+				break
+			}
+		}
+	}
+
+	if pool.SubmitKeyed("key", nil) {
+		t.Error("Submitting nil keyed task should return false")
+	}
+	pool.Stop()
+	if pool.SubmitKeyed("key", func() (int, error) { return 0, nil }) {
+		t.Error("Submitting keyed task to stopped pool should return false")
+	}
+}
+
+func TestWorkerPoolV2SubmitKeyedParallel(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](8, 100)
+	pool.Start()
+	defer pool.Stop()
+
+	var running, maxRunning atomic.Int64
+	for i := range 32 {
+		pool.SubmitKeyed("key"+strconv.Itoa(i), func() (int, error) {
+			n := running.Add(1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			running.Add(-1)
+			return i, nil
+		})
+	}
+
+	results, _ := pool.FetchResults(5 * time.Second)
+	if len(results) != 32 {
+		t.Fatalf("Expected 32 results, got %d", len(results))
+	}
+	if maxRunning.Load() < 2 {
+		t.Errorf("Expected tasks with different keys to run in parallel, max running %d", maxRunning.Load())
+	}
+}
+
+func TestWorkerPoolV2FetchResultsMap(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](4, 20)
+	pool.Start()
+	defer pool.Stop()
+
+	testErr := errors.New("task failed")
+	for i := range 10 {
+		id := "task-" + strconv.Itoa(i)
+		ok := pool.SubmitWithID(id, func() (int, error) {
+			if i == 3 {
+				return 0, testErr
+			}
+			return i * 10, nil
+		})
+		if !ok {
+			t.Fatalf("Failed to submit task %s", id)
+		}
+	}
+	pool.Submit(func() (int, error) { return -1, nil })
+
+	results := pool.FetchResultsMap(5 * time.Second)
+	if len(results) != 11 {
+		t.Fatalf("Expected 11 results, got %d", len(results))
+	}
+	for i := range 10 {
+		res, ok := results["task-"+strconv.Itoa(i)]
+		if !ok {
+			t.Fatalf("Missing result for task-%d", i)
+		}
+		if i == 3 {
+			if !errors.Is(res.Err, testErr) {
+				t.Errorf("Expected error for task-3, got %v", res.Err)
+			}
+			continue
+		}
+		if res.Err != nil || res.Value != i*10 {
+			t.Errorf("Unexpected result for task-%d: %+v", i, res)
+		}
+	}
+	if res := results[""]; res.Value != -1 {
+		t.Errorf("Expected result without id to be stored with empty id, got %+v", res)
+	}
+
+	if pool.SubmitWithID("nil", nil) {
+		t.Error("Submitting nil task should return false")
+	}
+	if got := pool.FetchResultsMap(10 * time.Millisecond); len(got) != 0 {
+		t.Errorf("Expected empty map, got %v", got)
+	}
+}
+
+func TestWorkerPoolV2MaxResults(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](1, 1, 2)
+	pool.Start()
+	defer pool.Stop()
+
+	task := func() (int, error) { return 1, nil }
+
+	// 2 results in buffer, 1 task blocked in worker, 1 task in queue
+	for i := range 4 {
+		if !pool.Submit(task, time.Second) {
+			t.Fatalf("Failed to submit task %d", i)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for pool.ResultBufferLen() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := pool.ResultBufferLen(); got != 2 {
+		t.Fatalf("Expected 2 buffered results, got %d", got)
+	}
+
+	if pool.Submit(task, 50*time.Millisecond) {
+		t.Fatal("Expected submit to time out because of backpressure")
+	}
+	if got := pool.ResultBufferLen(); got != 2 {
+		t.Errorf("Expected result buffer to stay bounded at 2, got %d", got)
+	}
+
+	results, errs := pool.FetchResults(time.Second)
+	if len(results) != 4 {
+		t.Fatalf("Expected 4 results, got %d", len(results))
+	}
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if got := pool.ResultBufferLen(); got != 0 {
+		t.Errorf("Expected empty result buffer, got %d", got)
+	}
+}
+
+func TestWorkerPoolV2Restart(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](1, 50, 100)
+	var started atomic.Int64
+	pool.SetHooks(func() { started.Add(1) }, nil)
+	pool.Start()
+	defer pool.Stop()
+
+	for i := range 20 {
+		pool.Submit(func() (int, error) {
+			time.Sleep(time.Millisecond)
+			return i, nil
+		})
+	}
+	pool.SubmitKeyed("key", func() (int, error) { return 100, nil })
+
+	pool.Restart(4, 10)
+	if got := pool.Finished(); got != 21 {
+		t.Fatalf("Expected all 21 tasks to be drained before restart, got %d", got)
+	}
+	if pool.IsStopped() {
+		t.Fatal("Expected pool to be running after restart")
+	}
+
+	var running, maxRunning atomic.Int64
+	for range 8 {
+		ok := pool.Submit(func() (int, error) {
+			n := running.Add(1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			running.Add(-1)
+			return 0, nil
+		}, time.Second)
+		if !ok {
+			t.Fatal("Failed to submit task after restart")
+		}
+	}
+
+	results, _ := pool.FetchResults(5 * time.Second)
+	if len(results) != 29 {
+		t.Fatalf("Expected 29 results across restart, got %d", len(results))
+	}
+	if maxRunning.Load() < 2 {
+		t.Errorf("Expected tasks to run on more workers after restart, max running %d", maxRunning.Load())
+	}
+	if started.Load() != 29 {
+		t.Errorf("Expected hooks to be kept across restart, got %d calls", started.Load())
+	}
+}
+
+func TestWorkerPoolV2RestartConcurrentSubmit(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](2, 10, 1000)
+	pool.Start()
+	defer pool.Stop()
+
+	var wg sync.WaitGroup
+	var accepted atomic.Int64
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				if pool.Submit(func() (int, error) { return 1, nil }, time.Second) {
+					accepted.Add(1)
+				}
+			}
+		}()
+	}
+	for i := range 5 {
+		pool.Restart(i+1, 5+i)
+	}
+	wg.Wait()
+
+	results, _ := pool.FetchResults(5 * time.Second)
+	if int64(len(results)) != accepted.Load() {
+		t.Errorf("Expected %d results, got %d", accepted.Load(), len(results))
+	}
+}
+
+func TestWorkerPoolV2RestartNotStarted(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](1, 1)
+	pool.Restart(3, 0)
+	if !pool.IsStopped() {
+		t.Fatal("Expected pool to stay stopped")
+	}
+	pool.Start()
+	defer pool.Stop()
+
+	for range 300 {
+		if !pool.Submit(func() (int, error) { return 1, nil }, time.Second) {
+			t.Fatal("Failed to submit task")
+		}
+	}
+	results, _ := pool.FetchResults(5 * time.Second)
+	if len(results) != 300 {
+		t.Errorf("Expected 300 results, got %d", len(results))
+	}
+}
+
+func TestWorkerPoolV2FetchResultsOrdered(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](4, 100)
+	pool.Start()
+	defer pool.Stop()
+
+	for i := range 20 {
+		pool.Submit(func() (int, error) {
+			time.Sleep(time.Duration(20-i) * time.Millisecond)
+			return i, nil
+		})
+	}
+
+	results, errs := pool.FetchResultsOrdered(5 * time.Second)
+	if len(results) != 20 || len(errs) != 20 {
+		t.Fatalf("Expected 20 results, got %d", len(results))
+	}
+	for i, v := range results {
+		if v != i {
+			t.Fatalf("Expected results in submission order, got %v", results)
+		}
+	}
+}
+
+func TestWorkerPoolV2OrderedGapTimeout(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](4, 100)
+	pool.SetOrderedGapTimeout(50 * time.Millisecond)
+	pool.Start()
+	defer pool.Stop()
+
+	release := make(chan struct{})
+	pool.Submit(func() (int, error) {
+		<-release
+		return 0, nil
+	})
+	for i := 1; i < 6; i++ {
+		pool.Submit(func() (int, error) { return i, nil })
+	}
+
+	start := time.Now()
+	results, _ := pool.FetchResultsOrdered(5 * time.Second)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected gap timeout to bound waiting, took %v", elapsed)
+	}
+	if !reflect.DeepEqual(results, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("Expected available results in order, got %v", results)
+	}
+
+	close(release)
+	results, _ = pool.FetchResultsOrdered(5 * time.Second)
+	if !reflect.DeepEqual(results, []int{0}) {
+		t.Errorf("Expected slow result in the next fetch, got %v", results)
+	}
+}
+
+func TestWorkerPoolV2ResultTransform(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](2, 10)
+	pool.SetResultTransform(func(v int) int { return v * 10 })
+	pool.Start()
+	defer pool.Stop()
+
+	testErr := errors.New("failed")
+	for i := range 5 {
+		pool.Submit(func() (int, error) {
+			if i == 4 {
+				return 4, testErr
+			}
+			return i, nil
+		})
+	}
+
+	results, errs := pool.FetchResultsOrdered(5 * time.Second)
+	if !reflect.DeepEqual(results, []int{0, 10, 20, 30, 4}) {
+		t.Errorf("Expected transformed results except failed one, got %v", results)
+	}
+	if !errors.Is(errs[4], testErr) {
+		t.Errorf("Expected error of failed task, got %v", errs[4])
+	}
+
+	pool.SetOverflowPolicy(abstract.OverflowCallerRuns)
+	pool.SubmitWithID("id", func() (int, error) { return 7, nil })
+	if res := pool.FetchResultsMap(5 * time.Second)["id"]; res.Value != 70 {
+		t.Errorf("Expected transformed result, got %d", res.Value)
+	}
+
+	pool.SetResultTransform(nil)
+	pool.Submit(func() (int, error) { return 7, nil })
+	if results, _ := pool.FetchResults(5 * time.Second); !reflect.DeepEqual(results, []int{7}) {
+		t.Errorf("Expected untransformed result, got %v", results)
+	}
+}
+
+func TestWorkerPoolV2HealthCheck(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](1, 10)
+	if !pool.LastActivity().IsZero() {
+		t.Error("Expected zero last activity before start")
+	}
+	pool.Start()
+	defer pool.Stop()
+
+	if pool.LastActivity().IsZero() {
+		t.Error("Expected last activity to be set after start")
+	}
+	if !pool.HealthCheck(time.Millisecond) {
+		t.Error("Expected idle pool to be healthy")
+	}
+
+	release := make(chan struct{})
+	pool.Submit(func() (int, error) {
+		<-release
+		return 1, nil
+	})
+	pool.Submit(func() (int, error) { return 2, nil })
+
+	time.Sleep(100 * time.Millisecond)
+	if pool.HealthCheck(20 * time.Millisecond) {
+		t.Error("Expected stuck pool to be unhealthy")
+	}
+	if !pool.HealthCheck(time.Hour) {
+		t.Error("Expected pool to be healthy with a large threshold")
+	}
+
+	before := pool.LastActivity()
+	close(release)
+	results, _ := pool.FetchResults(5 * time.Second)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %v", results)
+	}
+	if !pool.LastActivity().After(before) {
+		t.Error("Expected last activity to be updated")
+	}
+	if !pool.HealthCheck(time.Millisecond) {
+		t.Error("Expected pool without queued tasks to be healthy")
+	}
+}
+
+func TestWorkerPoolV2QueueSampling(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](1, 10)
+	pool.Start()
+	defer pool.Stop()
+
+	if pool.QueueDepthHistory() != nil || pool.QueueDepthPercentile(95) != 0 {
+		t.Error("Expected no samples before sampling is enabled")
+	}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	pool.Submit(func() (int, error) {
+		close(started)
+		<-release
+		return 0, nil
+	})
+	<-started
+	for i := range 3 {
+		pool.Submit(func() (int, error) { return i, nil })
+	}
+
+	pool.EnableQueueSampling(5*time.Millisecond, 4)
+	time.Sleep(100 * time.Millisecond)
+
+	history := pool.QueueDepthHistory()
+	if len(history) != 4 {
+		t.Fatalf("Expected history to be limited to 4 samples, got %v", history)
+	}
+	for _, depth := range history {
+		if depth != 3 {
+			t.Errorf("Expected queue depth 3, got %v", history)
+			break
+		}
+	}
+	if p := pool.QueueDepthPercentile(95); p != 3 {
+		t.Errorf("Expected p95 queue depth 3, got %d", p)
+	}
+
+	close(release)
+	pool.FetchResults(5 * time.Second)
+	time.Sleep(50 * time.Millisecond)
+	if history := pool.QueueDepthHistory(); history[len(history)-1] != 0 {
+		t.Errorf("Expected last queue depth 0, got %v", history)
+	}
+	if p := pool.QueueDepthPercentile(0); p != 0 {
+		t.Errorf("Expected p0 queue depth 0, got %d", p)
+	}
+
+	pool.EnableQueueSampling(0)
+	if pool.QueueDepthHistory() != nil {
+		t.Error("Expected no samples after sampling is disabled")
+	}
+}
+
+func TestWorkerPoolWithState(t *testing.T) {
+	type conn struct{ id int64 }
+
+	var created, released atomic.Int64
+	pool := abstract.NewWorkerPoolWithState(3, 100, func() *conn {
+		return &conn{id: created.Add(1)}
+	}, func(c *conn) (int64, error) {
+		time.Sleep(time.Millisecond)
+		return c.id, nil
+	})
+	pool.SetStateRelease(func(*conn) { released.Add(1) })
+	pool.Start()
+	defer pool.Stop()
+
+	for range 50 {
+		pool.Submit()
+	}
+	pool.SubmitWithID("id")
+	pool.SubmitKeyed("key")
+	pool.WorkerPoolV2.Submit(func() (int64, error) { return -1, nil })
+
+	results, errs := pool.FetchResults(5 * time.Second)
+	if len(results) != 53 {
+		t.Fatalf("Expected 53 results, got %d", len(results))
+	}
+	for i, id := range results {
+		if errs[i] != nil {
+			t.Errorf("Unexpected error: %v", errs[i])
+		}
+		if id != -1 && (id < 1 || id > 3) {
+			t.Errorf("Expected state of one of 3 workers, got %d", id)
+		}
+	}
+	if n := created.Load(); n != 3 {
+		t.Errorf("Expected state to be created once per worker, got %d", n)
+	}
+
+	pool.Restart(2, 10)
+	if n := released.Load(); n != 3 {
+		t.Errorf("Expected states of 3 old workers to be released, got %d", n)
+	}
+	pool.Submit()
+	results, _ = pool.FetchResults(5 * time.Second)
+	if len(results) != 1 || results[0] < 4 {
+		t.Errorf("Expected state of a restarted worker, got %v", results)
+	}
+
+	noTask := abstract.NewWorkerPoolWithState[int64](1, 1, func() *conn { return &conn{} }, nil)
+	noTask.Start()
+	defer noTask.Stop()
+	if noTask.Submit() {
+		t.Error("Expected submission to be rejected without task")
+	}
+}
+
+func TestWorkerPoolWithStateCallerRuns(t *testing.T) {
+	type conn struct{ id int64 }
+
+	var created, released atomic.Int64
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	pool := abstract.NewWorkerPoolWithState(1, 1, func() *conn {
+		return &conn{id: created.Add(1)}
+	}, func(c *conn) (int64, error) {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return c.id, nil
+	})
+	pool.SetStateRelease(func(*conn) { released.Add(1) })
+	pool.SetOverflowPolicy(abstract.OverflowCallerRuns)
+	pool.Start()
+
+	pool.Submit()
+	<-started
+	if !pool.Submit() {
+		t.Error("Expected task to be queued")
+	}
+	// The caller goroutine has no state, so the task is rejected instead of creating a new state
+	if pool.Submit() {
+		t.Error("Expected task to be rejected when the queue is full")
+	}
+	close(release)
+
+	results, _ := pool.FetchResultsOrdered(5 * time.Second)
+	if !reflect.DeepEqual(results, []int64{1, 1}) {
+		t.Errorf("Expected worker state for queued tasks, got %v", results)
+	}
+	if n := created.Load(); n != 1 {
+		t.Errorf("Expected only worker state to be created, got %d", n)
+	}
+	if pool.Dropped() != 1 {
+		t.Errorf("Expected 1 dropped task, got %d", pool.Dropped())
+	}
+
+	pool.Stop()
+	deadline := time.Now().Add(5 * time.Second)
+	for released.Load() != 1 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := released.Load(); n != 1 {
+		t.Errorf("Expected worker state to be released after stop, got %d", n)
+	}
+}
+
+func TestWorkerPoolV2Cancel(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](1, 10)
+	pool.Start()
+	defer pool.Stop()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var executed atomic.Int64
+	pool.SubmitWithID("running", func() (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	<-started
+	pool.SubmitWithID("queued", func() (int, error) {
+		executed.Add(1)
+		return 2, nil
+	})
+	pool.SubmitWithID("kept", func() (int, error) { return 3, nil })
+
+	if pool.Cancel("running") {
+		t.Error("Expected started task not to be cancelled")
+	}
+	if !pool.Cancel("queued") {
+		t.Error("Expected queued task to be cancelled")
+	}
+	if pool.Cancel("queued") || pool.Cancel("unknown") || pool.Cancel("") {
+		t.Error("Expected cancel to fail for already cancelled or unknown task")
+	}
+
+	close(release)
+	results := pool.FetchResultsMap(5 * time.Second)
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %v", results)
+	}
+	if res := results["queued"]; !errors.Is(res.Err, context.Canceled) || res.Value != 0 {
+		t.Errorf("Expected canceled result, got %+v", res)
+	}
+	if executed.Load() != 0 {
+		t.Error("Expected cancelled task not to be executed")
+	}
+	if results["running"].Value != 1 || results["kept"].Value != 3 {
+		t.Errorf("Unexpected results: %v", results)
+	}
+	if pool.Cancel("kept") {
+		t.Error("Expected completed task not to be cancelled")
+	}
+}
+
+func TestTypedWorkerPool(t *testing.T) {
+	pool := abstract.NewTypedWorkerPool(3, 20, func(in string) (int, error) {
+		if in == "" {
+			return 0, errors.New("empty input")
+		}
+		return len(in), nil
+	})
+	pool.Start()
+	defer pool.Stop()
+
+	for _, in := range []string{"a", "bb", "ccc"} {
+		if !pool.Submit(in) {
+			t.Fatalf("Expected input %q to be accepted", in)
+		}
+	}
+	results, errs := pool.FetchResultsOrdered(5 * time.Second)
+	if !reflect.DeepEqual(results, []int{1, 2, 3}) {
+		t.Errorf("Expected [1 2 3], got %v", results)
+	}
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+
+	pool.SubmitWithID("empty", "")
+	pool.SubmitKeyed("key", "dddd")
+	res := pool.FetchResultsMap(5 * time.Second)
+	if res["empty"].Err == nil {
+		t.Error("Expected handler error")
+	}
+	if res[""].Value != 4 {
+		t.Errorf("Expected keyed result 4, got %v", res[""])
+	}
+
+	noHandler := abstract.NewTypedWorkerPool[string, int](1, 1, nil)
+	noHandler.Start()
+	defer noHandler.Stop()
+	if noHandler.Submit("x") {
+		t.Error("Expected input to be rejected without handler")
+	}
+}
+
+func TestTypedWorkerPoolAllocs(t *testing.T) {
+	pool := abstract.NewTypedWorkerPool(1, 1000, func(in string) (int, error) {
+		return len(in), nil
+	})
+	pool.Start()
+	defer pool.Stop()
+
+	allocs := testing.AllocsPerRun(100, func() {
+		pool.Submit("input")
+	})
+	if allocs > 0 {
+		t.Errorf("Expected no allocations per submission, got %v", allocs)
+	}
+
+	results, _ := pool.FetchResults(5 * time.Second)
+	if len(results) != 101 {
+		t.Errorf("Expected 101 results, got %d", len(results))
+	}
+}
+
+func TestWorkerPoolV2DrainResults(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](2, 10)
+	pool.Start()
+
+	for i := range 5 {
+		pool.Submit(func() (int, error) { return i, nil })
+	}
+	for pool.Finished() < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	pool.Stop()
+
+	var drained []int
+	n := pool.DrainResults(func(res abstract.TaskResult[int]) {
+		if res.Err != nil {
+			t.Errorf("Unexpected error: %v", res.Err)
+		}
+		drained = append(drained, res.Value)
+	})
+	sort.Ints(drained)
+	if n != 5 || !reflect.DeepEqual(drained, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("Expected all results to be drained, got %d: %v", n, drained)
+	}
+	if pool.Submitted() != 0 || pool.Finished() != 0 || pool.ResultBufferLen() != 0 {
+		t.Error("Expected counters to be reset after draining")
+	}
+	if n := pool.DrainResults(nil); n != 0 {
+		t.Errorf("Expected nothing to drain, got %d", n)
+	}
+}
+
+func TestWorkerPoolV2Deduplicate(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](1, 10)
+	pool.SetDeduplicate(true)
+	pool.Start()
+	defer pool.Stop()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var executed atomic.Int64
+	job := func() (int, error) {
+		executed.Add(1)
+		return 42, nil
+	}
+	pool.SubmitWithID("running", func() (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	<-started
+
+	for range 5 {
+		if !pool.SubmitWithID("job", job) {
+			t.Fatal("Expected submission to be accepted")
+		}
+	}
+	if !pool.SubmitWithID("running", job) {
+		t.Fatal("Expected submission of running task to be accepted")
+	}
+	pool.Submit(job)
+	pool.Submit(job)
+
+	if pool.Deduplicated() != 5 {
+		t.Errorf("Expected 5 deduplicated submissions, got %d", pool.Deduplicated())
+	}
+	if pool.Submitted() != 4 {
+		t.Errorf("Expected 4 submitted tasks, got %d", pool.Submitted())
+	}
+
+	close(release)
+	results := pool.FetchResultsMap(5 * time.Second)
+	if results["job"].Value != 42 || results["running"].Value != 1 {
+		t.Errorf("Unexpected results: %v", results)
+	}
+	if executed.Load() != 3 {
+		t.Errorf("Expected job to be executed 3 times, got %d", executed.Load())
+	}
+
+	pool.SubmitWithID("job", job)
+	pool.FetchResultsMap(5 * time.Second)
+	if executed.Load() != 4 || pool.Deduplicated() != 5 {
+		t.Errorf("Expected completed task not to be deduplicated, executed %d", executed.Load())
+	}
+
+	pool.SetDeduplicate(false)
+	pool.SubmitWithID("a", job)
+	pool.SubmitWithID("a", job)
+	pool.FetchResults(5 * time.Second)
+	if executed.Load() != 6 {
+		t.Errorf("Expected tasks to be executed without deduplication, executed %d", executed.Load())
+	}
+}
+
+func TestWorkerPoolV2SubmitWithWeight(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](1, 10)
+	pool.Start()
+	defer pool.Stop()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	pool.Submit(func() (int, error) {
+		close(started)
+		<-release
+		return 0, nil
+	})
+	<-started
+
+	task := func() (int, error) { return 1, nil }
+	if pool.SubmitWithWeight(task, 11) {
+		t.Error("Expected task heavier than the queue capacity to be rejected")
+	}
+	if !pool.SubmitWithWeight(task, 6) {
+		t.Fatal("Expected task to be accepted")
+	}
+	if pool.SubmitWithWeight(task, 6, 10*time.Millisecond) {
+		t.Error("Expected task to be rejected when there is not enough free weight")
+	}
+	if !pool.SubmitWithWeight(task, 3) || !pool.Submit(task) {
+		t.Fatal("Expected tasks to fit into the rest of the queue")
+	}
+	if pool.QueuedWeight() != 10 {
+		t.Errorf("Expected queued weight 10, got %d", pool.QueuedWeight())
+	}
+
+	pool.SetOverflowPolicy(abstract.OverflowDropNewest)
+	if pool.SubmitWithWeight(task, 0) {
+		t.Error("Expected task to be dropped when the queue weight is exhausted")
+	}
+	pool.SetOverflowPolicy(abstract.OverflowBlock)
+
+	accepted := make(chan bool)
+	go func() { accepted <- pool.SubmitWithWeight(task, 5, 5*time.Second) }()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	if !<-accepted {
+		t.Fatal("Expected blocked task to be accepted after the weight is released")
+	}
+
+	results, _ := pool.FetchResults(5 * time.Second)
+	if len(results) != 5 {
+		t.Errorf("Expected 5 results, got %d", len(results))
+	}
+	if pool.QueuedWeight() != 0 {
+		t.Errorf("Expected no queued weight, got %d", pool.QueuedWeight())
+	}
+}
+
+func TestWorkerPoolV2FetchResultsFunc(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](3, 20)
+	pool.Start()
+	defer pool.Stop()
+
+	for i := range 10 {
+		pool.Submit(func() (int, error) {
+			if i%3 == 0 {
+				return i, errors.New("failed")
+			}
+			return i % 2, nil
+		})
+	}
+
+	results := pool.FetchResultsFunc(func(v int, err error) bool { return err == nil && v != 0 }, 5*time.Second)
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 successful non-zero results, got %v", results)
+	}
+	for _, res := range results {
+		if res.Err != nil || res.Value != 1 {
+			t.Errorf("Unexpected result: %+v", res)
+		}
+	}
+	if pool.ResultBufferLen() != 0 {
+		t.Errorf("Expected discarded results to be fetched, %d left", pool.ResultBufferLen())
+	}
+
+	for i := range 4 {
+		pool.Submit(func() (int, error) { return i, nil })
+	}
+	if all := pool.FetchResultsFunc(nil, 5*time.Second); len(all) != 4 {
+		t.Errorf("Expected nil keep to return all 4 results, got %v", all)
+	}
+}
+
+func TestWorkerPoolV2SubmitKeyedBackpressure(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](1, 1000)
+	pool.Start()
+	defer pool.Stop()
+
+	release := make(chan struct{})
+	pool.SubmitKeyed("key", func() (int, error) {
+		<-release
+		return 0, nil
+	})
+
+	// The keyed queue of a worker is small and does not grow with the queue capacity
+	accepted := 0
+	for range 100 {
+		if !pool.SubmitKeyed("key", func() (int, error) { return 1, nil }, 10*time.Millisecond) {
+			break
+		}
+		accepted++
+	}
+	if accepted == 0 || accepted >= 100 {
+		t.Errorf("Expected keyed queue to push back after a few tasks, accepted %d", accepted)
+	}
+
+	close(release)
+	results, _ := pool.FetchResults(5 * time.Second)
+	if len(results) != accepted+1 {
+		t.Errorf("Expected %d results, got %d", accepted+1, len(results))
+	}
+}
+
+func TestWorkerPoolV2RestartDoesNotBlockPool(t *testing.T) {
+	pool := abstract.NewWorkerPoolV2[int](1, 10)
+	pool.Start()
+	defer pool.Stop()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	pool.Submit(func() (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	<-started
+	pool.Submit(func() (int, error) { return 2, nil })
+
+	restarted := make(chan struct{})
+	go func() {
+		pool.Restart(2, 10)
+		close(restarted)
+	}()
+
+	// Wait until the restart has begun and is waiting for the running task
+	deadline := time.Now().Add(5 * time.Second)
+	for pool.Submit(func() (int, error) { return 3, nil }) {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected submissions to be rejected during restart")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	healthy := make(chan bool)
+	go func() { healthy <- pool.HealthCheck(time.Hour) }()
+	select {
+	case <-healthy:
+	case <-time.After(time.Second):
+		t.Fatal("Expected HealthCheck not to block while Restart waits for tasks")
+	}
+
+	close(release)
+	select {
+	case <-restarted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Restart to complete after the task returned")
+	}
+
+	if !pool.Submit(func() (int, error) { return 4, nil }) {
+		t.Error("Expected submissions to be accepted after restart")
+	}
+	results, _ := pool.FetchResults(5 * time.Second)
+	if pool.Submitted() != 0 || len(results) < 3 {
+		t.Errorf("Expected all accepted tasks to complete, got %v", results)
+	}
+}